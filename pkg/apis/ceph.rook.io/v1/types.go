@@ -21,6 +21,7 @@ import (
 
 	rookv1 "github.com/rook/rook/pkg/apis/rook.io/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -386,6 +387,15 @@ type ObjectStoreSpec struct {
 
 	// The rgw pod info
 	Gateway GatewaySpec `json:"gateway"`
+
+	// The maximum quotas a user of the object store can be given
+	MaxUserQuotas *ObjectUserQuotaSpec `json:"maxUserQuotas,omitempty"`
+
+	// The quotas of the users of the object store that do not set them themselves
+	DefaultUserQuotas *ObjectUserQuotaSpec `json:"defaultUserQuotas,omitempty"`
+
+	// The admin capabilities of the users of the object store, in addition to those they set themselves
+	DefaultUserCapabilities *ObjectUserCapSpec `json:"defaultUserCapabilities,omitempty"`
 }
 
 // +genclient
@@ -395,8 +405,8 @@ type ObjectStoreSpec struct {
 type CephObjectStoreUser struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
-	Spec              ObjectStoreUserSpec `json:"spec"`
-	Status            *Status             `json:"status"`
+	Spec              ObjectStoreUserSpec    `json:"spec"`
+	Status            *ObjectStoreUserStatus `json:"status"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -411,10 +421,443 @@ type CephObjectStoreUserList struct {
 type ObjectStoreUserSpec struct {
 	//The store the user will be created in
 	Store string `json:"store,omitempty"`
+	// The uid of the RGW user, e.g. "tenant$user", the name of the CR if not set. It cannot be changed
+	// once the user is created.
+	UID string `json:"uid,omitempty"`
 	//The display name for the ceph users
 	DisplayName string `json:"displayName,omitempty"`
+	//The email address of the ceph user
+	//If not set, the email of the user is cleared
+	Email string `json:"email,omitempty"`
+	//The operations the user is allowed to perform, a comma separated list of read, write, delete or *
+	//If not set, the RGW default is kept
+	OpMask string `json:"opMask,omitempty"`
+	//The quotas of the user, limited by the maximum quotas of the object store
+	Quotas *ObjectUserQuotaSpec `json:"quotas,omitempty"`
+	//The subusers of the user
+	Subusers []SubuserSpec `json:"subusers,omitempty"`
+	//Whether the generated keys of all subusers are generated again when the S3 keys of the user rotate
+	RotateSubuserKeys bool `json:"rotateSubuserKeys,omitempty"`
+	//The placement target the new buckets of the user are created in
+	//If not set, the default placement of the user is left untouched
+	DefaultPlacement string `json:"defaultPlacement,omitempty"`
+	//The storage class of the placement target the new objects of the user are stored in
+	DefaultStorageClass string `json:"defaultStorageClass,omitempty"`
+	//The placement tags of the user, which allow it to create buckets in the placement targets with these tags
+	//If not set, the placement tags of the user are left untouched
+	PlacementTags []string `json:"placementTags,omitempty"`
+	//The format of the secret holding the keys of the user, "rook" by default. "mc" adds a MinIO client
+	//config to the keys, "standard" has the key names commonly used by secret stores, e.g. accessKeyID
+	SecretFormat string `json:"secretFormat,omitempty"`
+	// The time the user expires at, in RFC3339, after which the user is suspended but not deleted
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// The realm the user is expected in, the reconcile fails if the realm of the store is another one
+	// to keep the user metadata out of the wrong realm of a multisite setup
+	Realm string `json:"realm,omitempty"`
+	// The type of the secret holding the keys of the user, "kubernetes.io/rook" by default, e.g. Opaque
+	// for the tools that only consume secrets of that type
+	SecretType v1.SecretType `json:"secretType,omitempty"`
+	// Whether the secret also holds the keys under the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY names of the AWS SDKs
+	AWSKeyNames bool `json:"awsKeyNames,omitempty"`
+	//The S3 keys of the user, to hold several keys at once, e.g. for a rotation without downtime
+	//If not set, the user has the single key RGW generates on creation
+	Keys []UserKeySpec `json:"keys,omitempty"`
+	// The Swift temp URL keys of the user, to sign the temporary URLs of its objects
+	TempURLKeys []TempURLKeySpec `json:"tempURLKeys,omitempty"`
+	// Whether an existing RGW user is adopted with its S3 keys, which the operator then never changes so
+	// that the existing clients keep working. The rest of the spec is still applied.
+	Adopt bool `json:"adopt,omitempty"`
+	//Whether the user has no top-level keys, for accounts whose buckets are only accessed through subusers
+	SuppressUserKeys bool `json:"suppressUserKeys,omitempty"`
+	//Whether a key is generated for an existing user that has no top-level keys, e.g. after a migration
+	//If not set, such a user fails to reconcile rather than getting a secret without keys
+	GenerateMissingKey bool `json:"generateMissingKey,omitempty"`
+	//Whether the operator generates the S3 keys of the user, for credentials that are entirely managed outside
+	//If false, the keys must be read from the secrets of the keys of the spec. Defaults to true.
+	GenerateKey *bool `json:"generateKey,omitempty"`
+	// The S3 key of the user is derived from a seed and its uid instead of being generated randomly, so
+	// that it is the same whenever the user is provisioned again. Only honored if the operator allows it.
+	DeterministicKey *DeterministicKeySpec `json:"deterministicKey,omitempty"`
+	// The secret in the namespace of the user holding the AccessKey and SecretKey the user is restored
+	// with, e.g. after a disaster, so that the clients keep the keys they hold. The key is the only key
+	// of the user, the other keys are removed.
+	RestoreKeysFrom string `json:"restoreKeysFrom,omitempty"`
+	// The schedule the generated S3 key of the user is rotated on, the previous key is removed once the
+	// secret of the user holds the new one
+	KeyRotation *KeyRotationSpec `json:"keyRotation,omitempty"`
+	// The admin capabilities of the user
+	Capabilities *ObjectUserCapSpec `json:"capabilities,omitempty"`
+	// The preset of admin capabilities of the user, the capabilities set in capabilities override those
+	// of the preset
+	Preset ObjectUserPreset `json:"preset,omitempty"`
+	// What happens to the RGW user when the CR is deleted, defaults to delete
+	PreservePolicy PreservePolicy `json:"preservePolicy,omitempty"`
+	// Whether the user is deleted with the data of its buckets, even if object bucket claims still use
+	// them. Otherwise the deletion waits until the object buckets of the user are gone.
+	PurgeDataOnDeletion bool `json:"purgeDataOnDeletion,omitempty"`
+	// How long the RGW user and its secret are kept after the CR is deleted, e.g. "24h", to recover
+	// from a mistaken deletion. The deletion is immediate if not set.
+	DeletionGracePeriod *metav1.Duration `json:"deletionGracePeriod,omitempty"`
+	// Metadata of the user for billing or showback, e.g. its cost center, set as annotations of its
+	// secret as RGW has no user metadata to hold them
+	Tags map[string]string `json:"tags,omitempty"`
+	// The labels added to the secret of the user, e.g. for the selectors of network policies. The labels
+	// of the operator cannot be overridden.
+	SecretLabels map[string]string `json:"secretLabels,omitempty"`
+	// The annotations added to the secret of the user. The annotations of the operator cannot be overridden.
+	SecretAnnotations map[string]string `json:"secretAnnotations,omitempty"`
+	// The namespace of the CephCluster and the CephObjectStore, defaults to the namespace of the user
+	ClusterNamespace string `json:"clusterNamespace,omitempty"`
+	// The other namespaces the secret of the user is replicated into, e.g. for the apps that use the keys
+	SecretNamespaces []string `json:"secretNamespaces,omitempty"`
+	// Whether the operator only manages the RGW user and never writes the secret with its keys, for
+	// consumers that fetch the keys with their own tooling
+	DisableSecretGeneration bool `json:"disableSecretGeneration,omitempty"`
+	// The existing buckets to link to the user, e.g. to transfer them from a user they were unlinked from
+	LinkBuckets []string `json:"linkBuckets,omitempty"`
+	// The buckets to create for the user once it is reconciled, owned by the user, e.g. the default
+	// bucket of a tenant. Each bucket is only created once, removing it from the list keeps the bucket.
+	InitialBuckets []string `json:"initialBuckets,omitempty"`
+	// Whether the versioning of the initial buckets is enabled, RGW has no such default for the
+	// buckets the user creates itself
+	DefaultBucketVersioning bool `json:"defaultBucketVersioning,omitempty"`
+	// The users generated from this CR as a template, a CephObjectStoreUser named "<name>-<user>" with
+	// the rest of this spec is created for each of them. The template itself has no RGW user.
+	Users []string `json:"users,omitempty"`
+}
+
+// ObjectStoreUserPhase is the phase of an object store user, derived from its conditions
+type ObjectStoreUserPhase string
+
+const (
+	// ObjectStoreUserPhaseCreated is the phase of a user that was not reconciled yet
+	ObjectStoreUserPhaseCreated ObjectStoreUserPhase = "Created"
+	// ObjectStoreUserPhaseReconciling is the phase of a user that is reconciled or waits for its cluster
+	ObjectStoreUserPhaseReconciling ObjectStoreUserPhase = "Reconciling"
+	// ObjectStoreUserPhaseReconcileFailed is the phase of a user whose last reconcile failed
+	ObjectStoreUserPhaseReconcileFailed ObjectStoreUserPhase = "ReconcileFailed"
+	// ObjectStoreUserPhaseReady is the phase of a user that is reconciled
+	ObjectStoreUserPhaseReady ObjectStoreUserPhase = "Ready"
+	// ObjectStoreUserPhaseValidated is the phase of a user that a dry run validated
+	ObjectStoreUserPhaseValidated ObjectStoreUserPhase = "Validated"
+)
+
+// PreservePolicy is what happens to the RGW user of an object store user when its CR is deleted
+type PreservePolicy string
+
+const (
+	// PreservePolicyDelete deletes the RGW user with the CR
+	PreservePolicyDelete PreservePolicy = "delete"
+	// PreservePolicyRetain keeps the RGW user when the CR is deleted, only its secret is removed
+	PreservePolicyRetain PreservePolicy = "retain"
+)
+
+// ObjectUserCapSpec represents the admin capabilities of an object store user. Each capability is one
+// of "read", "write", "read, write" or "*", a capability that is not set is left untouched.
+type ObjectUserCapSpec struct {
+	User     string `json:"user,omitempty"`
+	Bucket   string `json:"bucket,omitempty"`
+	Metadata string `json:"metadata,omitempty"`
+	Usage    string `json:"usage,omitempty"`
+	Zone     string `json:"zone,omitempty"`
+	// The roles capability, to manage the roles that STS users assume, requires RGW Nautilus or newer
+	Roles string `json:"roles,omitempty"`
+	// The info capability, to read the info of the cluster, e.g. for exporters, requires RGW Pacific or newer
+	Info string `json:"info,omitempty"`
+	// The capabilities in the "<type>=<perm>;<type>=<perm>" form of radosgw-admin, e.g. for the capability
+	// types of newer RGW releases that have no field yet. Cannot be set with the other fields or a preset,
+	// and not used by the default capabilities of an object store.
+	Raw string `json:"raw,omitempty"`
+}
+
+// ObjectUserPreset is a set of admin capabilities for a common kind of object store user
+type ObjectUserPreset string
+
+const (
+	// ObjectUserPresetMonitoring reads the info, usage and metadata of the object store, e.g. for exporters
+	ObjectUserPresetMonitoring ObjectUserPreset = "monitoring"
+)
+
+// UserKeySpec represents an S3 or a Swift key of an object store user
+type UserKeySpec struct {
+	// The name the key is tracked under, defaults to "key-<index>"
+	Name string `json:"name,omitempty"`
+	// The type of the key, s3 if not set. A swift key is the Swift key of the subuser of the spec with
+	// the name of the key.
+	Type UserKeyType `json:"type,omitempty"`
+	// The secret in the namespace of the user holding the AccessKey and SecretKey to set, instead of generating the key
+	SecretName string `json:"secretName,omitempty"`
+	// The expected hex encoded SHA-256 of "<AccessKey>:<SecretKey>" in the secret, verified before setting the key
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// KeyRotationSpec represents the schedule the S3 key of an object store user is rotated on
+type KeyRotationSpec struct {
+	// The number of days between two rotations of the key
+	IntervalDays int `json:"intervalDays"`
 }
 
+// DeterministicKeySpec represents the seed the S3 key of an object store user is derived from
+type DeterministicKeySpec struct {
+	// The secret in the namespace of the user whose Seed the key is derived from
+	SeedRef string `json:"seedRef"`
+}
+
+// UserKeyType is the type of a key of an object store user
+type UserKeyType string
+
+const (
+	// UserKeyTypeS3 is an S3 key with an access key and a secret key
+	UserKeyTypeS3 UserKeyType = "s3"
+	// UserKeyTypeSwift is a Swift key, which RGW only holds for subusers
+	UserKeyTypeSwift UserKeyType = "swift"
+)
+
+// TempURLKeySpec represents a Swift temp URL key of an object store user
+type TempURLKeySpec struct {
+	// The ID of the key, 0 or 1 as RGW holds two keys, e.g. to rotate them without downtime
+	ID int `json:"id"`
+	// Whether the operator generates the key
+	Generate bool `json:"generate,omitempty"`
+	// The secret in the namespace of the user holding the TempURLKey to set, instead of generating the key
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// SubuserSpec represents a subuser of an object store user
+type SubuserSpec struct {
+	// The name of the subuser, without the "<user>:" prefix
+	Name string `json:"name"`
+	// The access of the subuser to the buckets of the user
+	// If not set, the subuser has no access
+	Access AccessSpec `json:"access,omitempty"`
+	// The type of the key of the subuser, swift or s3
+	// If not set, the subuser holds a Swift key
+	KeyType UserKeyType `json:"keyType,omitempty"`
+	// Whether RGW generates a key of the key type for the subuser
+	GenerateKey bool `json:"generateKey,omitempty"`
+	// The secret in the namespace of the user holding the key to set as the key of the subuser, the
+	// SecretKey of a Swift key or the AccessKey and SecretKey of an S3 key
+	SecretName string `json:"secretName,omitempty"`
+	// Whether the generated key of the subuser is generated again when the S3 keys of the user rotate
+	RotateWithUser bool `json:"rotateWithUser,omitempty"`
+}
+
+// AccessSpec is the access level of a subuser
+type AccessSpec string
+
+const (
+	// AccessSpecRead allows the subuser to read objects
+	AccessSpecRead AccessSpec = "read"
+	// AccessSpecWrite allows the subuser to write objects
+	AccessSpecWrite AccessSpec = "write"
+	// AccessSpecReadWrite allows the subuser to read and write objects
+	AccessSpecReadWrite AccessSpec = "readwrite"
+	// AccessSpecFull gives the subuser full control, including the access control lists
+	AccessSpecFull AccessSpec = "full"
+)
+
+// ObjectUserQuotaSpec can be used to set quotas for the object store user to limit their usage
+type ObjectUserQuotaSpec struct {
+	// Maximum bucket limit for the ceph user, -1 for unlimited and 0 to disable the creation of buckets
+	MaxBuckets *int `json:"maxBuckets,omitempty"`
+	// Maximum size limit of all objects across all the user's buckets
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+	// Maximum size limit of all objects across all the user's buckets in KB of 1024 bytes, -1 for
+	// unlimited. An alternative to maxSize that is set as is, without the rounding of a quantity.
+	MaxSizeKB *int64 `json:"maxSizeKB,omitempty"`
+	// Maximum size limit of all objects across all the user's buckets in percent of the capacity of
+	// the data pool of the object store, from 1 to 100. An alternative to maxSize that is computed when
+	// the user is reconciled, RGW enforces the resulting size. Not used by the quotas of an object store.
+	MaxSizePercent *int `json:"maxSizePercent,omitempty"`
+	// Maximum number of objects across all the user's buckets
+	MaxObjects *int64 `json:"maxObjects,omitempty"`
+	// Whether RGW enforces the user quota, by default it is enforced if one of its limits is set. Only
+	// setting it enables or disables the quota without changing its limits. Not used by the maximum
+	// quotas of an object store.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Whether the stats of the user are synced after its quota changed, so that RGW enforces the
+	// new quota against the current usage. Not used by the maximum quotas of an object store.
+	SyncStats bool `json:"syncStats,omitempty"`
+	// Whether the user is suspended while its usage exceeds its user quota, and enabled again once its
+	// usage is within the quota, as RGW may only enforce the quota with a lag. The usage is checked each
+	// time the user is reconciled. Not used by the maximum quotas of an object store.
+	SuspendOnBreach bool `json:"suspendOnBreach,omitempty"`
+	// Maximum size limit of the objects of each bucket of the user, with the bucket scope quota of the
+	// user. Not used by the quotas of an object store.
+	DefaultBucketMaxSize *resource.Quantity `json:"defaultBucketMaxSize,omitempty"`
+	// Maximum number of objects of each bucket of the user, with the bucket scope quota of the user. Not
+	// used by the quotas of an object store.
+	DefaultBucketMaxObjects *int64 `json:"defaultBucketMaxObjects,omitempty"`
+}
+
+// ObjectStoreUserStatus represents the status of an object store user
+type ObjectStoreUserStatus struct {
+	Phase      ObjectStoreUserPhase   `json:"phase,omitempty"`
+	Conditions []Condition            `json:"conditions,omitempty"`
+	Quota      *ObjectUserQuotaStatus `json:"quota,omitempty"`
+	// The hash of the spec the user was last reconciled with
+	ObservedSpecHash string `json:"observedSpecHash,omitempty"`
+	// The value of the force resync annotation the user was last reconciled with
+	ObservedForceResync string `json:"observedForceResync,omitempty"`
+	// The value of the debug reconcile annotation the user was last reconciled with
+	ObservedDebugReconcile string `json:"observedDebugReconcile,omitempty"`
+	// The generation of the CR the user was last reconciled with, the user is only ready for the
+	// spec of that generation
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// The S3 keys tracked under the names of the spec
+	Keys []ObjectUserKeyStatus `json:"keys,omitempty"`
+	// The changes a dry run reconcile would make to the user
+	PlannedChanges []string `json:"plannedChanges,omitempty"`
+	// The step of the update of the user that failed in the last reconcile, e.g. "quota"
+	FailedStep string `json:"failedStep,omitempty"`
+	// The outcome of the steps of the update of the user run by the last reconcile, e.g. "quota: Applied"
+	// or "caps: Failed: <error>"
+	ComponentStatus map[string]string `json:"componentStatus,omitempty"`
+	// The CephObjectStoreUsers generated from the template
+	Users []string `json:"users,omitempty"`
+	// The SHA-256 of the data of the secret of the user, which changes whenever the keys in the secret change
+	SecretHash string `json:"secretHash,omitempty"`
+	// The time left until the user expires, e.g. "71h59m30s", empty once it expired
+	ExpiresIn string `json:"expiresIn,omitempty"`
+	// The RGW realm the user is managed in, which is the realm of the object store
+	Realm string `json:"realm,omitempty"`
+	// The uid of the RGW user of the CR
+	UID string `json:"uid,omitempty"`
+	// The time the RGW user is deleted at once the deletion grace period of the deleted CR passed
+	DeletionScheduledAt *metav1.Time `json:"deletionScheduledAt,omitempty"`
+	// The tags of the user as of the last reconcile
+	Tags map[string]string `json:"tags,omitempty"`
+	// The name of the secret holding the keys of the user
+	SecretName string `json:"secretName,omitempty"`
+	// The namespaces the secret of the user was replicated into, to remove the replicas that are no
+	// longer in the spec
+	SecretNamespaces []string `json:"secretNamespaces,omitempty"`
+	// The buckets owned by the user, in alphabetical order and limited to the first 100
+	Buckets []string `json:"buckets,omitempty"`
+	// The number of buckets owned by the user
+	BucketCount int `json:"bucketCount,omitempty"`
+	// The buckets of the spec linked to the user, to unlink the buckets that are no longer in the spec
+	LinkedBuckets []string `json:"linkedBuckets,omitempty"`
+	// The initial buckets of the spec that were created for the user or found owned by it, which are
+	// not created again
+	InitialBuckets []string `json:"initialBuckets,omitempty"`
+	// Whether the max buckets of the spec is set on the user, to restore the RGW default once it is
+	// cleared from the spec
+	MaxBucketsSet bool `json:"maxBucketsSet,omitempty"`
+	// The IDs of the temp URL keys of the spec set on the user, to remove the keys that are no longer in the spec
+	TempURLKeys []int `json:"tempURLKeys,omitempty"`
+	// The types of the admin capabilities of the spec set on the user, e.g. "users", to remove the
+	// capabilities of the user once they are cleared from the spec
+	Capabilities []string `json:"capabilities,omitempty"`
+	// The placement tags of the user as RGW reports them after the last reconcile
+	PlacementTags []string `json:"placementTags,omitempty"`
+	// The quotas of the user as RGW reports them after the last reconcile, including the RGW defaults
+	// of the quotas that are not set in the spec
+	EffectiveQuota *ObjectUserEffectiveQuotaStatus `json:"effectiveQuota,omitempty"`
+	// The usage of the user in percent of the size of its user quota, can exceed 100 for a user over
+	// quota. It is not set without an enforced size quota.
+	QuotaUtilizationPercent *int `json:"quotaUtilizationPercent,omitempty"`
+	// The admin capabilities of the user as RGW reports them after the last reconcile, by type with
+	// their permission, including the capabilities that are not set in the spec
+	EffectiveCapabilities map[string]string `json:"effectiveCapabilities,omitempty"`
+	// The time the RGW user was created, as RGW reports it or else the time the operator created it
+	CreatedAt *metav1.Time `json:"createdAt,omitempty"`
+	// The version of the rgw daemons of the cluster when the operator created the RGW user, empty for
+	// a user created outside of the operator
+	CreatedWithVersion string `json:"createdWithVersion,omitempty"`
+	// The scheduled rotations of the S3 key of the user
+	KeyRotation *ObjectUserKeyRotationStatus `json:"keyRotation,omitempty"`
+	// The counts of the radosgw-admin commands run by the last reconciles of the user, for debugging
+	AdminCommandStats *ObjectUserAdminCommandStats `json:"adminCommandStats,omitempty"`
+}
+
+// ObjectUserAdminCommandStats represents the radosgw-admin commands run by the last reconciles of a user
+type ObjectUserAdminCommandStats struct {
+	// The commands run by each of the last reconciles, the latest last
+	Reconciles []ObjectUserAdminCommandCounts `json:"reconciles,omitempty"`
+	// The error of the last command that failed in these reconciles
+	LastError string `json:"lastError,omitempty"`
+}
+
+// ObjectUserAdminCommandCounts represents the radosgw-admin commands run by a reconcile of a user
+type ObjectUserAdminCommandCounts struct {
+	// The number of commands run, each retry of a command is counted
+	Calls int `json:"calls"`
+	// The number of commands that failed
+	Failures int `json:"failures"`
+}
+
+// ObjectUserKeyRotationStatus represents the scheduled rotations of the S3 key of an object store user
+type ObjectUserKeyRotationStatus struct {
+	// The time the key was last rotated, not set before the first rotation
+	LastRotation *metav1.Time `json:"lastRotation,omitempty"`
+	// The time the key is rotated next
+	NextRotation *metav1.Time `json:"nextRotation,omitempty"`
+	// The access key of the current key, which the secret holds
+	AccessKey string `json:"accessKey,omitempty"`
+}
+
+// ObjectUserKeyStatus represents the S3 key tracked under a name of the spec
+type ObjectUserKeyStatus struct {
+	Name      string `json:"name"`
+	AccessKey string `json:"accessKey"`
+}
+
+// ObjectUserQuotaStatus represents whether the quotas of an object store user are enforced by RGW
+type ObjectUserQuotaStatus struct {
+	// Whether the user scope quota is enabled
+	UserEnforced bool `json:"userEnforced"`
+	// Whether the bucket scope quota is enabled
+	BucketEnforced bool `json:"bucketEnforced"`
+}
+
+// ObjectUserEffectiveQuotaStatus represents the quotas RGW reports for an object store user
+type ObjectUserEffectiveQuotaStatus struct {
+	// The maximum number of buckets of the user, -1 for unlimited and 0 if the user cannot create buckets
+	MaxBuckets int `json:"maxBuckets"`
+	// The user scope quota
+	User ObjectUserQuotaLimits `json:"user"`
+	// The bucket scope quota
+	Bucket ObjectUserQuotaLimits `json:"bucket"`
+}
+
+// ObjectUserQuotaLimits represents the limits of a user or bucket scope quota, -1 for unlimited
+type ObjectUserQuotaLimits struct {
+	// Whether RGW enforces the quota
+	Enabled bool `json:"enabled"`
+	// The maximum size in bytes
+	MaxSize int64 `json:"maxSize"`
+	// The maximum number of objects
+	MaxObjects int64 `json:"maxObjects"`
+}
+
+const (
+	// ConditionQuotaClamped is set when the requested quotas of a user exceed the object store maximum
+	ConditionQuotaClamped ConditionType = "QuotaClamped"
+	// ConditionBucketLinkConflict is set when a bucket to link to a user is linked to another user
+	ConditionBucketLinkConflict ConditionType = "BucketLinkConflict"
+	// ConditionInitialBucketConflict is set when an initial bucket of a user is owned by another user
+	ConditionInitialBucketConflict ConditionType = "InitialBucketConflict"
+	// ConditionExpired is set when a user is suspended because it expired
+	ConditionExpired ConditionType = "Expired"
+	// ConditionOverQuota is set when the usage of a user exceeds its user quota, e.g. after the quota
+	// was lowered below the usage
+	ConditionOverQuota ConditionType = "OverQuota"
+	// ConditionSuspendedOnQuotaBreach is set when a user is suspended because its usage exceeds its user
+	// quota
+	ConditionSuspendedOnQuotaBreach ConditionType = "SuspendedOnQuotaBreach"
+	// ConditionSecretNameCollision is set when the secret name of a user is taken by the secret of another user
+	ConditionSecretNameCollision ConditionType = "SecretNameCollision"
+	// ConditionObjectStoreProgressing is set when a user is reconciled while its object store is not ready,
+	// e.g. while the store is updated
+	ConditionObjectStoreProgressing ConditionType = "ObjectStoreProgressing"
+	// ConditionPaused is set when the reconcile of a user is paused by its annotation
+	ConditionPaused ConditionType = "Paused"
+	// ConditionObjectStoreReadOnly is set when the object store of a user does not accept changes, e.g.
+	// during a planned maintenance
+	ConditionObjectStoreReadOnly ConditionType = "ObjectStoreReadOnly"
+)
+
 type GatewaySpec struct {
 	// The port the rgw service will be listening on (http)
 	Port int32 `json:"port"`
@@ -442,6 +885,10 @@ type GatewaySpec struct {
 
 	// PriorityClassName sets priority classes on the rgw pods
 	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// The endpoint of an rgw that is not run by the operator, e.g. of an external cluster, in the
+	// "http(s)://<host>:<port>" form. The users of the store probe it instead of waiting for rgw pods.
+	ExternalEndpoint string `json:"externalEndpoint,omitempty"`
 }
 
 // +genclient
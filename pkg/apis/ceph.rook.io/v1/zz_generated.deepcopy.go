@@ -23,6 +23,7 @@ package v1
 import (
 	rookiov1 "github.com/rook/rook/pkg/apis/rook.io/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -428,11 +429,11 @@ func (in *CephObjectStoreUser) DeepCopyInto(out *CephObjectStoreUser) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	if in.Status != nil {
 		in, out := &in.Status, &out.Status
-		*out = new(Status)
-		**out = **in
+		*out = new(ObjectStoreUserStatus)
+		(*in).DeepCopyInto(*out)
 	}
 	return
 }
@@ -714,6 +715,22 @@ func (in *DashboardSpec) DeepCopy() *DashboardSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeterministicKeySpec) DeepCopyInto(out *DeterministicKeySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeterministicKeySpec.
+func (in *DeterministicKeySpec) DeepCopy() *DeterministicKeySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DeterministicKeySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DisruptionManagementSpec) DeepCopyInto(out *DisruptionManagementSpec) {
 	*out = *in
@@ -851,6 +868,22 @@ func (in *GatewaySpec) DeepCopy() *GatewaySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyRotationSpec) DeepCopyInto(out *KeyRotationSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyRotationSpec.
+func (in *KeyRotationSpec) DeepCopy() *KeyRotationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyRotationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetadataServerSpec) DeepCopyInto(out *MetadataServerSpec) {
 	*out = *in
@@ -991,6 +1024,21 @@ func (in *ObjectStoreSpec) DeepCopyInto(out *ObjectStoreSpec) {
 	out.MetadataPool = in.MetadataPool
 	out.DataPool = in.DataPool
 	in.Gateway.DeepCopyInto(&out.Gateway)
+	if in.MaxUserQuotas != nil {
+		in, out := &in.MaxUserQuotas, &out.MaxUserQuotas
+		*out = new(ObjectUserQuotaSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DefaultUserQuotas != nil {
+		in, out := &in.DefaultUserQuotas, &out.DefaultUserQuotas
+		*out = new(ObjectUserQuotaSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DefaultUserCapabilities != nil {
+		in, out := &in.DefaultUserCapabilities, &out.DefaultUserCapabilities
+		*out = new(ObjectUserCapSpec)
+		**out = **in
+	}
 	return
 }
 
@@ -1007,6 +1055,101 @@ func (in *ObjectStoreSpec) DeepCopy() *ObjectStoreSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectStoreUserSpec) DeepCopyInto(out *ObjectStoreUserSpec) {
 	*out = *in
+	if in.Quotas != nil {
+		in, out := &in.Quotas, &out.Quotas
+		*out = new(ObjectUserQuotaSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Subusers != nil {
+		in, out := &in.Subusers, &out.Subusers
+		*out = make([]SubuserSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.PlacementTags != nil {
+		in, out := &in.PlacementTags, &out.PlacementTags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]UserKeySpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.TempURLKeys != nil {
+		in, out := &in.TempURLKeys, &out.TempURLKeys
+		*out = make([]TempURLKeySpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.GenerateKey != nil {
+		in, out := &in.GenerateKey, &out.GenerateKey
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DeterministicKey != nil {
+		in, out := &in.DeterministicKey, &out.DeterministicKey
+		*out = new(DeterministicKeySpec)
+		**out = **in
+	}
+	if in.KeyRotation != nil {
+		in, out := &in.KeyRotation, &out.KeyRotation
+		*out = new(KeyRotationSpec)
+		**out = **in
+	}
+	if in.Capabilities != nil {
+		in, out := &in.Capabilities, &out.Capabilities
+		*out = new(ObjectUserCapSpec)
+		**out = **in
+	}
+	if in.DeletionGracePeriod != nil {
+		in, out := &in.DeletionGracePeriod, &out.DeletionGracePeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SecretLabels != nil {
+		in, out := &in.SecretLabels, &out.SecretLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SecretAnnotations != nil {
+		in, out := &in.SecretAnnotations, &out.SecretAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SecretNamespaces != nil {
+		in, out := &in.SecretNamespaces, &out.SecretNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LinkBuckets != nil {
+		in, out := &in.LinkBuckets, &out.LinkBuckets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InitialBuckets != nil {
+		in, out := &in.InitialBuckets, &out.InitialBuckets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -1020,6 +1163,332 @@ func (in *ObjectStoreUserSpec) DeepCopy() *ObjectStoreUserSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStoreUserStatus) DeepCopyInto(out *ObjectStoreUserStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Quota != nil {
+		in, out := &in.Quota, &out.Quota
+		*out = new(ObjectUserQuotaStatus)
+		**out = **in
+	}
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]ObjectUserKeyStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.PlannedChanges != nil {
+		in, out := &in.PlannedChanges, &out.PlannedChanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ComponentStatus != nil {
+		in, out := &in.ComponentStatus, &out.ComponentStatus
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeletionScheduledAt != nil {
+		in, out := &in.DeletionScheduledAt, &out.DeletionScheduledAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SecretNamespaces != nil {
+		in, out := &in.SecretNamespaces, &out.SecretNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Buckets != nil {
+		in, out := &in.Buckets, &out.Buckets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LinkedBuckets != nil {
+		in, out := &in.LinkedBuckets, &out.LinkedBuckets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InitialBuckets != nil {
+		in, out := &in.InitialBuckets, &out.InitialBuckets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TempURLKeys != nil {
+		in, out := &in.TempURLKeys, &out.TempURLKeys
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.Capabilities != nil {
+		in, out := &in.Capabilities, &out.Capabilities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PlacementTags != nil {
+		in, out := &in.PlacementTags, &out.PlacementTags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EffectiveQuota != nil {
+		in, out := &in.EffectiveQuota, &out.EffectiveQuota
+		*out = new(ObjectUserEffectiveQuotaStatus)
+		**out = **in
+	}
+	if in.QuotaUtilizationPercent != nil {
+		in, out := &in.QuotaUtilizationPercent, &out.QuotaUtilizationPercent
+		*out = new(int)
+		**out = **in
+	}
+	if in.EffectiveCapabilities != nil {
+		in, out := &in.EffectiveCapabilities, &out.EffectiveCapabilities
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CreatedAt != nil {
+		in, out := &in.CreatedAt, &out.CreatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.KeyRotation != nil {
+		in, out := &in.KeyRotation, &out.KeyRotation
+		*out = new(ObjectUserKeyRotationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AdminCommandStats != nil {
+		in, out := &in.AdminCommandStats, &out.AdminCommandStats
+		*out = new(ObjectUserAdminCommandStats)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStoreUserStatus.
+func (in *ObjectStoreUserStatus) DeepCopy() *ObjectStoreUserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStoreUserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectUserAdminCommandCounts) DeepCopyInto(out *ObjectUserAdminCommandCounts) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectUserAdminCommandCounts.
+func (in *ObjectUserAdminCommandCounts) DeepCopy() *ObjectUserAdminCommandCounts {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectUserAdminCommandCounts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectUserAdminCommandStats) DeepCopyInto(out *ObjectUserAdminCommandStats) {
+	*out = *in
+	if in.Reconciles != nil {
+		in, out := &in.Reconciles, &out.Reconciles
+		*out = make([]ObjectUserAdminCommandCounts, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectUserAdminCommandStats.
+func (in *ObjectUserAdminCommandStats) DeepCopy() *ObjectUserAdminCommandStats {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectUserAdminCommandStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectUserCapSpec) DeepCopyInto(out *ObjectUserCapSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectUserCapSpec.
+func (in *ObjectUserCapSpec) DeepCopy() *ObjectUserCapSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectUserCapSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectUserEffectiveQuotaStatus) DeepCopyInto(out *ObjectUserEffectiveQuotaStatus) {
+	*out = *in
+	out.User = in.User
+	out.Bucket = in.Bucket
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectUserEffectiveQuotaStatus.
+func (in *ObjectUserEffectiveQuotaStatus) DeepCopy() *ObjectUserEffectiveQuotaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectUserEffectiveQuotaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectUserKeyRotationStatus) DeepCopyInto(out *ObjectUserKeyRotationStatus) {
+	*out = *in
+	if in.LastRotation != nil {
+		in, out := &in.LastRotation, &out.LastRotation
+		*out = (*in).DeepCopy()
+	}
+	if in.NextRotation != nil {
+		in, out := &in.NextRotation, &out.NextRotation
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectUserKeyRotationStatus.
+func (in *ObjectUserKeyRotationStatus) DeepCopy() *ObjectUserKeyRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectUserKeyRotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectUserKeyStatus) DeepCopyInto(out *ObjectUserKeyStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectUserKeyStatus.
+func (in *ObjectUserKeyStatus) DeepCopy() *ObjectUserKeyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectUserKeyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectUserQuotaLimits) DeepCopyInto(out *ObjectUserQuotaLimits) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectUserQuotaLimits.
+func (in *ObjectUserQuotaLimits) DeepCopy() *ObjectUserQuotaLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectUserQuotaLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectUserQuotaSpec) DeepCopyInto(out *ObjectUserQuotaSpec) {
+	*out = *in
+	if in.MaxBuckets != nil {
+		in, out := &in.MaxBuckets, &out.MaxBuckets
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxSize != nil {
+		in, out := &in.MaxSize, &out.MaxSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxSizeKB != nil {
+		in, out := &in.MaxSizeKB, &out.MaxSizeKB
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxSizePercent != nil {
+		in, out := &in.MaxSizePercent, &out.MaxSizePercent
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxObjects != nil {
+		in, out := &in.MaxObjects, &out.MaxObjects
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DefaultBucketMaxSize != nil {
+		in, out := &in.DefaultBucketMaxSize, &out.DefaultBucketMaxSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.DefaultBucketMaxObjects != nil {
+		in, out := &in.DefaultBucketMaxObjects, &out.DefaultBucketMaxObjects
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectUserQuotaSpec.
+func (in *ObjectUserQuotaSpec) DeepCopy() *ObjectUserQuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectUserQuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectUserQuotaStatus) DeepCopyInto(out *ObjectUserQuotaStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectUserQuotaStatus.
+func (in *ObjectUserQuotaStatus) DeepCopy() *ObjectUserQuotaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectUserQuotaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PoolSpec) DeepCopyInto(out *PoolSpec) {
 	*out = *in
@@ -1085,3 +1554,51 @@ func (in *Status) DeepCopy() *Status {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SubuserSpec) DeepCopyInto(out *SubuserSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubuserSpec.
+func (in *SubuserSpec) DeepCopy() *SubuserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SubuserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TempURLKeySpec) DeepCopyInto(out *TempURLKeySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TempURLKeySpec.
+func (in *TempURLKeySpec) DeepCopy() *TempURLKeySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TempURLKeySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserKeySpec) DeepCopyInto(out *UserKeySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserKeySpec.
+func (in *UserKeySpec) DeepCopy() *UserKeySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UserKeySpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -17,11 +17,20 @@ limitations under the License.
 package object
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	osexec "os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/util/exec"
 )
 
 // Context holds the context for the object store.
@@ -29,23 +38,293 @@ type Context struct {
 	Context     *clusterd.Context
 	Name        string
 	ClusterName string
+	// ReadOnly only permits the radosgw-admin commands that read the object store
+	ReadOnly bool
+	// Timeout bounds the RADOS operations of radosgw-admin, so that a command against a degraded
+	// cluster fails with ETIMEDOUT instead of hanging, and the command itself, which is killed if it
+	// still did not complete, e.g. while it connects to the mons. No timeout is set if zero.
+	Timeout time.Duration
+	// ReadRetries is the number of times a read-only command that timed out is retried
+	ReadRetries int
+	// Stats counts the radosgw-admin commands run with the context, not counted if nil. The copies of
+	// the context count into the same stats.
+	Stats *AdminStats
+	// TraceArgs logs every radosgw-admin command with its arguments at debug level, with the keys
+	// redacted
+	TraceArgs bool
 }
 
+// AdminStats are the counts of the radosgw-admin commands run against an object store
+type AdminStats struct {
+	// Calls is the number of commands run, each retry of a command is counted
+	Calls int
+	// Failures is the number of commands that failed
+	Failures int
+	// LastError is the error of the last command that failed
+	LastError string
+	// Trace records every command run in Commands, without its arguments that may hold keys
+	Trace    bool
+	Commands []string
+}
+
+func (s *AdminStats) record(commandName string, err error) {
+	if s == nil {
+		return
+	}
+	s.Calls++
+	if err != nil {
+		s.Failures++
+		s.LastError = fmt.Sprintf("radosgw-admin %s: %v", commandName, err)
+	}
+	if s.Trace {
+		command := fmt.Sprintf("radosgw-admin %s", commandName)
+		if err != nil {
+			command = s.LastError
+		}
+		s.Commands = append(s.Commands, command)
+	}
+}
+
+// secretAdminFlags are the radosgw-admin flags whose value is a key, which is never logged
+var secretAdminFlags = []string{"--access-key", "--secret-key", "--temp-url-key", "--temp-url-key-2"}
+
+func init() {
+	exec.AddSecretFlags(secretAdminFlags...)
+}
+
+// adminOutputKeyPattern matches the keys of a user in the JSON output of radosgw-admin, the "val" being
+// a temp URL key
+var adminOutputKeyPattern = regexp.MustCompile(`("(?:access_key|secret_key|val)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// redactAdminOutput returns the output of a radosgw-admin command with the keys redacted, both the keys
+// of a user in the output and the values of the key flags given in args, should the output repeat them
+func redactAdminOutput(output string, args []string) string {
+	for i, arg := range args {
+		for _, flag := range secretAdminFlags {
+			value := ""
+			if arg == flag && i+1 < len(args) {
+				value = args[i+1]
+			} else if strings.HasPrefix(arg, flag+"=") {
+				value = strings.TrimPrefix(arg, flag+"=")
+			}
+			if value != "" {
+				output = strings.Replace(output, value, "<redacted>", -1)
+			}
+		}
+	}
+	return adminOutputKeyPattern.ReplaceAllString(output, `$1"<redacted>"`)
+}
+
+// adminRetryInterval is the interval before the first retry of a read-only command that timed out,
+// it doubles with every retry
+var adminRetryInterval = time.Second
+
+// adminMaxRetryWait is the most a command waits in total between its retries, which hold the reconcile
+// worker. The command is not retried again once the next wait would exceed it.
+var adminMaxRetryWait = 10 * time.Second
+
+// readOnlyAdminCommands are the radosgw-admin operations that do not modify the object store
+var readOnlyAdminCommands = []string{"get", "info", "list", "stats"}
+
 // NewContext creates a new object store context.
 func NewContext(context *clusterd.Context, name, clusterName string) *Context {
 	return &Context{Context: context, Name: name, ClusterName: clusterName}
 }
 
+// NewReadOnlyContext returns a copy of the object store context that fails the commands that would
+// modify the object store, for the code paths that must only read it
+func NewReadOnlyContext(c *Context) *Context {
+	readOnly := *c
+	readOnly.ReadOnly = true
+	return &readOnly
+}
+
+// isReadOnlyAdminCommand returns whether the radosgw-admin command only reads the object store. The
+// stats of a user are written when they are synced.
+func isReadOnlyAdminCommand(commandName string, args []string) bool {
+	for _, arg := range args {
+		if arg == "--sync-stats" {
+			return false
+		}
+	}
+	parts := strings.Split(commandName, " ")
+	operation := parts[len(parts)-1]
+	for _, readOnly := range readOnlyAdminCommands {
+		if operation == readOnly {
+			return true
+		}
+	}
+	return false
+}
+
 func runAdminCommandNoRealm(c *Context, args ...string) (string, error) {
+	commandName := adminCommandName(args)
+	if c.ReadOnly && !isReadOnlyAdminCommand(commandName, args) {
+		return "", errors.Errorf("refusing to run radosgw-admin %q in a read-only context", commandName)
+	}
+	if c.Timeout > 0 {
+		// The timeouts are whole seconds and 0 disables them, so a sub-second timeout is rounded up
+		timeout := strconv.Itoa(int(math.Ceil(c.Timeout.Seconds())))
+		args = append(args, "--rados-osd-op-timeout="+timeout, "--rados-mon-op-timeout="+timeout)
+	}
+	if c.TraceArgs {
+		logger.Debugf("radosgw-admin %s", strings.Join(exec.RedactArgs(args), " "))
+	}
 	command, args := client.FinalizeCephCommandArgs("radosgw-admin", args, c.Context.ConfigDir, c.ClusterName)
 
-	// start the rgw admin command
-	output, err := c.Context.Executor.ExecuteCommandWithOutput(client.IsDebugLevel(), "", command, args...)
+	// Only the commands that do not modify the object store are safe to retry
+	attempts := 1
+	if isReadOnlyAdminCommand(commandName, args) {
+		attempts += c.ReadRetries
+	}
+	retryInterval, waited := adminRetryInterval, time.Duration(0)
+	for attempt := 1; ; attempt++ {
+		// start the rgw admin command
+		start := time.Now()
+		var output string
+		var err error
+		if c.Timeout > 0 {
+			output, err = c.Context.Executor.ExecuteCommandWithOutputTimeout(client.IsDebugLevel(), c.Timeout, "", command, args...)
+		} else {
+			output, err = c.Context.Executor.ExecuteCommandWithOutput(client.IsDebugLevel(), "", command, args...)
+		}
+		adminCommandDuration.WithLabelValues(c.ClusterName, c.Name, commandName).Observe(time.Since(start).Seconds())
+		c.Stats.record(commandName, err)
+		if err == nil {
+			return output, nil
+		}
+		if attempt >= attempts || !isTimeout(err) || waited+retryInterval > adminMaxRetryWait {
+			return "", adminCommandError(err, output, args)
+		}
+		logger.Warningf("radosgw-admin %q timed out, retrying in %s", commandName, retryInterval.String())
+		time.Sleep(retryInterval)
+		waited += retryInterval
+		retryInterval *= 2
+	}
+}
+
+// ProbeAdmin checks that the admin commands of the object store can be run, with a cheap read of its
+// zone. The commands fail while the cluster or the realm of a store that was just created are not
+// reachable yet.
+func ProbeAdmin(c *Context) error {
+	_, err := runAdminCommand(c, "zone", "get", fmt.Sprintf("--rgw-zone=%s", c.Name))
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to run radosgw-admin")
+		return errors.Wrapf(err, "failed to get rgw zone for %s", c.Name)
 	}
+	return nil
+}
+
+// isTimeout returns whether the radosgw-admin command failed because a RADOS operation timed out, or
+// was killed because it did not complete within the timeout
+func isTimeout(err error) bool {
+	cmdErr, ok := err.(*exec.CommandError)
+	return ok && (cmdErr.ExitStatus() == int(syscall.ETIMEDOUT) || cmdErr.IsTimeout())
+}
 
-	return output, nil
+// outputSnippetLength is the length of the output of a radosgw-admin command kept in the error when
+// the output is not the expected JSON
+const outputSnippetLength = 200
+
+// UnexpectedOutputError is the error of a radosgw-admin command that succeeded but did not print JSON,
+// e.g. the error page of a proxy in front of an external cluster or the messages of a daemon that is
+// restarting. The output is cut to a snippet, which usually carries the actual failure.
+type UnexpectedOutputError struct {
+	Snippet string
+}
+
+func (e *UnexpectedOutputError) Error() string {
+	return fmt.Sprintf("radosgw-admin output is not json: %q", e.Snippet)
+}
+
+// decodeAdminOutput decodes the JSON output of a radosgw-admin command. An output that is not JSON at
+// all is reported with a snippet of it instead of the error of the JSON decoder, which hides it.
+func decodeAdminOutput(output string, v interface{}) error {
+	trimmed := strings.TrimSpace(output)
+	if trimmed != "" && trimmed[0] != '{' && trimmed[0] != '[' && trimmed[0] != '"' {
+		snippet := strings.Join(strings.Fields(redactAdminOutput(trimmed, nil)), " ")
+		if len(snippet) > outputSnippetLength {
+			snippet = snippet[:outputSnippetLength] + "..."
+		}
+		return &UnexpectedOutputError{Snippet: snippet}
+	}
+	return json.Unmarshal([]byte(output), v)
+}
+
+// IsTransientError returns whether the radosgw-admin command failed because the cluster could not be
+// reached or did not answer in time, so that running it again later may succeed. An output that is not
+// JSON is counted as transient, it is not an answer of RGW to the command.
+func IsTransientError(err error) bool {
+	if _, ok := errors.Cause(err).(*UnexpectedOutputError); ok {
+		return true
+	}
+	cmdErr, ok := errors.Cause(err).(*exec.CommandError)
+	if !ok {
+		return false
+	}
+	if cmdErr.IsTimeout() {
+		return true
+	}
+	switch syscall.Errno(cmdErr.ExitStatus()) {
+	case syscall.ETIMEDOUT, syscall.ECONNREFUSED, syscall.EAGAIN, syscall.EBUSY:
+		return true
+	}
+	return false
+}
+
+// IsPermissionDenied returns whether the radosgw-admin command failed because the ceph credentials it
+// ran with lack the caps for it, which retrying does not fix
+func IsPermissionDenied(err error) bool {
+	cmdErr, ok := errors.Cause(err).(*exec.CommandError)
+	if !ok {
+		return false
+	}
+	switch syscall.Errno(cmdErr.ExitStatus()) {
+	case syscall.EACCES, syscall.EPERM:
+		return true
+	}
+	return false
+}
+
+// IsReadOnly returns whether the radosgw-admin command failed because the object store does not accept
+// changes, e.g. while its zone is read-only for a maintenance. Reads still succeed.
+func IsReadOnly(err error) bool {
+	cmdErr, ok := errors.Cause(err).(*exec.CommandError)
+	return ok && cmdErr.ExitStatus() == int(syscall.EROFS)
+}
+
+// adminCommandName returns the name of a radosgw-admin command without its options, e.g. "user create"
+func adminCommandName(args []string) string {
+	name := []string{}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") || len(name) == 2 {
+			break
+		}
+		name = append(name, arg)
+	}
+	return strings.Join(name, " ")
+}
+
+// adminCommandError wraps the error of a failed radosgw-admin command with its exit code and output,
+// which carry the reason why RGW rejected the command. The keys in the output are redacted, since the
+// error ends up in the logs, the status and the events of the user.
+func adminCommandError(err error, output string, args []string) error {
+	msg := "failed to run radosgw-admin"
+	if cmdErr, ok := err.(*exec.CommandError); ok {
+		if exitErr, ok := cmdErr.Err.(*osexec.ExitError); ok {
+			// the stderr is part of the message of the error
+			exitErr.Stderr = []byte(redactAdminOutput(string(exitErr.Stderr), args))
+		}
+		if cmdErr.IsTimeout() {
+			msg = fmt.Sprintf("%s, killed after it did not complete in time", msg)
+		}
+		if code := cmdErr.ExitStatus(); code > 0 {
+			msg = fmt.Sprintf("%s, exit code %d (%s)", msg, code, syscall.Errno(code).Error())
+		}
+	}
+	if output != "" {
+		msg = fmt.Sprintf("%s, output %q", msg, redactAdminOutput(output, args))
+	}
+	return errors.Wrap(err, msg)
 }
 
 func runAdminCommand(c *Context, args ...string) (string, error) {
@@ -0,0 +1,332 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package object
+
+import (
+	"bytes"
+	"context"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/pkg/errors"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/exec"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminCommandError(t *testing.T) {
+	// radosgw-admin exits with the errno of the failure, e.g. EEXIST
+	_, exitErr := osexec.Command("sh", "-c", "echo 'user: my-user exists' >&2; exit 17").Output()
+	cmdErr := &exec.CommandError{ActionName: "radosgw-admin", Err: exitErr}
+
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			return "could not create user", cmdErr
+		},
+	}
+	c := NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook-ceph")
+
+	_, err := runAdminCommand(c, "user", "create", "--uid", "my-user")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exit code 17 (file exists)")
+	assert.Contains(t, err.Error(), `output "could not create user"`)
+	assert.Contains(t, err.Error(), "user: my-user exists")
+	assert.Equal(t, 17, errors.Cause(err).(*exec.CommandError).ExitStatus())
+
+	// errors that are not from the command itself are only wrapped
+	err = adminCommandError(errors.New("timeout"), "", nil)
+	assert.Equal(t, "failed to run radosgw-admin: timeout", err.Error())
+}
+
+func TestReadOnlyContext(t *testing.T) {
+	var commands [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			commands = append(commands, args)
+			return "", nil
+		},
+	}
+	writable := NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook-ceph")
+	c := NewReadOnlyContext(writable)
+
+	_, err := runAdminCommand(c, "user", "info", "--uid", "my-user")
+	assert.NoError(t, err)
+	_, err = runAdminCommand(c, "zonegroup", "get")
+	assert.NoError(t, err)
+	assert.Len(t, commands, 2)
+
+	// the mutating commands fail without being run
+	_, err = runAdminCommand(c, "user", "modify", "--uid", "my-user", "--max-buckets", "5")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `refusing to run radosgw-admin "user modify" in a read-only context`)
+	_, err = runAdminCommand(c, "caps", "add", "--uid", "my-user", "--caps", "users=*")
+	assert.Error(t, err)
+	_, err = runAdminCommand(c, "user", "stats", "--uid", "my-user", "--sync-stats")
+	assert.Error(t, err)
+	assert.Len(t, commands, 2)
+
+	// the context it was copied from is not read-only
+	_, err = runAdminCommand(writable, "user", "modify", "--uid", "my-user", "--max-buckets", "5")
+	assert.NoError(t, err)
+	assert.Len(t, commands, 3)
+}
+
+func TestAdminCommandTimeout(t *testing.T) {
+	adminRetryInterval = time.Millisecond
+	_, exitErr := osexec.Command("sh", "-c", "exit 110").Output()
+	timeoutErr := &exec.CommandError{ActionName: "radosgw-admin", Err: exitErr}
+
+	var commands [][]string
+	var timeouts []time.Duration
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputTimeout: func(debug bool, timeout time.Duration, actionName, command string, args ...string) (string, error) {
+			commands = append(commands, args)
+			timeouts = append(timeouts, timeout)
+			return "", timeoutErr
+		},
+	}
+	c := NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook-ceph")
+	c.Timeout = 30 * time.Second
+	c.ReadRetries = 2
+
+	// a read that timed out is retried, the command itself is bounded as well
+	_, err := runAdminCommand(c, "user", "info", "--uid", "my-user")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exit code 110 (connection timed out)")
+	assert.Len(t, commands, 3)
+	assert.Contains(t, commands[0], "--rados-osd-op-timeout=30")
+	assert.Contains(t, commands[0], "--rados-mon-op-timeout=30")
+	assert.Equal(t, 30*time.Second, timeouts[0])
+
+	// a sub-second timeout is rounded up, 0 would disable it
+	commands = nil
+	c.Timeout = 500 * time.Millisecond
+	_, err = runAdminCommand(c, "user", "modify", "--uid", "my-user")
+	assert.Error(t, err)
+	assert.Contains(t, commands[0], "--rados-osd-op-timeout=1")
+	assert.Contains(t, commands[0], "--rados-mon-op-timeout=1")
+	c.Timeout = 30 * time.Second
+
+	// a write is not
+	commands = nil
+	_, err = runAdminCommand(c, "user", "modify", "--uid", "my-user")
+	assert.Error(t, err)
+	assert.Len(t, commands, 1)
+
+	// a read that was killed because it did not complete in time is retried
+	commands = nil
+	executor.MockExecuteCommandWithOutputTimeout = func(debug bool, timeout time.Duration, actionName, command string, args ...string) (string, error) {
+		commands = append(commands, args)
+		return "", &exec.CommandError{ActionName: "radosgw-admin", Err: context.DeadlineExceeded}
+	}
+	_, err = runAdminCommand(c, "user", "info", "--uid", "my-user")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "killed after it did not complete in time")
+	assert.True(t, IsTransientError(err))
+	assert.Len(t, commands, 3)
+
+	// the retries stop once they would wait too long in total
+	commands = nil
+	c.ReadRetries = 10
+	adminMaxRetryWait = 3 * time.Millisecond
+	_, err = runAdminCommand(c, "user", "info", "--uid", "my-user")
+	assert.Error(t, err)
+	assert.Len(t, commands, 3)
+	adminMaxRetryWait = 10 * time.Second
+	c.ReadRetries = 2
+
+	// nor is a read that failed for another reason
+	commands = nil
+	executor.MockExecuteCommandWithOutputTimeout = func(debug bool, timeout time.Duration, actionName, command string, args ...string) (string, error) {
+		commands = append(commands, args)
+		return "", errors.New("failed")
+	}
+	_, err = runAdminCommand(c, "user", "info", "--uid", "my-user")
+	assert.Error(t, err)
+	assert.Len(t, commands, 1)
+
+	// no timeout is passed unless one is set
+	commands = nil
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName, command string, args ...string) (string, error) {
+		commands = append(commands, args)
+		return "", nil
+	}
+	c = NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook-ceph")
+	_, _ = runAdminCommand(c, "user", "info", "--uid", "my-user")
+	assert.NotContains(t, strings.Join(commands[0], " "), "timeout")
+}
+
+func TestAdminStats(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[1] == "modify" {
+				return "", errors.New("invalid argument")
+			}
+			return "", nil
+		},
+	}
+	c := NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook-ceph")
+	c.Stats = &AdminStats{}
+
+	_, err := runAdminCommand(c, "user", "info", "--uid", "my-user")
+	assert.NoError(t, err)
+	_, err = runAdminCommand(NewReadOnlyContext(c), "user", "info", "--uid", "my-user")
+	assert.NoError(t, err)
+	_, err = runAdminCommand(c, "user", "modify", "--uid", "my-user")
+	assert.Error(t, err)
+	assert.Equal(t, AdminStats{Calls: 3, Failures: 1, LastError: "radosgw-admin user modify: invalid argument"}, *c.Stats)
+
+	// a refused command is not run, so it is not counted
+	_, err = runAdminCommand(NewReadOnlyContext(c), "user", "modify", "--uid", "my-user")
+	assert.Error(t, err)
+	assert.Equal(t, 3, c.Stats.Calls)
+}
+
+func TestRedactAdminArgs(t *testing.T) {
+	args := []string{"key", "create", "--uid", "my-user", "--key-type", "s3", "--access-key", "AK", "--secret-key", "SK"}
+	redacted := exec.RedactArgs(args)
+	assert.Equal(t, []string{"key", "create", "--uid", "my-user", "--key-type", "s3", "--access-key", "<redacted>", "--secret-key", "<redacted>"}, redacted)
+	// the arguments of the command are not changed
+	assert.Equal(t, "AK", args[7])
+
+	redacted = exec.RedactArgs([]string{"user", "modify", "--uid", "my-user", "--temp-url-key=K1", "--temp-url-key-2", "K2", "--max-buckets", "10"})
+	assert.Equal(t, []string{"user", "modify", "--uid", "my-user", "--temp-url-key=<redacted>", "--temp-url-key-2", "<redacted>", "--max-buckets", "10"}, redacted)
+	for _, arg := range redacted {
+		assert.NotContains(t, []string{"K1", "K2"}, arg)
+	}
+}
+
+func TestAdminKeysNotLogged(t *testing.T) {
+	var buf bytes.Buffer
+	capnslog.SetFormatter(capnslog.NewStringFormatter(&buf))
+	defer capnslog.SetFormatter(capnslog.NewDefaultFormatter(os.Stderr))
+	capnslog.SetGlobalLogLevel(capnslog.DEBUG)
+	defer capnslog.SetGlobalLogLevel(capnslog.INFO)
+	secrets := []string{"AK-SECRET", "SK-SECRET", "TK-SECRET"}
+
+	// the command that is run is logged without its keys
+	c := NewContext(&clusterd.Context{Executor: &exec.CommandExecutor{}}, "my-store", "rook-ceph")
+	c.TraceArgs = true
+	_, err := runAdminCommand(c, "key", "create", "--uid", "my-user", "--access-key", "AK-SECRET", "--secret-key=SK-SECRET")
+	assert.Error(t, err)
+	_, err = runAdminCommand(c, "user", "modify", "--uid", "my-user", "--temp-url-key", "TK-SECRET")
+	assert.Error(t, err)
+	assert.Contains(t, buf.String(), "Running command: radosgw-admin key create --uid my-user --access-key <redacted> --secret-key=<redacted>")
+	for _, secret := range secrets {
+		assert.NotContains(t, buf.String(), secret)
+		assert.NotContains(t, err.Error(), secret)
+	}
+
+	// nor are they in the error, from either the output or the stderr of the command
+	_, exitErr := osexec.Command("sh", "-c", "echo 'could not set key SK-SECRET' >&2; exit 22").Output()
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			return `{"keys": [{"user": "my-user", "access_key": "AK-OTHER", "secret_key": "SK-OTHER"}], "temp_url_keys": [{"key": 0, "val": "TK-OTHER"}]}`,
+				&exec.CommandError{ActionName: "radosgw-admin", Err: exitErr}
+		},
+	}
+	c = NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook-ceph")
+	_, err = runAdminCommand(c, "key", "create", "--uid", "my-user", "--secret-key", "SK-SECRET")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "could not set key <redacted>")
+	assert.Contains(t, err.Error(), `\"access_key\": \"<redacted>\"`)
+	for _, secret := range append(secrets, "AK-OTHER", "SK-OTHER", "TK-OTHER") {
+		assert.NotContains(t, err.Error(), secret)
+	}
+
+	// nor in the snippet of an output that is not JSON
+	err = decodeAdminOutput(`warning: {"secret_key": "SK-OTHER"}`, &struct{}{})
+	assert.Equal(t, `warning: {"secret_key": "<redacted>"}`, err.(*UnexpectedOutputError).Snippet)
+}
+
+func TestSetKeysNotLogged(t *testing.T) {
+	var buf bytes.Buffer
+	capnslog.SetFormatter(capnslog.NewStringFormatter(&buf))
+	defer capnslog.SetFormatter(capnslog.NewDefaultFormatter(os.Stderr))
+	capnslog.SetGlobalLogLevel(capnslog.DEBUG)
+	defer capnslog.SetGlobalLogLevel(capnslog.INFO)
+
+	c := NewContext(&clusterd.Context{Executor: &exec.CommandExecutor{}}, "my-store", "rook-ceph")
+	displayName, accessKey, secretKey := "my user", "MY-ACCESS-KEY", "MY-SECRET-KEY"
+	setKeys := map[string]func() error{
+		"create user": func() error {
+			_, _, err := CreateUser(c, ObjectUser{UserID: "my-user", DisplayName: &displayName, AccessKey: &accessKey, SecretKey: &secretKey})
+			return err
+		},
+		"set key": func() error {
+			_, _, err := SetKey(c, "my-user", accessKey, secretKey)
+			return err
+		},
+		"set swift key": func() error {
+			_, _, err := SetSwiftKey(c, "my-user", "my-user:swift", secretKey)
+			return err
+		},
+		"set subuser key": func() error {
+			_, _, err := SetSubuserKey(c, "my-user", "my-user:s3", accessKey, secretKey)
+			return err
+		},
+		"set temp url key": func() error {
+			_, _, err := SetTempURLKey(c, "my-user", 0, secretKey)
+			return err
+		},
+		"set second temp url key": func() error {
+			_, _, err := SetTempURLKey(c, "my-user", 1, secretKey)
+			return err
+		},
+	}
+	for name, setKey := range setKeys {
+		t.Run(name, func(t *testing.T) {
+			buf.Reset()
+			err := setKey()
+			assert.Error(t, err)
+			assert.Contains(t, buf.String(), "Running command: radosgw-admin")
+			assert.NotContains(t, buf.String(), secretKey)
+			assert.NotContains(t, err.Error(), secretKey)
+		})
+	}
+}
+
+func TestUnexpectedAdminOutput(t *testing.T) {
+	page := "<html>\n<head><title>503 Service Temporarily Unavailable</title></head>\n<body>\n<center><h1>503 Service Temporarily Unavailable</h1></center>\n</body>\n</html>\n"
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			return page, nil
+		},
+	}
+	c := NewContext(&clusterd.Context{Executor: executor}, "my-store", "rook-ceph")
+
+	_, rgwerr, err := GetUser(c, "my-user")
+	assert.Error(t, err)
+	assert.Equal(t, RGWErrorParse, rgwerr)
+	assert.Contains(t, err.Error(), `output is not json: "<html> <head><title>503 Service Temporarily Unavailable</title></head>`)
+	assert.True(t, IsTransientError(err))
+
+	// the snippet is cut
+	err = decodeAdminOutput(strings.Repeat("x", 300), &struct{}{})
+	assert.Equal(t, outputSnippetLength+3, len(err.(*UnexpectedOutputError).Snippet))
+
+	// malformed JSON is reported by the decoder and is not transient
+	err = decodeAdminOutput(`{"user_id":`, &struct{}{})
+	assert.Error(t, err)
+	assert.False(t, IsTransientError(err))
+	assert.NoError(t, decodeAdminOutput(`{"user_id":"my-user"}`, &struct{}{}))
+}
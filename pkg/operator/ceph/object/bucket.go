@@ -170,6 +170,20 @@ func ListBuckets(c *Context) ([]ObjectBucket, error) {
 	return buckets, nil
 }
 
+// ListUserBuckets returns the names of the buckets owned by the user
+func ListUserBuckets(c *Context, id string) ([]string, error) {
+	result, err := runAdminCommand(c, "bucket", "list", "--uid", id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list buckets of user %q", id)
+	}
+
+	var buckets []string
+	if err := json.Unmarshal([]byte(result), &buckets); err != nil {
+		return nil, errors.Wrapf(err, "failed to read buckets of user %q result=%s", id, result)
+	}
+	return buckets, nil
+}
+
 func GetBucket(c *Context, bucket string) (*ObjectBucket, int, error) {
 	stat, notFound, err := GetBucketStats(c, bucket)
 	if notFound {
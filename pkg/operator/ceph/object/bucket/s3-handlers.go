@@ -82,6 +82,19 @@ func (s S3Agent) CreateBucket(name string) error {
 	return nil
 }
 
+// EnableBucketVersioning enables the versioning of the bucket with the given name
+func (s S3Agent) EnableBucketVersioning(name string) error {
+	logger.Infof("enabling versioning of bucket %q", name)
+	_, err := s.client.PutBucketVersioning(&s3.PutBucketVersioningInput{
+		Bucket:                  &name,
+		VersioningConfiguration: &s3.VersioningConfiguration{Status: aws.String(s3.BucketVersioningStatusEnabled)},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "versioning of bucket %q could not be enabled", name)
+	}
+	return nil
+}
+
 // PutBucketPolicy applies the policy to the bucket
 func (s S3Agent) PutBucketPolicy(bucket string, policy BucketPolicy) (*s3.PutBucketPolicyOutput, error) {
 
@@ -0,0 +1,33 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package object
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var adminCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "rook_ceph_object_admin_command_duration_seconds",
+	Help:    "Duration of the radosgw-admin commands run against an object store",
+	Buckets: prometheus.DefBuckets,
+}, []string{"namespace", "store", "command"})
+
+func init() {
+	// The metrics are served on the metrics endpoint of the controller-runtime manager
+	metrics.Registry.MustRegister(adminCommandDuration)
+}
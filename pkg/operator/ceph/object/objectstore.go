@@ -47,6 +47,18 @@ var (
 	}
 )
 
+// The errors the controllers of the object store resources return when an object store cannot be used.
+// They are wrapped with the details, so compare them with the cause of an error, errors.Cause(err).
+var (
+	// ErrObjectStoreNotFound is returned when the CephObjectStore does not exist
+	ErrObjectStoreNotFound = errors.New("object store not found")
+	// ErrObjectStoreNotReady is returned when the CephObjectStore exists but cannot be used yet, e.g.
+	// its external endpoint is unreachable
+	ErrObjectStoreNotReady = errors.New("object store not ready")
+	// ErrRGWNotRunning is returned when no rgw pod of the CephObjectStore runs
+	ErrRGWNotRunning = errors.New("rgw not running")
+)
+
 type idType struct {
 	ID string `json:"id"`
 }
@@ -197,6 +209,59 @@ func decodeID(data string) (string, error) {
 	return id.ID, err
 }
 
+type zoneGroupType struct {
+	PlacementTargets []struct {
+		Name           string   `json:"name"`
+		StorageClasses []string `json:"storage_classes"`
+		Tags           []string `json:"tags"`
+	} `json:"placement_targets"`
+}
+
+// getZoneGroup returns the zone group of the object store
+func getZoneGroup(context *Context) (*zoneGroupType, error) {
+	output, err := runAdminCommand(context, "zonegroup", "get")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get rgw zonegroup for %s", context.Name)
+	}
+
+	var zoneGroup zoneGroupType
+	err = json.Unmarshal([]byte(output), &zoneGroup)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to unmarshal zone group")
+	}
+	return &zoneGroup, nil
+}
+
+// GetPlacementTargets returns the placement targets of the zone group of the object store with their storage classes
+func GetPlacementTargets(context *Context) (map[string][]string, error) {
+	zoneGroup, err := getZoneGroup(context)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := map[string][]string{}
+	for _, target := range zoneGroup.PlacementTargets {
+		targets[target.Name] = target.StorageClasses
+	}
+	return targets, nil
+}
+
+// GetPlacementTags returns the tags of the placement targets of the zone group of the object store
+func GetPlacementTags(context *Context) (map[string]bool, error) {
+	zoneGroup, err := getZoneGroup(context)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string]bool{}
+	for _, target := range zoneGroup.PlacementTargets {
+		for _, tag := range target.Tags {
+			tags[tag] = true
+		}
+	}
+	return tags, nil
+}
+
 func getObjectStores(context *Context) ([]string, error) {
 	output, err := runAdminCommandNoRealm(context, "realm", "list")
 	if err != nil {
@@ -294,6 +359,11 @@ func createSimilarPools(context *Context, pools []string, poolSpec model.Pool) e
 	return nil
 }
 
+// DataPoolName returns the name of the pool holding the objects of the object store
+func DataPoolName(storeName string) string {
+	return poolName(storeName, dataPools[0])
+}
+
 func poolName(storeName, poolName string) string {
 	if strings.HasPrefix(poolName, ".") {
 		return poolName
@@ -17,11 +17,13 @@ limitations under the License.
 package object
 
 import (
-	"encoding/json"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/rook/rook/pkg/util/exec"
 )
 
 const (
@@ -35,11 +37,59 @@ const (
 
 // An ObjectUser defines the details of an object store user.
 type ObjectUser struct {
-	UserID      string  `json:"userId"`
-	DisplayName *string `json:"displayName"`
-	Email       *string `json:"email"`
-	AccessKey   *string `json:"accessKey"`
-	SecretKey   *string `json:"secretKey"`
+	UserID      string           `json:"userId"`
+	DisplayName *string          `json:"displayName"`
+	Email       *string          `json:"email"`
+	AccessKey   *string          `json:"accessKey"`
+	SecretKey   *string          `json:"secretKey"`
+	OpMask      *string          `json:"opMask"`
+	MaxBuckets  *int             `json:"maxBuckets"`
+	UserQuota   *ObjectUserQuota `json:"userQuota"`
+	BucketQuota *ObjectUserQuota `json:"bucketQuota"`
+	Subusers    []ObjectSubuser  `json:"subusers"`
+	Keys        []ObjectUserKey  `json:"keys"`
+	// The default placement target and storage class of the new buckets of the user
+	DefaultPlacement    *string `json:"defaultPlacement"`
+	DefaultStorageClass *string `json:"defaultStorageClass"`
+	// The placement tags of the user, which allow it to use the placement targets with these tags. No
+	// tags leave the tags of the user untouched, RGW cannot clear them.
+	PlacementTags []string `json:"placementTags"`
+	// The admin capabilities of the user by type, e.g. "users", with the permission as RGW reports it
+	Caps map[string]string `json:"caps"`
+	// The Swift temp URL keys of the user by ID, a key that is not set is missing
+	TempURLKeys map[int]string `json:"tempURLKeys"`
+	// Whether the user is suspended, which denies all of its requests
+	Suspended *bool `json:"suspended"`
+	// The time the user was created, only reported by newer versions of RGW
+	CreateDate *time.Time `json:"createDate"`
+}
+
+// An ObjectUserKey defines an S3 key of an object store user.
+type ObjectUserKey struct {
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+}
+
+// An ObjectSubuser defines a subuser of an object store user.
+type ObjectSubuser struct {
+	// The ID of the subuser, in the "<user>:<name>" form
+	ID string `json:"id"`
+	// The permission of the subuser as RGW reports it, e.g. "full-control"
+	Permissions string `json:"permissions"`
+	// The secret key of the Swift key of the subuser, empty if it has none
+	SwiftKey string `json:"swiftKey"`
+	// The S3 keys of the subuser
+	Keys []ObjectUserKey `json:"keys"`
+}
+
+// An ObjectUserQuota defines the user or bucket scope quota of an object store user. A negative limit means unlimited.
+type ObjectUserQuota struct {
+	Enabled    bool  `json:"enabled"`
+	MaxSize    int64 `json:"maxSize"`
+	MaxObjects int64 `json:"maxObjects"`
+	// Whether the max size is set in KB with max-size-kb rather than in bytes. RGW reports the max size
+	// in bytes either way.
+	SizeInKB bool `json:"sizeInKB,omitempty"`
 }
 
 // ListUsers lists the object pool users.
@@ -50,8 +100,8 @@ func ListUsers(c *Context) ([]string, int, error) {
 	}
 
 	var s []string
-	if err := json.Unmarshal([]byte(result), &s); err != nil {
-		return nil, RGWErrorParse, errors.Wrapf(err, "failed to read users info result=%s", result)
+	if err := decodeAdminOutput(result, &s); err != nil {
+		return nil, RGWErrorParse, errors.Wrap(err, "failed to read users info")
 	}
 
 	return s, RGWErrorNone, nil
@@ -61,29 +111,121 @@ type rgwUserInfo struct {
 	UserID      string `json:"user_id"`
 	DisplayName string `json:"display_name"`
 	Email       string `json:"email"`
-	Keys        []struct {
+	OpMask      string `json:"op_mask"`
+	MaxBuckets  int    `json:"max_buckets"`
+	Suspended   int    `json:"suspended"`
+	// The default placement is only reported by newer versions of RGW
+	DefaultPlacement    string   `json:"default_placement"`
+	DefaultStorageClass string   `json:"default_storage_class"`
+	PlacementTags       []string `json:"placement_tags"`
+	Subusers            []struct {
+		ID          string `json:"id"`
+		Permissions string `json:"permissions"`
+	} `json:"subusers"`
+	Keys []struct {
+		User      string `json:"user"`
 		AccessKey string `json:"access_key"`
 		SecretKey string `json:"secret_key"`
 	}
+	SwiftKeys []struct {
+		User      string `json:"user"`
+		SecretKey string `json:"secret_key"`
+	} `json:"swift_keys"`
+	UserQuota   rgwQuotaInfo `json:"user_quota"`
+	BucketQuota rgwQuotaInfo `json:"bucket_quota"`
+	Caps        []struct {
+		Type string `json:"type"`
+		Perm string `json:"perm"`
+	} `json:"caps"`
+	TempURLKeys []struct {
+		Key int    `json:"key"`
+		Val string `json:"val"`
+	} `json:"temp_url_keys"`
+	// The creation date is only reported by newer versions of RGW
+	CreateDate string `json:"create_date"`
+}
+
+type rgwQuotaInfo struct {
+	Enabled    bool  `json:"enabled"`
+	MaxSize    int64 `json:"max_size"`
+	MaxObjects int64 `json:"max_objects"`
 }
 
 func decodeUser(data string) (*ObjectUser, int, error) {
 	var user rgwUserInfo
-	err := json.Unmarshal([]byte(data), &user)
+	err := decodeAdminOutput(data, &user)
 	if err != nil {
 		return nil, RGWErrorParse, errors.Wrapf(err, "Failed to unmarshal json")
 	}
 
-	rookUser := ObjectUser{UserID: user.UserID, DisplayName: &user.DisplayName, Email: &user.Email}
+	rookUser := ObjectUser{UserID: user.UserID, DisplayName: &user.DisplayName, Email: &user.Email, OpMask: &user.OpMask, MaxBuckets: &user.MaxBuckets}
+	suspended := user.Suspended != 0
+	rookUser.Suspended = &suspended
+	rookUser.DefaultPlacement = &user.DefaultPlacement
+	rookUser.DefaultStorageClass = &user.DefaultStorageClass
+	rookUser.PlacementTags = append([]string{}, user.PlacementTags...)
+	rookUser.UserQuota = &ObjectUserQuota{
+		Enabled:    user.UserQuota.Enabled,
+		MaxSize:    user.UserQuota.MaxSize,
+		MaxObjects: user.UserQuota.MaxObjects,
+	}
+	rookUser.BucketQuota = &ObjectUserQuota{
+		Enabled:    user.BucketQuota.Enabled,
+		MaxSize:    user.BucketQuota.MaxSize,
+		MaxObjects: user.BucketQuota.MaxObjects,
+	}
+
+	rookUser.Caps = map[string]string{}
+	for _, c := range user.Caps {
+		rookUser.Caps[c.Type] = c.Perm
+	}
+
+	// RGW ignores an empty temp URL key, it is how a key is removed
+	rookUser.TempURLKeys = map[int]string{}
+	for _, key := range user.TempURLKeys {
+		if key.Val != "" {
+			rookUser.TempURLKeys[key.Key] = key.Val
+		}
+	}
+
+	swiftKeys := map[string]string{}
+	for _, key := range user.SwiftKeys {
+		swiftKeys[key.User] = key.SecretKey
+	}
+	subuserKeys := map[string][]ObjectUserKey{}
+	for _, key := range user.Keys {
+		if key.User != "" && key.User != user.UserID {
+			subuserKeys[key.User] = append(subuserKeys[key.User], ObjectUserKey{AccessKey: key.AccessKey, SecretKey: key.SecretKey})
+		}
+	}
+	for _, subuser := range user.Subusers {
+		rookUser.Subusers = append(rookUser.Subusers, ObjectSubuser{ID: subuser.ID, Permissions: subuser.Permissions, SwiftKey: swiftKeys[subuser.ID], Keys: subuserKeys[subuser.ID]})
+	}
 
-	if len(user.Keys) > 0 {
-		rookUser.AccessKey = &user.Keys[0].AccessKey
-		rookUser.SecretKey = &user.Keys[0].SecretKey
+	// The keys of the subusers are listed as well, only keep the top-level keys of the user
+	for _, key := range user.Keys {
+		if key.User == "" || key.User == user.UserID {
+			rookUser.Keys = append(rookUser.Keys, ObjectUserKey{AccessKey: key.AccessKey, SecretKey: key.SecretKey})
+		}
+	}
+	if len(rookUser.Keys) > 0 {
+		rookUser.AccessKey = &rookUser.Keys[0].AccessKey
+		rookUser.SecretKey = &rookUser.Keys[0].SecretKey
+	}
+	// A creation date in an unknown format is not reported rather than failing to read the user
+	if createDate, err := time.Parse(time.RFC3339Nano, user.CreateDate); err == nil {
+		rookUser.CreateDate = &createDate
 	}
 
 	return &rookUser, RGWErrorNone, nil
 }
 
+// ParseUserInfo reads a user from the JSON radosgw-admin prints for it, e.g. the output of "user info"
+func ParseUserInfo(data string) (*ObjectUser, error) {
+	user, _, err := decodeUser(data)
+	return user, err
+}
+
 // GetUser returns the user with the given ID.
 func GetUser(c *Context, id string) (*ObjectUser, int, error) {
 	logger.Infof("Getting user: %s", id)
@@ -122,8 +264,26 @@ func CreateUser(c *Context, user ObjectUser) (*ObjectUser, int, error) {
 		args = append(args, "--email", *user.Email)
 	}
 
+	if user.OpMask != nil {
+		args = append(args, "--op-mask", *user.OpMask)
+	}
+
+	if user.MaxBuckets != nil {
+		args = append(args, "--max-buckets", strconv.Itoa(*user.MaxBuckets))
+	}
+
+	// RGW generates the key of the user unless it is given
+	if user.AccessKey != nil && user.SecretKey != nil {
+		args = append(args, "--access-key", *user.AccessKey, "--secret-key", *user.SecretKey)
+	}
+
 	result, err := runAdminCommand(c, args...)
 	if err != nil {
+		// A user created concurrently, e.g. by another operator or before a restart, makes radosgw-admin
+		// exit with EEXIST, which is also the exit code of an email that is in use
+		if isAlreadyExists(err) && !strings.Contains(err.Error(), "is the email address an existing user") {
+			return nil, ErrorCodeFileExists, errors.Wrapf(err, "user already exists")
+		}
 		return nil, RGWErrorUnknown, errors.Wrapf(err, "failed to create user")
 	}
 
@@ -138,6 +298,18 @@ func CreateUser(c *Context, user ObjectUser) (*ObjectUser, int, error) {
 	return decodeUser(result)
 }
 
+// isAlreadyExists returns whether the radosgw-admin command failed because what it creates exists
+func isAlreadyExists(err error) bool {
+	cmdErr, ok := errors.Cause(err).(*exec.CommandError)
+	return ok && cmdErr.ExitStatus() == int(syscall.EEXIST)
+}
+
+// isNotFound returns whether the radosgw-admin command failed because the user does not exist
+func isNotFound(err error) bool {
+	cmdErr, ok := errors.Cause(err).(*exec.CommandError)
+	return ok && cmdErr.ExitStatus() == int(syscall.ENOENT)
+}
+
 // UpdateUser updates the user whose ID matches the user.
 func UpdateUser(c *Context, user ObjectUser) (*ObjectUser, int, error) {
 	logger.Infof("Updating user: %s", user.UserID)
@@ -150,9 +322,28 @@ func UpdateUser(c *Context, user ObjectUser) (*ObjectUser, int, error) {
 	if user.Email != nil {
 		args = append(args, "--email", *user.Email)
 	}
+	if user.OpMask != nil {
+		args = append(args, "--op-mask", *user.OpMask)
+	}
+	if user.MaxBuckets != nil {
+		args = append(args, "--max-buckets", strconv.Itoa(*user.MaxBuckets))
+	}
+	// RGW sets the placement target and storage class together, a missing one is cleared
+	if user.DefaultPlacement != nil {
+		args = append(args, "--placement-id", *user.DefaultPlacement)
+	}
+	if user.DefaultStorageClass != nil {
+		args = append(args, "--storage-class", *user.DefaultStorageClass)
+	}
+	if len(user.PlacementTags) > 0 {
+		args = append(args, "--tags", strings.Join(user.PlacementTags, ","))
+	}
 
 	body, err := runAdminCommand(c, args...)
 	if err != nil {
+		if isNotFound(err) {
+			return nil, RGWErrorNotFound, errors.Wrapf(err, "user %q not found", user.UserID)
+		}
 		return nil, RGWErrorUnknown, errors.Wrapf(err, "failed to update user")
 	}
 
@@ -171,6 +362,9 @@ func DeleteUser(c *Context, id string, opts ...string) (string, int, error) {
 	}
 	result, err := runAdminCommand(c, args...)
 	if err != nil {
+		if isNotFound(err) {
+			return "", RGWErrorNotFound, errors.Wrapf(err, "user %q does not exist so cannot delete", id)
+		}
 		return "", RGWErrorUnknown, errors.Wrapf(err, "failed to delete user")
 	}
 	if result == "unable to remove user, user does not exist" {
@@ -190,11 +384,266 @@ func SetQuotaUserBucketMax(c *Context, id string, max int) (string, int, error)
 	return result, errCode, err
 }
 
+// SetUserQuota sets the user scope quota of the user with the given ID and enables or disables it
+func SetUserQuota(c *Context, id string, quota ObjectUserQuota) (string, int, error) {
+	logger.Infof("Setting user %q quota to max size %d and max objects %d", id, quota.MaxSize, quota.MaxObjects)
+	args := []string{"--quota-scope", "user", "--max-size", strconv.FormatInt(quota.MaxSize, 10), "--max-objects", strconv.FormatInt(quota.MaxObjects, 10)}
+	if quota.SizeInKB {
+		// An unlimited size is -1 in KB too
+		maxSizeKB := quota.MaxSize
+		if maxSizeKB > 0 {
+			maxSizeKB /= 1024
+		}
+		args = []string{"--quota-scope", "user", "--max-size-kb", strconv.FormatInt(maxSizeKB, 10), "--max-objects", strconv.FormatInt(quota.MaxObjects, 10)}
+	}
+	_, _, err := setUserQuota(c, id, args)
+	if err != nil {
+		return "", RGWErrorUnknown, err
+	}
+
+	return EnableUserQuota(c, id, quota.Enabled)
+}
+
+// EnableUserQuota enables or disables the user scope quota of the user with the given ID, without
+// changing its limits
+func EnableUserQuota(c *Context, id string, enabled bool) (string, int, error) {
+	return enableQuota(c, id, "user", enabled)
+}
+
+// SetBucketQuota sets the bucket scope quota of the user with the given ID, which limits each of its
+// buckets on its own, and enables or disables it
+func SetBucketQuota(c *Context, id string, quota ObjectUserQuota) (string, int, error) {
+	logger.Infof("Setting user %q bucket quota to max size %d and max objects %d", id, quota.MaxSize, quota.MaxObjects)
+	args := []string{"--quota-scope", "bucket", "--max-size", strconv.FormatInt(quota.MaxSize, 10), "--max-objects", strconv.FormatInt(quota.MaxObjects, 10)}
+	_, _, err := setUserQuota(c, id, args)
+	if err != nil {
+		return "", RGWErrorUnknown, err
+	}
+
+	return enableQuota(c, id, "bucket", quota.Enabled)
+}
+
+// enableQuota enables or disables the quota of the given scope of the user with the given ID
+func enableQuota(c *Context, id, scope string, enabled bool) (string, int, error) {
+	state := "disable"
+	if enabled {
+		state = "enable"
+	}
+	result, err := runAdminCommand(c, "quota", state, "--quota-scope", scope, "--uid", id)
+	if err != nil {
+		return "", RGWErrorUnknown, errors.Wrapf(err, "failed to %s %s quota for user", state, scope)
+	}
+	return result, RGWErrorNone, nil
+}
+
+// SuspendUser suspends or enables the user with the given ID. A suspended user keeps its buckets and
+// keys, but all of its requests are denied.
+func SuspendUser(c *Context, id string, suspend bool) (string, int, error) {
+	command := "enable"
+	if suspend {
+		command = "suspend"
+	}
+	logger.Infof("Running %s of user %q", command, id)
+	result, err := runAdminCommand(c, "user", command, "--uid", id)
+	if err != nil {
+		return "", RGWErrorUnknown, errors.Wrapf(err, "failed to %s user", command)
+	}
+	return result, RGWErrorNone, nil
+}
+
+// SyncUserStats recalculates the stats of the user from its buckets, which the quota is enforced against
+func SyncUserStats(c *Context, id string) error {
+	logger.Infof("Syncing stats of user %q", id)
+	_, err := runAdminCommand(c, "user", "stats", "--uid", id, "--sync-stats")
+	if err != nil {
+		return errors.Wrapf(err, "failed to sync stats of user %q", id)
+	}
+	return nil
+}
+
+// UserStats is the usage of a user that its user scope quota is enforced against
+type UserStats struct {
+	// The size of the objects of the user in bytes
+	Size int64 `json:"size"`
+	// The number of objects of the user
+	NumObjects int64 `json:"num_objects"`
+}
+
+// GetUserStats returns the usage of the user with the given ID as of the last sync of its stats
+func GetUserStats(c *Context, id string) (*UserStats, int, error) {
+	result, err := runAdminCommand(c, "user", "stats", "--uid", id)
+	if err != nil {
+		return nil, RGWErrorUnknown, errors.Wrapf(err, "failed to get stats of user %q", id)
+	}
+
+	var stats struct {
+		Stats UserStats `json:"stats"`
+	}
+	if err := decodeAdminOutput(result, &stats); err != nil {
+		return nil, RGWErrorParse, errors.Wrap(err, "failed to read user stats")
+	}
+	return &stats.Stats, RGWErrorNone, nil
+}
+
+// CreateKey generates a new S3 key for the user with the given ID and returns the user with all its keys
+func CreateKey(c *Context, id string) (*ObjectUser, int, error) {
+	logger.Infof("Creating key of user %q", id)
+	result, err := runAdminCommand(c, "key", "create", "--uid", id, "--key-type", "s3", "--gen-access-key", "--gen-secret")
+	if err != nil {
+		return nil, RGWErrorUnknown, errors.Wrapf(err, "failed to create key")
+	}
+	return decodeUser(result)
+}
+
+// SetKey sets the given S3 key on the user with the given ID and returns the user with all its keys.
+// The secret key is replaced if the user already has the access key.
+func SetKey(c *Context, id, accessKey, secretKey string) (*ObjectUser, int, error) {
+	logger.Infof("Setting key %q of user %q", accessKey, id)
+	result, err := runAdminCommand(c, "key", "create", "--uid", id, "--key-type", "s3", "--access-key", accessKey, "--secret-key", secretKey)
+	if err != nil {
+		return nil, RGWErrorUnknown, errors.Wrapf(err, "failed to set key")
+	}
+	return decodeUser(result)
+}
+
+// RemoveKey removes the S3 key with the given access key from the user with the given ID
+func RemoveKey(c *Context, id, accessKey string) (string, int, error) {
+	logger.Infof("Removing key %q of user %q", accessKey, id)
+	result, err := runAdminCommand(c, "key", "rm", "--uid", id, "--key-type", "s3", "--access-key", accessKey)
+	if err != nil {
+		return "", RGWErrorUnknown, errors.Wrapf(err, "failed to remove key")
+	}
+	return result, RGWErrorNone, nil
+}
+
+// AddCaps adds the admin capabilities to the user with the given ID. The caps are given in the
+// "<type>=<perm>;<type>=<perm>" form accepted by radosgw-admin and added to the existing permissions.
+func AddCaps(c *Context, id, caps string) (string, int, error) {
+	logger.Infof("Adding caps %q to user %q", caps, id)
+	return runCapsCommand(c, "add", id, caps)
+}
+
+// RemoveCaps removes the admin capabilities from the user with the given ID, see AddCaps
+func RemoveCaps(c *Context, id, caps string) (string, int, error) {
+	logger.Infof("Removing caps %q from user %q", caps, id)
+	return runCapsCommand(c, "rm", id, caps)
+}
+
+func runCapsCommand(c *Context, command, id, caps string) (string, int, error) {
+	result, err := runAdminCommand(c, "caps", command, "--uid", id, "--caps", caps)
+	if err != nil {
+		return "", RGWErrorUnknown, errors.Wrapf(err, "failed to %s caps", command)
+	}
+	return result, RGWErrorNone, nil
+}
+
+// CreateSubuser creates a subuser of the user with the given ID. The access is one of the
+// tokens accepted by radosgw-admin, i.e. read, write, readwrite or full. An empty access grants none.
+func CreateSubuser(c *Context, id, subuserID, access string) (string, int, error) {
+	logger.Infof("Creating subuser %q with access %q", subuserID, access)
+	return runSubuserCommand(c, "create", id, subuserID, access)
+}
+
+// ModifySubuser changes the access of the subuser of the user with the given ID
+func ModifySubuser(c *Context, id, subuserID, access string) (string, int, error) {
+	logger.Infof("Modifying subuser %q access to %q", subuserID, access)
+	return runSubuserCommand(c, "modify", id, subuserID, access)
+}
+
+// CreateSwiftKey generates a Swift key for the subuser of the user with the given ID and returns the user
+func CreateSwiftKey(c *Context, id, subuserID string) (*ObjectUser, int, error) {
+	logger.Infof("Creating swift key of subuser %q", subuserID)
+	result, err := runAdminCommand(c, "key", "create", "--uid", id, "--subuser", subuserID, "--key-type", "swift", "--gen-secret")
+	if err != nil {
+		return nil, RGWErrorUnknown, errors.Wrapf(err, "failed to create swift key")
+	}
+	return decodeUser(result)
+}
+
+// SetSwiftKey sets the given secret key as the Swift key of the subuser of the user with the given ID
+func SetSwiftKey(c *Context, id, subuserID, secretKey string) (*ObjectUser, int, error) {
+	logger.Infof("Setting swift key of subuser %q", subuserID)
+	result, err := runAdminCommand(c, "key", "create", "--uid", id, "--subuser", subuserID, "--key-type", "swift", "--secret-key", secretKey)
+	if err != nil {
+		return nil, RGWErrorUnknown, errors.Wrapf(err, "failed to set swift key")
+	}
+	return decodeUser(result)
+}
+
+// CreateSubuserKey generates an S3 key for the subuser of the user with the given ID and returns the user
+func CreateSubuserKey(c *Context, id, subuserID string) (*ObjectUser, int, error) {
+	logger.Infof("Creating s3 key of subuser %q", subuserID)
+	result, err := runAdminCommand(c, "key", "create", "--uid", id, "--subuser", subuserID, "--key-type", "s3", "--gen-access-key", "--gen-secret")
+	if err != nil {
+		return nil, RGWErrorUnknown, errors.Wrapf(err, "failed to create subuser key")
+	}
+	return decodeUser(result)
+}
+
+// SetSubuserKey sets the given S3 key on the subuser of the user with the given ID. The secret key is
+// replaced if the subuser already has the access key.
+func SetSubuserKey(c *Context, id, subuserID, accessKey, secretKey string) (*ObjectUser, int, error) {
+	logger.Infof("Setting s3 key %q of subuser %q", accessKey, subuserID)
+	result, err := runAdminCommand(c, "key", "create", "--uid", id, "--subuser", subuserID, "--key-type", "s3", "--access-key", accessKey, "--secret-key", secretKey)
+	if err != nil {
+		return nil, RGWErrorUnknown, errors.Wrapf(err, "failed to set subuser key")
+	}
+	return decodeUser(result)
+}
+
+// RemoveSubuserKey removes the S3 key with the given access key from the subuser of the user with the
+// given ID
+func RemoveSubuserKey(c *Context, id, subuserID, accessKey string) (string, int, error) {
+	logger.Infof("Removing s3 key %q of subuser %q", accessKey, subuserID)
+	result, err := runAdminCommand(c, "key", "rm", "--uid", id, "--subuser", subuserID, "--key-type", "s3", "--access-key", accessKey)
+	if err != nil {
+		return "", RGWErrorUnknown, errors.Wrapf(err, "failed to remove subuser key")
+	}
+	return result, RGWErrorNone, nil
+}
+
+// SetTempURLKey sets the Swift temp URL key with the given ID, 0 or 1, of the user with the given ID.
+// An empty key removes it.
+func SetTempURLKey(c *Context, id string, keyID int, key string) (*ObjectUser, int, error) {
+	logger.Infof("Setting temp url key %d of user %q", keyID, id)
+	flag := "--temp-url-key"
+	if keyID == 1 {
+		flag = "--temp-url-key-2"
+	}
+	result, err := runAdminCommand(c, "user", "modify", "--uid", id, flag, key)
+	if err != nil {
+		return nil, RGWErrorUnknown, errors.Wrapf(err, "failed to set temp url key")
+	}
+	return decodeUser(result)
+}
+
+// RemoveSubuser removes the subuser of the user with the given ID together with its keys
+func RemoveSubuser(c *Context, id, subuserID string) (string, int, error) {
+	logger.Infof("Removing subuser %q", subuserID)
+	result, err := runAdminCommand(c, "subuser", "rm", "--uid", id, "--subuser", subuserID, "--purge-keys")
+	if err != nil {
+		return "", RGWErrorUnknown, errors.Wrapf(err, "failed to rm subuser")
+	}
+	return result, RGWErrorNone, nil
+}
+
+func runSubuserCommand(c *Context, command, id, subuserID, access string) (string, int, error) {
+	args := []string{"subuser", command, "--uid", id, "--subuser", subuserID}
+	if access != "" {
+		args = append(args, "--access", access)
+	}
+	result, err := runAdminCommand(c, args...)
+	if err != nil {
+		return "", RGWErrorUnknown, errors.Wrapf(err, "failed to %s subuser", command)
+	}
+	return result, RGWErrorNone, nil
+}
+
 func setUserQuota(c *Context, id string, args []string) (string, int, error) {
 	args = append([]string{"quota", "set", "--uid", id}, args...)
 	result, err := runAdminCommand(c, args...)
 	if err != nil {
-		err = errors.Wrapf(err, "failed to set max buckets for user")
+		err = errors.Wrapf(err, "failed to set quota for user")
 	}
 	return result, RGWErrorNone, err
 }
@@ -0,0 +1,130 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	reasonCephClusterNotReady   = "CephClusterNotReady"
+	reasonCephClusterUnhealthy  = "CephClusterUnhealthy"
+	reasonObjectStoreNotReady   = "ObjectStoreNotReady"
+	reasonObjectStoreNotFound   = "ObjectStoreNotFound"
+	reasonAdminNotResponding    = "AdminNotResponding"
+	reasonAdminCapsInsufficient = "AdminCapsInsufficient"
+	reasonInvalidSpec           = "InvalidSpec"
+	reasonReconciling           = "Reconciling"
+	reasonCephUserFailed        = "CephUserFailed"
+	reasonUserHasNoKeys         = "UserHasNoKeys"
+	reasonKeyNotProvided        = "KeyNotProvided"
+	reasonUserConflict          = "UserConflict"
+	reasonSecretFailed          = "SecretFailed"
+	reasonSecretKeysRepaired    = "SecretKeysRepaired"
+	reasonDeletionRetrying      = "DeletionRetrying"
+	reasonDeletionScheduled     = "DeletionScheduled"
+	reasonDeletionFailed        = "DeletionFailed"
+	reasonReconciled            = "Reconciled"
+	reasonValidated             = "DryRunValidated"
+	reasonReconcilePaused       = "ReconcilePaused"
+	reasonReconcileResumed      = "ReconcileResumed"
+	reasonObjectStoreReadOnly   = "ObjectStoreReadOnly"
+)
+
+// setCondition adds or updates a condition in the status of the object store user. The status
+// is persisted with the next status update.
+func setCondition(u *cephv1.CephObjectStoreUser, conditionType cephv1.ConditionType, status v1.ConditionStatus, reason, message string) {
+	existingCondition := findCondition(u.Status.Conditions, conditionType)
+	if existingCondition == nil {
+		u.Status.Conditions = append(u.Status.Conditions, cephv1.Condition{
+			Type:               conditionType,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			LastTransitionTime: metav1.NewTime(time.Now()),
+			LastHeartbeatTime:  metav1.NewTime(time.Now()),
+		})
+	} else if existingCondition.Status != status || existingCondition.Message != message {
+		if existingCondition.Status != status {
+			existingCondition.LastTransitionTime = metav1.NewTime(time.Now())
+		}
+		existingCondition.Status = status
+		existingCondition.Reason = reason
+		existingCondition.Message = message
+		existingCondition.LastHeartbeatTime = metav1.NewTime(time.Now())
+	}
+}
+
+// findCondition returns the condition of the given type, or nil if it is not set
+func findCondition(conditions []cephv1.Condition, conditionType cephv1.ConditionType) *cephv1.Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// setPhase records the phase of the object store user in its Progressing, Ready and Failure conditions
+// and derives the phase from them. The conditions keep the time of their last transition, so a past
+// failure or wait remains visible after the user became ready.
+func setPhase(u *cephv1.CephObjectStoreUser, phase cephv1.ObjectStoreUserPhase, reason, message string) {
+	switch phase {
+	case cephv1.ObjectStoreUserPhaseReconciling:
+		setCondition(u, cephv1.ConditionProgressing, v1.ConditionTrue, reason, message)
+	case cephv1.ObjectStoreUserPhaseReconcileFailed:
+		setCondition(u, cephv1.ConditionFailure, v1.ConditionTrue, reason, message)
+		setCondition(u, cephv1.ConditionProgressing, v1.ConditionFalse, reason, message)
+		setCondition(u, cephv1.ConditionReady, v1.ConditionFalse, reason, message)
+	case cephv1.ObjectStoreUserPhaseReady:
+		setCondition(u, cephv1.ConditionFailure, v1.ConditionFalse, reason, message)
+		setCondition(u, cephv1.ConditionProgressing, v1.ConditionFalse, reason, message)
+		setCondition(u, cephv1.ConditionReady, v1.ConditionTrue, reason, message)
+	case cephv1.ObjectStoreUserPhaseValidated:
+		// A dry run only validates the user, it is not ready to be used
+		setCondition(u, cephv1.ConditionFailure, v1.ConditionFalse, reasonValidated, message)
+		setCondition(u, cephv1.ConditionProgressing, v1.ConditionFalse, reasonValidated, message)
+		setCondition(u, cephv1.ConditionReady, v1.ConditionFalse, reasonValidated, message)
+	}
+	u.Status.Phase = phaseFromConditions(u.Status.Conditions)
+}
+
+// phaseFromConditions returns the phase of an object store user with the given conditions
+func phaseFromConditions(conditions []cephv1.Condition) cephv1.ObjectStoreUserPhase {
+	isTrue := func(conditionType cephv1.ConditionType) bool {
+		condition := findCondition(conditions, conditionType)
+		return condition != nil && condition.Status == v1.ConditionTrue
+	}
+
+	// A user being reconciled again after a failure is reconciling
+	switch {
+	case isTrue(cephv1.ConditionProgressing):
+		return cephv1.ObjectStoreUserPhaseReconciling
+	case isTrue(cephv1.ConditionFailure):
+		return cephv1.ObjectStoreUserPhaseReconcileFailed
+	case isTrue(cephv1.ConditionReady):
+		return cephv1.ObjectStoreUserPhaseReady
+	}
+	if ready := findCondition(conditions, cephv1.ConditionReady); ready != nil && ready.Reason == reasonValidated {
+		return cephv1.ObjectStoreUserPhaseValidated
+	}
+	return cephv1.ObjectStoreUserPhaseCreated
+}
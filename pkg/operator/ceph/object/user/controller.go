@@ -19,44 +19,455 @@ package objectuser
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	opcontroller "github.com/rook/rook/pkg/operator/ceph/controller"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/coreos/pkg/capnslog"
+	bktv1alpha1 "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
 	"github.com/pkg/errors"
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/clusterd"
+	cephclient "github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
 	"github.com/rook/rook/pkg/operator/ceph/object"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	appName        = object.AppName
 	controllerName = "ceph-object-store-user-controller"
+	// auditRedacted replaces sensitive values in the audit log
+	auditRedacted = "<redacted>"
+
+	cephHealthWarn  = "HEALTH_WARN"
+	cephHealthError = "HEALTH_ERR"
+	// initialFailureBackoff is the requeue interval after the first failed reconcile of a user
+	initialFailureBackoff = 5 * time.Second
+	// defaultMaxFailureBackoff caps the requeue interval of a user that keeps failing
+	defaultMaxFailureBackoff = 5 * time.Minute
+	// secretFormatRook is the legacy layout of the secret of the user, the default
+	secretFormatRook = "rook"
+	// secretFormatMC adds a MinIO client config for the object store to the secret of the user
+	secretFormatMC = "mc"
+	// secretFormatStandard is the layout with the key names commonly used by secret stores, e.g. for
+	// external-secrets to sync the secret into a vault
+	secretFormatStandard = "standard"
+	// secretSchemaAnnotation holds the format and the version of the layout of the secret of the user,
+	// e.g. "standard/v1". The keys of a version are never renamed or removed.
+	secretSchemaAnnotation = "ceph.rook.io/secret-schema"
+	// secretSchemaVersion is the version of the layouts of the secret
+	secretSchemaVersion = "v1"
+	// endpointKey is the key of the endpoint of the object store in the standard secret format
+	endpointKey = "endpoint"
+	// mcConfigKey is the key of the MinIO client config in the secret, the file name mc expects
+	mcConfigKey = "config.json"
+	// awsAccessKeyIDKey and awsSecretAccessKeyKey are the keys of the secret with the AWS names of the keys
+	awsAccessKeyIDKey     = "AWS_ACCESS_KEY_ID"
+	awsSecretAccessKeyKey = "AWS_SECRET_ACCESS_KEY"
+	// disableDriftCorrectionAnnotation opts a user out of the drift correction on periodic resyncs,
+	// the user is still updated when its spec changes
+	disableDriftCorrectionAnnotation = "ceph.rook.io/disable-drift-correction"
+	// dryRunAnnotation only validates the user and reports the changes a reconcile would make,
+	// nothing is changed in RGW or in the secret of the user
+	dryRunAnnotation = "ceph.rook.io/dry-run"
+	// forceResyncAnnotation re-applies the spec to the user once for every new value, e.g. a
+	// timestamp, even if the spec and the generation of the CR did not change
+	forceResyncAnnotation = "ceph.rook.io/force-resync"
+	// forceDeleteAnnotation deletes the RGW user of a deleted CR right away, without waiting for the
+	// deletion grace period
+	forceDeleteAnnotation = "ceph.rook.io/force-delete"
+	// debugReconcileAnnotation forces a resync of the user once for every new value, like the force
+	// resync annotation, and logs the radosgw-admin commands it ran and its outcome. It is only honored
+	// if the operator allows debug reconciles.
+	debugReconcileAnnotation = "ceph.rook.io/debug-reconcile"
+	// secretHashAnnotation holds the SHA-256 of the data of the secret of the user, so that consumers
+	// can tell that the keys changed without reading them
+	secretHashAnnotation = "ceph.rook.io/secret-hash"
+	// userIDAnnotation holds the uid of the RGW user on the secret of the user, which is named after
+	// the CR as the uid is not necessarily a valid name
+	userIDAnnotation = "ceph.rook.io/uid"
+	// pausedAnnotation stops the reconciles of the user while it is "true", e.g. during a manual repair
+	// of the RGW user. Nothing is changed in RGW, in the secret or in the finalizers of the user.
+	pausedAnnotation = "rook.io/reconcile-paused"
+	// tagAnnotationPrefix prefixes the tags of the user in the annotations of its secret
+	tagAnnotationPrefix = "tags.ceph.rook.io/"
+	// managedByLabel marks the secrets of the users, and their copies, as credentials managed by the
+	// operator, so that they can be listed for an audit
+	managedByLabel = "rook.io/managed-by"
+	managedByValue = "object-user-controller"
+	// objectStoreNotFoundRequeueAfter is the requeue interval of a user whose object store does not
+	// exist, which is likely a typo in the store name rather than a store that is still being created
+	objectStoreNotFoundRequeueAfter = 2 * time.Minute
+	// readOnlyRequeueAfter is the requeue interval of a user whose object store does not accept changes,
+	// which lasts until the maintenance of the store is over
+	readOnlyRequeueAfter = time.Minute
+	// adminCapsRequeueAfter is the requeue interval of a user whose admin commands are denied, which is
+	// a misconfiguration of the ceph credentials rather than a transient failure
+	adminCapsRequeueAfter = 5 * time.Minute
+	// objectBucketsRequeueAfter is the requeue interval of a deleted user whose object buckets still exist
+	objectBucketsRequeueAfter = time.Minute
+	// rgwDefaultMaxBuckets is the default of rgw_user_max_buckets, which a max buckets cleared from the
+	// spec is restored to
+	rgwDefaultMaxBuckets = 1000
+	// maxStatusBuckets limits the buckets listed in the status of a user that owns many of them
+	maxStatusBuckets = 100
+	// maxAdminStatsReconciles limits the reconciles whose radosgw-admin commands are counted in the status
+	maxAdminStatsReconciles = 10
+	// maxSubuserNameLength limits the names of the subusers, RGW itself does not limit them
+	maxSubuserNameLength = 64
+	// endpointProbeTimeout is the time to wait for a connection to the endpoint of an external store
+	endpointProbeTimeout = 5 * time.Second
+	// defaultPlacementTarget is the placement target RGW uses for users without a default placement
+	defaultPlacementTarget = "default-placement"
+)
+
+// secretKeyNames are the names of the keys in the secret of the user in a secret format. The named
+// keys, the Swift keys and the temp URL keys are suffixed with their index or ID, e.g. "AccessKey_0".
+type secretKeyNames struct {
+	accessKey  string
+	secretKey  string
+	keyName    string
+	swiftUser  string
+	swiftKey   string
+	tempURLKey string
+	// The S3 keys of the subusers
+	subuser          string
+	subuserAccessKey string
+	subuserSecretKey string
+}
+
+var (
+	rookSecretKeyNames     = secretKeyNames{accessKey: "AccessKey", secretKey: "SecretKey", keyName: "KeyName", swiftUser: "SwiftUser", swiftKey: "SwiftKey", tempURLKey: "TempURLKey", subuser: "Subuser", subuserAccessKey: "SubuserAccessKey", subuserSecretKey: "SubuserSecretKey"}
+	standardSecretKeyNames = secretKeyNames{accessKey: "accessKeyID", secretKey: "secretAccessKey", keyName: "keyName", swiftUser: "swiftUser", swiftKey: "swiftKey", tempURLKey: "tempURLKey", subuser: "subuser", subuserAccessKey: "subuserAccessKeyID", subuserSecretKey: "subuserSecretAccessKey"}
 )
 
+// secretFormat returns the secret format of the spec, the legacy format if not set
+func secretFormat(spec cephv1.ObjectStoreUserSpec) string {
+	if spec.SecretFormat == "" {
+		return secretFormatRook
+	}
+	return spec.SecretFormat
+}
+
+// opMaskOperations are the operations accepted in a user op mask, in the order RGW reports them
+var opMaskOperations = []string{"read", "write", "delete"}
+
+// managedSecretLabels and managedSecretAnnotations are set on the secret of the user by the operator,
+// the tags are annotations of the secret as well
+var managedSecretLabels = []string{"app", "user", "rook_cluster", "rook_object_store", managedByLabel}
+var managedSecretAnnotations = []string{secretHashAnnotation, secretSchemaAnnotation, userIDAnnotation}
+
+// capTypes are the admin capability types of RGW, in the order of the spec
+var capTypes = []string{"users", "buckets", "metadata", "usage", "zone", "roles", "info"}
+
+// rawCapTypeRegexp matches the capability types of raw capabilities
+var rawCapTypeRegexp = regexp.MustCompile(`^[a-z][a-z-]*$`)
+
+// capPresets are the admin capabilities of the presets of the spec
+var capPresets = map[cephv1.ObjectUserPreset]cephv1.ObjectUserCapSpec{
+	cephv1.ObjectUserPresetMonitoring: {Info: "read", Usage: "read", Metadata: "read"},
+}
+
+// rgwSubuserAccess maps the subuser access of the spec to the access token accepted by radosgw-admin
+// and to the permission RGW reports for it
+var rgwSubuserAccess = map[cephv1.AccessSpec]struct {
+	token      string
+	permission string
+}{
+	cephv1.AccessSpecRead:      {token: "read", permission: "read"},
+	cephv1.AccessSpecWrite:     {token: "write", permission: "write"},
+	cephv1.AccessSpecReadWrite: {token: "readwrite", permission: "read-write"},
+	cephv1.AccessSpecFull:      {token: "full", permission: "full-control"},
+}
+
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", controllerName)
 
+// namedKey is an S3 key of the user tracked under a name of the spec
+type namedKey struct {
+	name      string
+	accessKey string
+	secretKey string
+}
+
+// KeyIntegrityError is returned when the key in the secret referenced by a key of the spec does not
+// match the expected hash of the key
+type KeyIntegrityError struct {
+	Key    string
+	Secret string
+}
+
+func (e *KeyIntegrityError) Error() string {
+	return fmt.Sprintf("key %q in secret %q does not match its expected sha256", e.Key, e.Secret)
+}
+
+// NoKeysError is returned when an existing user has no top-level keys for its secret and the spec
+// does not allow to generate one
+type NoKeysError struct {
+	User string
+}
+
+func (e *NoKeysError) Error() string {
+	return fmt.Sprintf("ceph object user %q has no keys, set generateMissingKey to generate one", e.User)
+}
+
+// UserConflictError is returned when an existing RGW user that the CR does not manage yet looks like
+// another user, so that it is not taken over by accident
+type UserConflictError struct {
+	User        string
+	Differences []string
+}
+
+func (e *UserConflictError) Error() string {
+	return fmt.Sprintf("ceph object user %q already exists with %s, set adopt to take it over", e.User, strings.Join(e.Differences, " and "))
+}
+
+// KeyNotProvidedError is returned when the user needs a key that the operator must not generate
+type KeyNotProvidedError struct {
+	User string
+}
+
+func (e *KeyNotProvidedError) Error() string {
+	return fmt.Sprintf("ceph object user %q needs a key but generateKey is false, provide one with the secretName of a key", e.User)
+}
+
+// UserNotFoundError is returned when the ceph user is not found by an update, because it was deleted
+// since it was looked up
+type UserNotFoundError struct {
+	User string
+}
+
+func (e *UserNotFoundError) Error() string {
+	return fmt.Sprintf("ceph object user %q was deleted while it was updated", e.User)
+}
+
+// StepError is returned when a step of the update of the ceph user fails. The steps only apply the
+// settings that differ from the spec, so the next reconcile resumes with the failed step.
+type StepError struct {
+	Step      string
+	Completed []string
+	Err       error
+}
+
+func (e *StepError) Error() string {
+	completed := "none"
+	if len(e.Completed) > 0 {
+		completed = strings.Join(e.Completed, ", ")
+	}
+	return fmt.Sprintf("step %q failed, completed steps: %s: %v", e.Step, completed, e.Err)
+}
+
+// isPermissionDenied returns whether an admin command was denied, also when it failed a step of the
+// update of the ceph user
+func isPermissionDenied(err error) bool {
+	if stepErr, ok := errors.Cause(err).(*StepError); ok {
+		err = stepErr.Err
+	}
+	return object.IsPermissionDenied(err)
+}
+
+// isReadOnly returns whether the object store did not accept a change, also when it failed a step of the
+// update of the ceph user
+func isReadOnly(err error) bool {
+	if stepErr, ok := errors.Cause(err).(*StepError); ok {
+		err = stepErr.Err
+	}
+	return object.IsReadOnly(err)
+}
+
+// isUserNotFound returns whether an update did not find the ceph user, also when it failed a step of
+// the update of the ceph user
+func isUserNotFound(err error) bool {
+	if stepErr, ok := errors.Cause(err).(*StepError); ok {
+		err = stepErr.Err
+	}
+	_, ok := errors.Cause(err).(*UserNotFoundError)
+	return ok
+}
+
 // ReconcileObjectStoreUser reconciles a ObjectStoreUser object
 type ReconcileObjectStoreUser struct {
 	client     client.Client
 	scheme     *runtime.Scheme
 	context    *clusterd.Context
-	objContext *object.Context
-	userConfig object.ObjectUser
+	recorder   record.EventRecorder
+	maxBackoff time.Duration
+	failures   map[types.NamespacedName]int
+	// failuresMutex guards the failures of the concurrent reconciles
+	failuresMutex sync.Mutex
+	// blockOnHealthWarn also holds the reconciles back while the CephCluster is HEALTH_WARN
+	blockOnHealthWarn bool
+	// allowDebugReconcile enables the debug reconcile annotation
+	allowDebugReconcile bool
+	// allowDeterministicKeys enables the deterministic keys of the spec
+	allowDeterministicKeys bool
+	// adminTimeout and adminReadRetries are the timeout and the retries of the radosgw-admin commands
+	adminTimeout     time.Duration
+	adminReadRetries int
+	// traceAdminArgs logs the radosgw-admin commands with their arguments, the keys redacted
+	traceAdminArgs bool
+	// store is the only object store whose users are reconciled, all stores if empty
+	store string
+	// resyncPeriod is the interval a reconciled user is reconciled again after to repair drift in RGW,
+	// never if zero
+	resyncPeriod time.Duration
+	// locks serializes the reconciles of the same RGW user, shared by the copies of the reconciler
+	locks *userLocks
+	// maxConcurrentReconciles is the number of users reconciled at the same time
+	maxConcurrentReconciles int
+	// reconcileState is the state of the reconcile in progress, which every reconcile starts afresh
+	reconcileState
+}
+
+// reconcileState is the state of a single reconcile of a user, see ReconcileObjectStoreUser.reconcile
+type reconcileState struct {
+	objContext  *object.Context
+	objectStore *cephv1.CephObjectStore
+	userConfig  object.ObjectUser
+	changes     []string
+	userSpec    cephv1.ObjectStoreUserSpec
+	quotaStatus *cephv1.ObjectUserQuotaStatus
+	keyStatus   []cephv1.ObjectUserKeyStatus
+	keys        []namedKey
+	// keyRefs are the keys of the spec read from a secret, by name
+	keyRefs map[string]namedKey
+	// swiftKeyRefs are the Swift keys of the subusers of the spec read from a secret, by subuser ID
+	swiftKeyRefs map[string]string
+	// swiftKeys are the Swift keys of the subusers, tracked under the subuser ID
+	swiftKeys []namedKey
+	// subuserKeyRefs are the S3 keys of the subusers of the spec read from a secret, by subuser ID
+	subuserKeyRefs map[string]namedKey
+	// subuserKeys are the S3 keys of the subusers, named after the subuser ID
+	subuserKeys []namedKey
+	// tempURLKeyRefs are the temp URL keys of the spec read from a secret, by ID
+	tempURLKeyRefs map[int]string
+	// tempURLKeys are the temp URL keys of the spec, by ID
+	tempURLKeys map[int]string
+	// tempURLKeyIDs are the IDs of the temp URL keys of the spec set on the user
+	tempURLKeyIDs []int
+	// managedCaps are the types of the admin capabilities of the spec set on the user
+	managedCaps []string
+	// placementTags are the placement tags of the user after its update, nil if unknown
+	placementTags []string
+	// linkedBuckets are the buckets of the spec linked to the user
+	linkedBuckets []string
+	// bucketConflicts are the buckets of the spec that cannot be linked to the user
+	bucketConflicts []string
+	// completedSteps are the steps of the update of the ceph user done by this reconcile
+	completedSteps []string
+	// recreatingUser is set when this reconcile creates the ceph user again, after it was deleted while
+	// it was updated
+	recreatingUser bool
+	// maxBucketsSet is set when the max buckets of the spec is set on the user
+	maxBucketsSet bool
+	// suspendedOnExpiry is set when the user was suspended by a previous reconcile because it expired
+	suspendedOnExpiry bool
+	// overQuotaReported is set when a previous reconcile reported the usage of the user over its quota
+	overQuotaReported bool
+	// quotaUsageChecked is set when this reconcile checked the usage of the user against its quota
+	quotaUsageChecked bool
+	// overQuota describes how the usage of the user exceeds its quota, empty if it does not
+	overQuota string
+	// quotaUsage is the usage of the user this reconcile read to check it against its quota
+	quotaUsage *object.UserStats
+	// suspendedOnBreach is set when the user is suspended because its usage exceeds its quota
+	suspendedOnBreach bool
+	// breachSuspension is the change of the suspension of the user on a quota breach by this reconcile,
+	// "suspended" or "enabled"
+	breachSuspension string
+	// rotatedKeys are the access keys replaced by a rotation of the key of the user, which are removed
+	// once the secret holds the new key
+	rotatedKeys []string
+	// skipDriftCorrection is set when an existing user must not be updated by this reconcile
+	skipDriftCorrection bool
+	// resyncing is set when the user is ready with the spec it was last reconciled with, as on periodic
+	// resyncs, so that its ceph user exists unless it was deleted out of band
+	resyncing bool
+	// dryRun is set when this reconcile must only plan the changes to the user
+	dryRun bool
+	// log is the logger of the user this reconcile reconciles
+	log *userLogger
+	// clusterHealth is the health of the CephCluster this reconcile proceeds under
+	clusterHealth string
+	// adminStats counts the radosgw-admin commands run by this reconcile
+	adminStats *object.AdminStats
+	// adminStatsRecorded is set once the counts of this reconcile are in the status of the user
+	adminStatsRecorded bool
+	// failureReason is the reason of the failure of this reconcile, empty if it did not fail
+	failureReason string
+}
+
+// userLocks holds a mutex per RGW user, keyed by the namespace and object store of the user and its uid.
+// A mutex is only held while a reconcile holds or waits for it, so that the users that come and go do
+// not pile up.
+type userLocks struct {
+	mutex sync.Mutex
+	locks map[string]*userLock
+}
+
+// userLock is the mutex of a user with the number of reconciles holding or waiting for it
+type userLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks the mutex of the given user and returns the function to unlock it
+func (l *userLocks) lock(user string) func() {
+	l.mutex.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*userLock{}
+	}
+	lock, ok := l.locks[user]
+	if !ok {
+		lock = &userLock{}
+		l.locks[user] = lock
+	}
+	lock.refs++
+	l.mutex.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mutex.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, user)
+		}
+		l.mutex.Unlock()
+	}
 }
 
 // Add creates a new CephObjectStoreUser Controller and adds it to the Manager. The Manager will set fields on the Controller
@@ -70,23 +481,143 @@ func newReconciler(mgr manager.Manager, context *clusterd.Context) reconcile.Rec
 	// Add the cephv1 scheme to the manager scheme so that the controller knows about it
 	mgrScheme := mgr.GetScheme()
 	cephv1.AddToScheme(mgr.GetScheme())
+	// The object buckets of the claims are checked before a user is deleted
+	bktv1alpha1.AddToScheme(mgr.GetScheme())
 
-	return &ReconcileObjectStoreUser{
-		client:  mgr.GetClient(),
-		scheme:  mgrScheme,
-		context: context,
+	r := &ReconcileObjectStoreUser{
+		client:     mgr.GetClient(),
+		scheme:     mgrScheme,
+		context:    context,
+		recorder:   mgr.GetEventRecorderFor(controllerName),
+		maxBackoff: defaultMaxFailureBackoff,
+		locks:      &userLocks{},
+	}
+
+	// allow overriding the maximum requeue interval of failing users with an env var on the operator
+	if maxBackoff, ok := durationSetting("ROOK_OBJECT_USER_MAX_FAILURE_BACKOFF", true); ok {
+		logger.Infof("object store user maximum failure backoff is %s", maxBackoff)
+		r.maxBackoff = maxBackoff
+	}
+
+	// allow bounding the radosgw-admin commands, which otherwise hang on a degraded cluster
+	if adminTimeout, ok := durationSetting("ROOK_OBJECT_USER_ADMIN_TIMEOUT", true); ok {
+		logger.Infof("object store user admin command timeout is %s", adminTimeout)
+		r.adminTimeout = adminTimeout
+	}
+	if adminReadRetries, ok := intSetting("ROOK_OBJECT_USER_ADMIN_READ_RETRIES", false); ok {
+		logger.Infof("object store user admin read retries is %d", adminReadRetries)
+		r.adminReadRetries = adminReadRetries
+	}
+
+	// allow reconciling the users periodically, to repair the changes made to them outside of the operator
+	if resyncPeriod, ok := durationSetting("ROOK_OBJECT_USER_RESYNC_PERIOD", false); ok {
+		logger.Infof("object store user resync period is %s", resyncPeriod)
+		r.resyncPeriod = resyncPeriod
+	}
+
+	// allow sharding the users by store, with an operator per store
+	r.store = os.Getenv("ROOK_OBJECT_USER_STORE")
+	if r.store != "" {
+		logger.Infof("only the users of object store %q are reconciled", r.store)
+	}
+
+	// allow reconciling several users at the same time, e.g. to converge faster after a restart of the
+	// operator with many users
+	if maxConcurrentReconciles, ok := intSetting("ROOK_OBJECT_USER_MAX_CONCURRENT_RECONCILES", true); ok {
+		logger.Infof("object store user max concurrent reconciles is %d", maxConcurrentReconciles)
+		r.maxConcurrentReconciles = maxConcurrentReconciles
+	}
+
+	// allow holding the users back on a cluster with warnings, an erroring cluster always holds them back
+	if os.Getenv("ROOK_OBJECT_USER_BLOCK_ON_HEALTH_WARN") == "true" {
+		logger.Info("object store users are not reconciled while the ceph cluster is HEALTH_WARN")
+		r.blockOnHealthWarn = true
+	}
+
+	// allow tracing a one-shot reconcile of a user, e.g. a user that is stuck
+	if os.Getenv("ROOK_OBJECT_USER_ALLOW_DEBUG_RECONCILE") == "true" {
+		logger.Infof("object store users can be reconciled with the %q annotation", debugReconcileAnnotation)
+		r.allowDebugReconcile = true
+	}
+
+	// allow tracing the arguments of the radosgw-admin commands, e.g. to debug quotas or caps
+	if os.Getenv("ROOK_OBJECT_USER_TRACE_ADMIN_ARGS") == "true" {
+		logger.Info("the radosgw-admin commands of object store users are logged at debug level, the keys redacted")
+		r.traceAdminArgs = true
+	}
+
+	// allow deriving the keys of users from a seed, e.g. for reproducible disaster recovery
+	if os.Getenv("ROOK_OBJECT_USER_ALLOW_DETERMINISTIC_KEYS") == "true" {
+		logger.Info("object store users can derive their keys from a seed")
+		r.allowDeterministicKeys = true
+	}
+	return r
+}
+
+// durationSetting returns the duration set by an env var of the operator, which must be positive or, if
+// not, at least zero. It returns false if the env var is not set or invalid, which is ignored with a
+// warning so that the default is used.
+func durationSetting(name string, positive bool) (time.Duration, bool) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, false
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 || (positive && duration == 0) {
+		warnInvalidSetting(name, value, positive, "duration")
+		return 0, false
+	}
+	return duration, true
+}
+
+// intSetting returns the integer set by an env var of the operator, see durationSetting
+func intSetting(name string, positive bool) (int, bool) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, false
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 || (positive && i == 0) {
+		warnInvalidSetting(name, value, positive, "integer")
+		return 0, false
+	}
+	return i, true
+}
+
+func warnInvalidSetting(name, value string, positive bool, kind string) {
+	requirement := "non-negative"
+	if positive {
+		requirement = "positive"
 	}
+	logger.Warningf("ignoring invalid %s %q, it must be a %s %s. using the default", name, value, requirement, kind)
 }
 
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
-	c, err := controller.New(controllerName, mgr, controller.Options{Reconciler: r})
+	options := controller.Options{Reconciler: r}
+	if userReconciler, ok := r.(*ReconcileObjectStoreUser); ok && userReconciler.maxConcurrentReconciles > 0 {
+		options.MaxConcurrentReconciles = userReconciler.maxConcurrentReconciles
+	}
+	c, err := controller.New(controllerName, mgr, options)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes on the CephObjectStoreUser CRD object, of the reconciled store only
+	predicates := []predicate.Predicate{opcontroller.WatchUpdatePredicate()}
+	if userReconciler, ok := r.(*ReconcileObjectStoreUser); ok && userReconciler.store != "" {
+		predicates = append(predicates, storePredicate(userReconciler.store))
+	}
+	err = c.Watch(&source.Kind{Type: &cephv1.CephObjectStoreUser{}}, &handler.EnqueueRequestForObject{}, predicates...)
 	if err != nil {
 		return err
 	}
 
-	// Watch for changes on the CephObjectStoreUser CRD object
-	err = c.Watch(&source.Kind{Type: &cephv1.CephObjectStoreUser{}}, &handler.EnqueueRequestForObject{}, opcontroller.WatchUpdatePredicate())
+	// Watch the users generated from a template
+	err = c.Watch(&source.Kind{Type: &cephv1.CephObjectStoreUser{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &cephv1.CephObjectStoreUser{},
+	}, opcontroller.WatchPredicateForNonCRDObject())
 	if err != nil {
 		return err
 	}
@@ -103,45 +634,175 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	return nil
 }
 
+// storePredicate only passes the events of the CephObjectStoreUsers of the given object store
+func storePredicate(store string) predicate.Funcs {
+	isOfStore := func(obj runtime.Object) bool {
+		u, ok := obj.(*cephv1.CephObjectStoreUser)
+		return ok && storeName(u) == store
+	}
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isOfStore(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isOfStore(e.ObjectNew)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isOfStore(e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return isOfStore(e.Object)
+		},
+	}
+}
+
 // Reconcile reads that state of the cluster for a CephObjectStoreUser object and makes changes based on the state read
 // and what is in the CephObjectStoreUser.Spec
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileObjectStoreUser) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	// A reconcile keeps its state in the reconciler, so every reconcile runs on its own copy of it to
+	// allow concurrent reconciles
+	worker := r.newWorker()
+
 	// workaround because the rook logging mechanism is not compatible with the controller-runtime loggin interface
-	reconcileResponse, err := r.reconcile(request)
+	reconcileResponse, err := worker.reconcile(request)
 	if err != nil {
-		logger.Errorf("failed to reconcile %v", err)
+		worker.log.Errorf("failed to reconcile %v", err)
+		// An invalid spec fails the same way until the spec changes, which triggers a reconcile of its
+		// own, so it is only retried with the capped backoff instead of the rate limiter
+		if worker.failureReason == failureReasonInvalidSpec {
+			return reconcile.Result{Requeue: true, RequeueAfter: r.failureBackoff(request.NamespacedName)}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	r.failuresMutex.Lock()
+	delete(r.failures, request.NamespacedName)
+	r.failuresMutex.Unlock()
+
+	return reconcileResponse, nil
+}
+
+// newWorker returns a copy of the reconciler with its settings, sharing the locks of the users. The
+// reconcileState is not copied, every reconcile starts it afresh.
+func (r *ReconcileObjectStoreUser) newWorker() *ReconcileObjectStoreUser {
+	return &ReconcileObjectStoreUser{
+		client:                 r.client,
+		scheme:                 r.scheme,
+		context:                r.context,
+		recorder:               r.recorder,
+		maxBackoff:             r.maxBackoff,
+		blockOnHealthWarn:      r.blockOnHealthWarn,
+		allowDebugReconcile:    r.allowDebugReconcile,
+		allowDeterministicKeys: r.allowDeterministicKeys,
+		adminTimeout:           r.adminTimeout,
+		adminReadRetries:       r.adminReadRetries,
+		traceAdminArgs:         r.traceAdminArgs,
+		store:                  r.store,
+		resyncPeriod:           r.resyncPeriod,
+		locks:                  r.locks,
 	}
+}
+
+// failureBackoff records a failed reconcile of the user and returns the interval to requeue it after.
+// The interval doubles with every consecutive failure up to the maximum backoff.
+func (r *ReconcileObjectStoreUser) failureBackoff(user types.NamespacedName) time.Duration {
+	r.failuresMutex.Lock()
+	defer r.failuresMutex.Unlock()
+	if r.failures == nil {
+		r.failures = map[types.NamespacedName]int{}
+	}
+	r.failures[user]++
 
-	return reconcileResponse, err
+	maxBackoff := r.maxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxFailureBackoff
+	}
+	backoff := initialFailureBackoff
+	for i := 1; i < r.failures[user] && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
 }
 
-func (r *ReconcileObjectStoreUser) reconcile(request reconcile.Request) (reconcile.Result, error) {
+func (r *ReconcileObjectStoreUser) reconcile(request reconcile.Request) (result reconcile.Result, err error) {
+	start := time.Now()
+
+	// Every line logged by this reconcile names the user, the store is known once the CR is read
+	r.reconcileState = reconcileState{
+		log:        newUserLogger(request.Name, "", request.Namespace),
+		adminStats: &object.AdminStats{},
+	}
+
 	// Fetch the CephObjectStoreUser instance
 	cephObjectStoreUser := &cephv1.CephObjectStoreUser{}
-	err := r.client.Get(context.TODO(), request.NamespacedName, cephObjectStoreUser)
+	err = r.client.Get(context.TODO(), request.NamespacedName, cephObjectStoreUser)
 	if err != nil {
 		if kerrors.IsNotFound(err) {
-			logger.Debug("CephObjectStoreUser resource not found. Ignoring since object must be deleted.")
+			r.log.Debug("CephObjectStoreUser resource not found. Ignoring since object must be deleted.")
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, errors.Wrap(err, "failed to get CephObjectStoreUser")
 	}
+	r.log = newUserLogger(cephObjectStoreUser.Name, storeName(cephObjectStoreUser), cephObjectStoreUser.Namespace)
+
+	// The users of other stores are left to the operator of their store, e.g. when a secret they own changed
+	if r.store != "" && storeName(cephObjectStoreUser) != r.store {
+		r.log.Debugf("ignoring the user, only the users of object store %q are reconciled", r.store)
+		return reconcile.Result{}, nil
+	}
+
+	// A paused user is left as it is, also when it is deleted
+	if cephObjectStoreUser.GetAnnotations()[pausedAnnotation] == "true" {
+		return reconcile.Result{}, r.reportPaused(cephObjectStoreUser)
+	}
+
+	// A template has no RGW user of its own, it only generates the CRs of its users
+	if isTemplate(cephObjectStoreUser) {
+		return r.reconcileTemplate(cephObjectStoreUser)
+	}
+
+	// Several CRs can refer to the same RGW user, whose GET/create/modify sequence must not interleave
+	if r.locks == nil {
+		r.locks = &userLocks{}
+	}
+	unlock := r.locks.lock(fmt.Sprintf("%s/%s/%s", clusterNamespace(cephObjectStoreUser), storeName(cephObjectStoreUser), userID(cephObjectStoreUser)))
+	defer unlock()
+
+	// Record the metrics of the reconcile once it is done
+	deleted := false
+	defer func() {
+		if err != nil && r.failureReason == "" {
+			r.failureReason = failureReasonOther
+		}
+		observeReconcile(cephObjectStoreUser.Namespace, storeName(cephObjectStoreUser), time.Since(start), r.failureReason)
+		if deleted {
+			deletePhaseMetric(cephObjectStoreUser)
+		} else {
+			setPhaseMetric(cephObjectStoreUser)
+		}
+	}()
 
 	// The CR was just created, initializing status fields
 	if cephObjectStoreUser.Status == nil {
-		cephObjectStoreUser.Status = &cephv1.Status{}
-		cephObjectStoreUser.Status.Phase = k8sutil.Created
-		err := opcontroller.UpdateStatus(r.client, cephObjectStoreUser)
+		cephObjectStoreUser.Status = &cephv1.ObjectStoreUserStatus{}
+		cephObjectStoreUser.Status.Phase = cephv1.ObjectStoreUserPhaseCreated
+		err := r.updateStatus(cephObjectStoreUser)
 		if err != nil {
 			return reconcile.Result{}, errors.Wrap(err, "failed to set status")
 		}
 	}
+	if paused := findCondition(cephObjectStoreUser.Status.Conditions, cephv1.ConditionPaused); paused != nil && paused.Status == v1.ConditionTrue {
+		r.log.Infof("reconcile is resumed, the %q annotation was removed", pausedAnnotation)
+		setCondition(cephObjectStoreUser, cephv1.ConditionPaused, v1.ConditionFalse, reasonReconcileResumed, "the reconcile is resumed")
+	}
 
 	// Make sure a CephCluster is present otherwise do nothing
-	_, isReadyToReconcile, cephClusterExists, reconcileResponse := opcontroller.IsReadyToReconcile(r.client, r.context, request.NamespacedName)
+	_, isReadyToReconcile, cephClusterExists, reconcileResponse := opcontroller.IsReadyToReconcile(r.client, r.context, types.NamespacedName{Name: request.Name, Namespace: clusterNamespace(cephObjectStoreUser)})
 	if !isReadyToReconcile {
 		// This handles the case where the Ceph Cluster is gone and we want to delete that CR
 		// We skip the deleteUser() function since everything is gone already
@@ -157,13 +818,50 @@ func (r *ReconcileObjectStoreUser) reconcile(request reconcile.Request) (reconci
 			}
 
 			// Return and do not requeue. Successful deletion.
+			deleted = true
 			return reconcile.Result{}, nil
 		}
 
-		logger.Debugf("CephCluster resource not ready in namespace %q, retrying in %q.", request.NamespacedName.Namespace, opcontroller.WaitForRequeueIfCephClusterNotReadyAfter.String())
+		r.log.Debugf("CephCluster resource not ready in namespace %q, retrying in %q.", clusterNamespace(cephObjectStoreUser), opcontroller.WaitForRequeueIfCephClusterNotReadyAfter.String())
+		setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconciling, reasonCephClusterNotReady, fmt.Sprintf("waiting for the CephCluster in namespace %q to be ready", clusterNamespace(cephObjectStoreUser)))
+		err = r.updateStatus(cephObjectStoreUser)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrap(err, "failed to set status")
+		}
 		return reconcileResponse, nil
 	}
 
+	// Many clusters are HEALTH_WARN in their steady state, where managing users is safe. A user can
+	// always be deleted.
+	if cephObjectStoreUser.GetDeletionTimestamp().IsZero() {
+		health, err := r.cephClusterHealth(clusterNamespace(cephObjectStoreUser))
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		// A new CephCluster can be ready before its first ceph status is reported
+		if health == "" {
+			r.log.Debugf("CephCluster in namespace %q did not report its health yet, retrying in %q.", clusterNamespace(cephObjectStoreUser), opcontroller.WaitForRequeueIfCephClusterNotReadyAfter.String())
+			setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconciling, reasonCephClusterNotReady, fmt.Sprintf("waiting for the CephCluster in namespace %q to report its health", clusterNamespace(cephObjectStoreUser)))
+			err = r.updateStatus(cephObjectStoreUser)
+			if err != nil {
+				return reconcile.Result{}, errors.Wrap(err, "failed to set status")
+			}
+			return opcontroller.WaitForRequeueIfCephClusterNotReady, nil
+		}
+		if health == cephHealthError || (health == cephHealthWarn && r.blockOnHealthWarn) {
+			r.log.Debugf("CephCluster in namespace %q is %s, retrying in %q.", clusterNamespace(cephObjectStoreUser), health, opcontroller.WaitForRequeueIfCephClusterNotReadyAfter.String())
+			setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconciling, reasonCephClusterUnhealthy, fmt.Sprintf("waiting for the CephCluster in namespace %q to recover from %s", clusterNamespace(cephObjectStoreUser), health))
+			err = r.updateStatus(cephObjectStoreUser)
+			if err != nil {
+				return reconcile.Result{}, errors.Wrap(err, "failed to set status")
+			}
+			return opcontroller.WaitForRequeueIfCephClusterNotReady, nil
+		}
+		if health == cephHealthWarn {
+			r.clusterHealth = health
+		}
+	}
+
 	// Set a finalizer so we can do cleanup before the object goes away
 	err = opcontroller.AddFinalizerIfNotPresent(r.client, cephObjectStoreUser)
 	if err != nil {
@@ -171,7 +869,7 @@ func (r *ReconcileObjectStoreUser) reconcile(request reconcile.Request) (reconci
 	}
 
 	// validate isObjectStoreInitialized
-	objContext, err := r.isObjectStoreInitialized(cephObjectStoreUser)
+	objContext, objectStore, err := r.isObjectStoreInitialized(cephObjectStoreUser)
 	if err != nil {
 		if !cephObjectStoreUser.GetDeletionTimestamp().IsZero() {
 			// Remove finalizer
@@ -181,11 +879,24 @@ func (r *ReconcileObjectStoreUser) reconcile(request reconcile.Request) (reconci
 			}
 
 			// Return and do not requeue. Successful deletion.
+			deleted = true
 			return reconcile.Result{}, nil
 		}
-		logger.Debugf("ObjectStore resource not ready in namespace %q, retrying in %q. %v", request.NamespacedName.Namespace, opcontroller.WaitForRequeueIfCephClusterNotReadyAfter.String(), err)
-		cephObjectStoreUser.Status.Phase = k8sutil.ReconcileFailedStatus
-		err := opcontroller.UpdateStatus(r.client, cephObjectStoreUser)
+		// A store that does not exist is most likely a wrong store name, which waiting does not fix
+		if errors.Cause(err) == object.ErrObjectStoreNotFound {
+			message := fmt.Sprintf("CephObjectStore %q does not exist in namespace %q", storeName(cephObjectStoreUser), clusterNamespace(cephObjectStoreUser))
+			r.log.Errorf("ceph object user %q: %s, retrying in %q", cephObjectStoreUser.Name, message, objectStoreNotFoundRequeueAfter.String())
+			r.recorder.Event(cephObjectStoreUser, v1.EventTypeWarning, reasonObjectStoreNotFound, message)
+			setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconcileFailed, reasonObjectStoreNotFound, message)
+			err := r.updateStatus(cephObjectStoreUser)
+			if err != nil {
+				return reconcile.Result{}, errors.Wrap(err, "failed to set status")
+			}
+			return reconcile.Result{Requeue: true, RequeueAfter: objectStoreNotFoundRequeueAfter}, nil
+		}
+		r.log.Debugf("ObjectStore resource not ready in namespace %q, retrying in %q. %v", request.NamespacedName.Namespace, opcontroller.WaitForRequeueIfCephClusterNotReadyAfter.String(), err)
+		setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconcileFailed, reasonObjectStoreNotReady, err.Error())
+		err := r.updateStatus(cephObjectStoreUser)
 		if err != nil {
 			return reconcile.Result{}, errors.Wrap(err, "failed to set status")
 		}
@@ -193,17 +904,160 @@ func (r *ReconcileObjectStoreUser) reconcile(request reconcile.Request) (reconci
 	}
 	// Set the object store context
 	r.objContext = objContext
+	r.objectStore = objectStore
+	refreshObjectStoreProgressing(cephObjectStoreUser, objectStore)
 
-	// Generate user config
-	userConfig := generateUserConfig(cephObjectStoreUser)
+	// Generate user config, with the defaults of the object store for what the spec does not set
+	effectiveUser := cephObjectStoreUser.DeepCopy()
+	effectiveUser.Spec = withStoreDefaults(cephObjectStoreUser.Spec, objectStore.Spec)
+	// A change of the defaults is a change of the spec too, unlike the capacity a size in percent
+	// resolves to
+	specHash := hashUserSpec(effectiveUser)
+	err = r.resolveMaxSizePercent(effectiveUser)
+	if err != nil {
+		setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconcileFailed, reasonQuotaCapacityUnavailable, err.Error())
+		errStatus := r.updateStatus(cephObjectStoreUser)
+		if errStatus != nil {
+			return reconcile.Result{}, errors.Wrap(errStatus, "failed to set status")
+		}
+		return reconcile.Result{}, err
+	}
+	userConfig, clampedQuotas, err := generateUserConfig(effectiveUser, objectStore.Spec.MaxUserQuotas)
+	if err != nil {
+		return r.failInvalid(cephObjectStoreUser, err)
+	}
+	// The rest of the state of the reconcile starts from what the previous reconciles recorded in the status
 	r.userConfig = userConfig
+	r.userSpec = withRestoredKeys(withDeterministicKey(effectiveUser.Spec))
+	r.keyStatus = cephObjectStoreUser.Status.Keys
+	r.tempURLKeyIDs = cephObjectStoreUser.Status.TempURLKeys
+	r.managedCaps = cephObjectStoreUser.Status.Capabilities
+	r.linkedBuckets = cephObjectStoreUser.Status.LinkedBuckets
+	r.maxBucketsSet = cephObjectStoreUser.Status.MaxBucketsSet
+	expiredCondition := findCondition(cephObjectStoreUser.Status.Conditions, cephv1.ConditionExpired)
+	r.suspendedOnExpiry = expiredCondition != nil && expiredCondition.Status == v1.ConditionTrue
+	overQuotaCondition := findCondition(cephObjectStoreUser.Status.Conditions, cephv1.ConditionOverQuota)
+	r.overQuotaReported = overQuotaCondition != nil && overQuotaCondition.Status == v1.ConditionTrue
+	breachCondition := findCondition(cephObjectStoreUser.Status.Conditions, cephv1.ConditionSuspendedOnQuotaBreach)
+	r.suspendedOnBreach = breachCondition != nil && breachCondition.Status == v1.ConditionTrue
+
+	// Only correct the drift of an opted out user if its spec changed since the last reconcile, or if a
+	// resync was forced with a value the user was not reconciled with yet
+	forceResync := cephObjectStoreUser.GetAnnotations()[forceResyncAnnotation]
+	r.skipDriftCorrection = cephObjectStoreUser.GetAnnotations()[disableDriftCorrectionAnnotation] == "true" && specHash != "" && cephObjectStoreUser.Status.ObservedSpecHash == specHash
+	r.resyncing = specHash != "" && cephObjectStoreUser.Status.ObservedSpecHash == specHash && cephObjectStoreUser.Status.Phase == cephv1.ObjectStoreUserPhaseReady
+	if forceResync != "" && forceResync != cephObjectStoreUser.Status.ObservedForceResync {
+		r.log.Infof("resync of ceph object user %q forced with %q", cephObjectStoreUser.Name, forceResync)
+		r.skipDriftCorrection = false
+	}
+	if debugReconcile := cephObjectStoreUser.GetAnnotations()[debugReconcileAnnotation]; debugReconcile != "" && debugReconcile != cephObjectStoreUser.Status.ObservedDebugReconcile {
+		if r.allowDebugReconcile {
+			r.log.Infof("debug reconcile of ceph object user %q with %q", cephObjectStoreUser.Name, debugReconcile)
+			r.skipDriftCorrection = false
+			r.adminStats.Trace = true
+			// Recorded right away, so that a failing reconcile is not traced over and over
+			cephObjectStoreUser.Status.ObservedDebugReconcile = debugReconcile
+			defer func() {
+				r.logDebugReconcile(cephObjectStoreUser, err)
+			}()
+		} else {
+			r.log.Warningf("ignoring the %q annotation, the operator does not allow debug reconciles", debugReconcileAnnotation)
+		}
+	}
+	r.dryRun = cephObjectStoreUser.GetAnnotations()[dryRunAnnotation] == "true"
+	if r.dryRun {
+		// A dry run must not change anything, even if a change slips past applyChange
+		r.objContext = object.NewReadOnlyContext(r.objContext)
+	}
+
+	// The rgw pods may run before the admin commands of a new store succeed, so wait for them instead
+	// of failing the user with a confusing connection error
+	err = object.ProbeAdmin(r.objContext)
+	if err != nil {
+		if object.IsPermissionDenied(err) {
+			return r.reportAdminCapsInsufficient(cephObjectStoreUser, err)
+		}
+		message := fmt.Sprintf("waiting for the admin commands of CephObjectStore %q to respond, radosgw-admin ran against %s", storeName(cephObjectStoreUser), r.adminTarget(cephObjectStoreUser))
+		r.log.Infof("ceph object user %q: %s, retrying in %q. %v", cephObjectStoreUser.Name, message, opcontroller.WaitForRequeueIfCephClusterNotReadyAfter.String(), err)
+		setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconciling, reasonAdminNotResponding, message)
+		err = r.updateStatus(cephObjectStoreUser)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrap(err, "failed to set status")
+		}
+		return opcontroller.WaitForRequeueIfCephClusterNotReady, nil
+	}
 
 	// DELETE: the CR was deleted
 	if !cephObjectStoreUser.GetDeletionTimestamp().IsZero() {
-		logger.Debugf("deleting pool %q", cephObjectStoreUser.Name)
-		err := deleteUser(r.context, cephObjectStoreUser)
+		// The user and its secret are kept until the grace period passed, unless the deletion is forced
+		if wait := deletionGraceRemaining(cephObjectStoreUser); wait > 0 {
+			deleteAt := metav1.NewTime(time.Now().Add(wait).Truncate(time.Second))
+			cephObjectStoreUser.Status.DeletionScheduledAt = &deleteAt
+			message := fmt.Sprintf("the user is deleted at %s, set preservePolicy to %q to keep it or annotate the CR with %s=true to delete it now", deleteAt.Format(time.RFC3339), cephv1.PreservePolicyRetain, forceDeleteAnnotation)
+			r.log.Infof("ceph object user %q: %s", cephObjectStoreUser.Name, message)
+			setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconciling, reasonDeletionScheduled, message)
+			err = r.updateStatus(cephObjectStoreUser)
+			if err != nil {
+				return reconcile.Result{}, errors.Wrap(err, "failed to set status")
+			}
+			return reconcile.Result{Requeue: true, RequeueAfter: wait}, nil
+		}
+
+		// A user that was only reconciled in dry runs was never created by the operator, so it is not
+		// deleted either. A user that was reconciled before the dry run was annotated is deleted.
+		if r.dryRun && observedUserID(cephObjectStoreUser) == "" {
+			r.log.Infof("dry run: not deleting ceph object user %q", cephObjectStoreUser.Name)
+		} else if cephObjectStoreUser.Spec.PreservePolicy == cephv1.PreservePolicyRetain {
+			// The secret is garbage collected with the CR that owns it
+			r.log.Infof("preserving ceph object user %q in store %q, only its secret is removed", cephObjectStoreUser.Name, storeName(cephObjectStoreUser))
+		} else {
+			// The buckets of the claims would be orphaned, unless their data is purged with the user
+			if !cephObjectStoreUser.Spec.PurgeDataOnDeletion {
+				objectBuckets, err := r.objectBucketsOfUser(cephObjectStoreUser)
+				if err != nil {
+					return reconcile.Result{}, errors.Wrapf(err, "failed to check the object buckets of ceph object user %q", cephObjectStoreUser.Name)
+				}
+				if len(objectBuckets) > 0 {
+					message := fmt.Sprintf("deletion is blocked until the object buckets %s of the user are removed, or purgeDataOnDeletion is set", strings.Join(objectBuckets, ", "))
+					r.log.Warningf("ceph object user %q: %s", cephObjectStoreUser.Name, message)
+					r.recorder.Event(cephObjectStoreUser, v1.EventTypeWarning, reasonObjectBucketsExist, message)
+					setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconciling, reasonObjectBucketsExist, message)
+					err = r.updateStatus(cephObjectStoreUser)
+					if err != nil {
+						return reconcile.Result{}, errors.Wrap(err, "failed to set status")
+					}
+					return reconcile.Result{Requeue: true, RequeueAfter: objectBucketsRequeueAfter}, nil
+				}
+			}
+			r.log.Debugf("deleting pool %q", cephObjectStoreUser.Name)
+			err := r.deleteUser(r.newObjectContext(cephObjectStoreUser), cephObjectStoreUser)
+			if err != nil {
+				// The finalizer is only removed once the user is deleted or known to be gone, the
+				// deletion is retried with the backoff of the controller
+				phase, reason := cephv1.ObjectStoreUserPhaseReconcileFailed, reasonDeletionFailed
+				if object.IsTransientError(err) {
+					phase, reason = cephv1.ObjectStoreUserPhaseReconciling, reasonDeletionRetrying
+				}
+				setPhase(cephObjectStoreUser, phase, reason, err.Error())
+				errStatus := r.updateStatus(cephObjectStoreUser)
+				if errStatus != nil {
+					r.log.Errorf("failed to set status. %v", errStatus)
+				}
+				return reconcile.Result{}, errors.Wrapf(err, "failed to delete ceph object user %q", cephObjectStoreUser.Name)
+			}
+		}
+
+		// The copies of the secret in other namespaces are not garbage collected with the CR. A copy made
+		// by a reconcile that failed afterwards is only in the spec.
+		replicaNamespaces := cephObjectStoreUser.Status.SecretNamespaces
+		for _, namespace := range cephObjectStoreUser.Spec.SecretNamespaces {
+			if namespace != cephObjectStoreUser.Namespace && !contains(replicaNamespaces, namespace) {
+				replicaNamespaces = append(replicaNamespaces, namespace)
+			}
+		}
+		err = r.deleteSecretReplicas(cephObjectStoreUser, replicaNamespaces)
 		if err != nil {
-			return reconcile.Result{}, errors.Wrapf(err, "failed to delete ceph object user %q", cephObjectStoreUser.Name)
+			return reconcile.Result{}, err
 		}
 
 		// Remove finalizer
@@ -213,202 +1067,2871 @@ func (r *ReconcileObjectStoreUser) reconcile(request reconcile.Request) (reconci
 		}
 
 		// Return and do not requeue. Successful deletion.
+		deleted = true
 		return reconcile.Result{}, nil
 	}
 
 	// validate the user settings
-	err = ValidateUser(cephObjectStoreUser)
+	// The defaults of the object store must be valid for the user too
+	err = ValidateUser(effectiveUser)
 	if err != nil {
-		cephObjectStoreUser.Status.Phase = k8sutil.ReconcileFailedStatus
-		err := opcontroller.UpdateStatus(r.client, cephObjectStoreUser)
-		if err != nil {
-			return reconcile.Result{}, errors.Wrap(err, "failed to set status")
-		}
-		return reconcile.Result{}, errors.Wrapf(err, "invalid pool CR %q spec", cephObjectStoreUser.Name)
+		return r.failInvalid(cephObjectStoreUser, err)
+	}
+
+	// The radosgw-admin commands of the user run in the realm named after the store
+	cephObjectStoreUser.Status.Realm = r.objContext.Name
+	if effectiveUser.Spec.Realm != "" && effectiveUser.Spec.Realm != r.objContext.Name {
+		err = errors.Errorf("realm %q does not match realm %q of object store %q", effectiveUser.Spec.Realm, r.objContext.Name, storeName(cephObjectStoreUser))
+		return r.failInvalid(cephObjectStoreUser, err)
+	}
+
+	// Anyone holding the seed of a deterministic key can derive the key, which the operator must allow
+	if effectiveUser.Spec.DeterministicKey != nil && !r.allowDeterministicKeys {
+		err = errors.New("deterministic keys are not allowed by the operator, ROOK_OBJECT_USER_ALLOW_DETERMINISTIC_KEYS is not set")
+		return r.failInvalid(cephObjectStoreUser, err)
+	}
+
+	// Changing the uid would create another RGW user and leave the previous one behind
+	if previousUID := observedUserID(cephObjectStoreUser); previousUID != "" && previousUID != userID(cephObjectStoreUser) {
+		err = errors.Errorf("uid cannot be changed from %q to %q once the user is created", previousUID, userID(cephObjectStoreUser))
+		return r.failInvalid(cephObjectStoreUser, err)
+	}
+
+	// Report whether the requested quotas had to be lowered to the object store maximum
+	if len(clampedQuotas) > 0 {
+		message := fmt.Sprintf("requested quotas exceed the maximum of object store %q, clamped %s", storeName(cephObjectStoreUser), strings.Join(clampedQuotas, ", "))
+		r.log.Warningf("ceph object user %q %s", cephObjectStoreUser.Name, message)
+		setCondition(cephObjectStoreUser, cephv1.ConditionQuotaClamped, v1.ConditionTrue, "QuotaExceedsMaximum", message)
+	} else if findCondition(cephObjectStoreUser.Status.Conditions, cephv1.ConditionQuotaClamped) != nil {
+		setCondition(cephObjectStoreUser, cephv1.ConditionQuotaClamped, v1.ConditionFalse, "QuotaWithinMaximum", "requested quotas are within the object store maximum")
 	}
 
 	// Start object reconciliation, updating status for this
-	cephObjectStoreUser.Status.Phase = k8sutil.ReconcilingStatus
-	err = opcontroller.UpdateStatus(r.client, cephObjectStoreUser)
+	setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconciling, reasonReconciling, "reconciling the object store user")
+	err = r.updateStatus(cephObjectStoreUser)
 	if err != nil {
 		return reconcile.Result{}, errors.Wrap(err, "failed to set status")
 	}
 
 	// CREATE/UPDATE CEPH USER
 	reconcileResponse, err = r.reconcileCephUser(cephObjectStoreUser)
+	if err != nil && isPermissionDenied(err) {
+		return r.reportAdminCapsInsufficient(cephObjectStoreUser, err)
+	}
+	if err != nil && isReadOnly(err) {
+		return r.reportObjectStoreReadOnly(cephObjectStoreUser, err)
+	}
+	if findCondition(cephObjectStoreUser.Status.Conditions, cephv1.ConditionObjectStoreReadOnly) != nil {
+		setCondition(cephObjectStoreUser, cephv1.ConditionObjectStoreReadOnly, v1.ConditionFalse, "ObjectStoreWritable", "the object store accepts changes")
+	}
 	if err != nil {
-		cephObjectStoreUser.Status.Phase = k8sutil.ReconcileFailedStatus
-		errStatus := opcontroller.UpdateStatus(r.client, cephObjectStoreUser)
+		r.failureReason = failureReasonCephUser
+		cephObjectStoreUser.Status.FailedStep = ""
+		if stepErr, ok := errors.Cause(err).(*StepError); ok {
+			cephObjectStoreUser.Status.FailedStep = stepErr.Step
+		}
+		cephObjectStoreUser.Status.ComponentStatus = r.componentStatus(err)
+		r.refreshBreachSuspension(cephObjectStoreUser)
+		reason := reasonCephUserFailed
+		switch errors.Cause(err).(type) {
+		case *NoKeysError:
+			reason = reasonUserHasNoKeys
+		case *KeyNotProvidedError:
+			reason = reasonKeyNotProvided
+		case *UserConflictError:
+			reason = reasonUserConflict
+		}
+		message := fmt.Sprintf("%s, radosgw-admin ran against %s", err.Error(), r.adminTarget(cephObjectStoreUser))
+		r.recorder.Event(cephObjectStoreUser, v1.EventTypeWarning, reason, message)
+		setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconcileFailed, reason, message)
+		errStatus := r.updateStatus(cephObjectStoreUser)
 		if errStatus != nil {
 			return reconcile.Result{}, errors.Wrap(errStatus, "failed to set status")
 		}
 		return reconcileResponse, err
 	}
 
+	// Report the planned changes without touching the secret or the rest of the status
+	if r.dryRun {
+		r.log.Infof("dry run: ceph object user %q planned changes %q", r.userConfig.UserID, strings.Join(r.changes, ","))
+		cephObjectStoreUser.Status.PlannedChanges = r.changes
+		setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseValidated, reasonValidated, "the dry run validated the object store user")
+		err = r.updateStatus(cephObjectStoreUser)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrap(err, "failed to set status")
+		}
+		return reconcile.Result{}, nil
+	}
+
+	cephObjectStoreUser.Status.UID = r.userConfig.UserID
+
 	// CREATE/UPDATE KUBERNETES SECRET
-	reconcileResponse, err = r.reconcileCephUserSecret(cephObjectStoreUser)
+	if cephObjectStoreUser.Spec.DisableSecretGeneration {
+		r.log.Debugf("secret generation of ceph object user %q is disabled", cephObjectStoreUser.Name)
+		cephObjectStoreUser.Status.SecretHash = ""
+	} else {
+		reconcileResponse, err = r.reconcileCephUserSecret(cephObjectStoreUser)
+		if err != nil {
+			r.failureReason = failureReasonSecret
+			setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconcileFailed, reasonSecretFailed, err.Error())
+			errStatus := r.updateStatus(cephObjectStoreUser)
+			if errStatus != nil {
+				return reconcile.Result{}, errors.Wrap(errStatus, "failed to set status")
+			}
+			return reconcileResponse, err
+		}
+	}
+
+	// The keys replaced by a rotation are only removed once the secret holds the new key
+	err = r.removeRotatedKeys()
 	if err != nil {
-		cephObjectStoreUser.Status.Phase = k8sutil.ReconcileFailedStatus
-		errStatus := opcontroller.UpdateStatus(r.client, cephObjectStoreUser)
+		r.failureReason = failureReasonCephUser
+		r.logAudit()
+		setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconcileFailed, reasonKeyRotationFailed, err.Error())
+		errStatus := r.updateStatus(cephObjectStoreUser)
 		if errStatus != nil {
 			return reconcile.Result{}, errors.Wrap(errStatus, "failed to set status")
 		}
-		return reconcileResponse, err
+		return reconcile.Result{}, err
 	}
 
-	// Set Ready status, we are done reconciling
-	cephObjectStoreUser.Status.Phase = k8sutil.ReadyStatus
-	err = opcontroller.UpdateStatus(r.client, cephObjectStoreUser)
+	// CREATE INITIAL BUCKETS, with the keys of the user that are now in RGW
+	err = r.reconcileInitialBuckets(cephObjectStoreUser)
 	if err != nil {
-		return reconcile.Result{}, errors.Wrap(err, "failed to set status")
+		r.failureReason = failureReasonInitialBuckets
+		r.logAudit()
+		setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReconcileFailed, reasonInitialBucketsFailed, err.Error())
+		errStatus := r.updateStatus(cephObjectStoreUser)
+		if errStatus != nil {
+			return reconcile.Result{}, errors.Wrap(errStatus, "failed to set status")
+		}
+		return reconcile.Result{}, err
 	}
 
-	// Return and do not requeue
-	logger.Debug("done reconciling")
-	return reconcile.Result{}, nil
-}
+	// Emit a single audit entry for everything this reconcile changed
+	r.logAudit()
 
-func (r *ReconcileObjectStoreUser) reconcileCephUser(cephObjectStoreUser *cephv1.CephObjectStoreUser) (reconcile.Result, error) {
-	err := r.createCephUser(cephObjectStoreUser)
+	// Report whether RGW enforces the quotas of the user
+	if r.quotaStatus != nil {
+		cephObjectStoreUser.Status.Quota = r.quotaStatus
+	}
+	r.refreshBuckets(cephObjectStoreUser)
+	r.refreshEffectiveConfig(cephObjectStoreUser)
+	r.refreshQuotaUtilization(cephObjectStoreUser)
+	refreshKeyRotation(cephObjectStoreUser, r.userConfig.AccessKey)
+	refreshExpiry(cephObjectStoreUser)
+	cephObjectStoreUser.Status.MaxBucketsSet = r.maxBucketsSet
+	cephObjectStoreUser.Status.LinkedBuckets = nil
+	if len(r.linkedBuckets) > 0 {
+		cephObjectStoreUser.Status.LinkedBuckets = r.linkedBuckets
+	}
+	cephObjectStoreUser.Status.Capabilities = nil
+	if len(r.managedCaps) > 0 {
+		cephObjectStoreUser.Status.Capabilities = r.managedCaps
+	}
+	if r.placementTags != nil {
+		cephObjectStoreUser.Status.PlacementTags = nil
+		if len(r.placementTags) > 0 {
+			cephObjectStoreUser.Status.PlacementTags = r.placementTags
+		}
+	}
+	cephObjectStoreUser.Status.TempURLKeys = nil
+	if len(r.tempURLKeyIDs) > 0 {
+		cephObjectStoreUser.Status.TempURLKeys = r.tempURLKeyIDs
+	}
+	if len(r.bucketConflicts) > 0 {
+		message := strings.Join(r.bucketConflicts, ", ")
+		r.log.Warningf("ceph object user %q cannot link all buckets, %s", r.userConfig.UserID, message)
+		setCondition(cephObjectStoreUser, cephv1.ConditionBucketLinkConflict, v1.ConditionTrue, "BucketsNotLinkable", message)
+	} else if findCondition(cephObjectStoreUser.Status.Conditions, cephv1.ConditionBucketLinkConflict) != nil {
+		setCondition(cephObjectStoreUser, cephv1.ConditionBucketLinkConflict, v1.ConditionFalse, "BucketsLinked", "the buckets of the spec are linked to the user")
+	}
+	r.refreshOverQuota(cephObjectStoreUser)
+	r.refreshBreachSuspension(cephObjectStoreUser)
+	cephObjectStoreUser.Status.Tags = cephObjectStoreUser.Spec.Tags
+	cephObjectStoreUser.Status.ObservedSpecHash = specHash
+	cephObjectStoreUser.Status.ObservedForceResync = forceResync
+	cephObjectStoreUser.Status.ObservedGeneration = cephObjectStoreUser.Generation
+	cephObjectStoreUser.Status.PlannedChanges = nil
+	cephObjectStoreUser.Status.FailedStep = ""
+	cephObjectStoreUser.Status.ComponentStatus = r.componentStatus(nil)
+	cephObjectStoreUser.Status.Keys = nil
+	for _, key := range r.keys {
+		cephObjectStoreUser.Status.Keys = append(cephObjectStoreUser.Status.Keys, cephv1.ObjectUserKeyStatus{Name: key.name, AccessKey: key.accessKey})
+	}
+
+	// Set Ready status, we are done reconciling
+	message := "the object store user is reconciled"
+	if r.clusterHealth != "" {
+		message = fmt.Sprintf("%s while the CephCluster is %s", message, r.clusterHealth)
+	}
+	setPhase(cephObjectStoreUser, cephv1.ObjectStoreUserPhaseReady, reasonReconciled, message)
+	err = r.updateStatus(cephObjectStoreUser)
 	if err != nil {
-		return reconcile.Result{}, errors.Wrapf(err, "failed to create object store user %q", cephObjectStoreUser.Name)
+		return reconcile.Result{}, errors.Wrap(err, "failed to set status")
 	}
 
+	// Return and only requeue for the periodic resync, to suspend the user once it expires or to rotate
+	// its key once the rotation is due
+	r.log.Debug("done reconciling")
+	if requeueAfter := keyRotationRequeueAfter(cephObjectStoreUser, expiryRequeueAfter(cephObjectStoreUser.Spec, r.resyncPeriod)); requeueAfter > 0 {
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	}
 	return reconcile.Result{}, nil
 }
 
-func (r *ReconcileObjectStoreUser) createCephUser(u *cephv1.CephObjectStoreUser) error {
-	logger.Infof("creating ceph object user %q in namespace %q", u.Name, u.Namespace)
-	user, rgwerr, err := object.CreateUser(r.objContext, r.userConfig)
-	if err != nil {
-		if rgwerr == object.ErrorCodeFileExists {
-			objectUser, _, err := object.GetUser(r.objContext, r.userConfig.UserID)
-			if err != nil {
-				return errors.Wrapf(err, "failed to get details from ceph object user %q", objectUser.UserID)
-			}
-
-			// Set access and secret key
-			r.userConfig.AccessKey = objectUser.AccessKey
-			r.userConfig.SecretKey = objectUser.SecretKey
+// failInvalid fails the reconcile of a user whose spec is invalid. It fails the same way until the spec
+// changes, so it is only retried with the failure backoff, see Reconcile.
+func (r *ReconcileObjectStoreUser) failInvalid(u *cephv1.CephObjectStoreUser, err error) (reconcile.Result, error) {
+	r.failureReason = failureReasonInvalidSpec
+	setPhase(u, cephv1.ObjectStoreUserPhaseReconcileFailed, reasonInvalidSpec, err.Error())
+	errStatus := r.updateStatus(u)
+	if errStatus != nil {
+		return reconcile.Result{}, errors.Wrap(errStatus, "failed to set status")
+	}
+	return reconcile.Result{}, errors.Wrapf(err, "invalid object store user CR %q spec", u.Name)
+}
 
-			return nil
-		}
-		return errors.Wrapf(err, "failed to create ceph object user %q. error code %d", u.Name, rgwerr)
+// reportAdminCapsInsufficient fails a user whose admin commands were denied. The ceph credentials the
+// operator runs radosgw-admin with must be granted the caps, so the user is only retried slowly.
+func (r *ReconcileObjectStoreUser) reportAdminCapsInsufficient(u *cephv1.CephObjectStoreUser, err error) (reconcile.Result, error) {
+	message := fmt.Sprintf("radosgw-admin was denied access, grant the ceph credentials of the operator the mon and osd caps to manage the users of CephObjectStore %q, e.g. \"mon 'allow rw' osd 'allow rwx'\". radosgw-admin ran against %s. %v", storeName(u), r.adminTarget(u), err)
+	r.log.Errorf("ceph object user %q: %s, retrying in %q", u.Name, message, adminCapsRequeueAfter.String())
+	r.recorder.Event(u, v1.EventTypeWarning, reasonAdminCapsInsufficient, message)
+	setPhase(u, cephv1.ObjectStoreUserPhaseReconcileFailed, reasonAdminCapsInsufficient, message)
+	errStatus := r.updateStatus(u)
+	if errStatus != nil {
+		return reconcile.Result{}, errors.Wrap(errStatus, "failed to set status")
+	}
+	return reconcile.Result{Requeue: true, RequeueAfter: adminCapsRequeueAfter}, nil
+}
+
+// reportObjectStoreReadOnly reports a user whose object store does not accept changes, e.g. during a
+// planned maintenance. It is not a failure of the user, which is retried until the store accepts
+// changes again without returning an error for every retry.
+func (r *ReconcileObjectStoreUser) reportObjectStoreReadOnly(u *cephv1.CephObjectStoreUser, err error) (reconcile.Result, error) {
+	message := fmt.Sprintf("CephObjectStore %q is read-only, the user is updated once it accepts changes again. %v", storeName(u), err)
+	r.log.Infof("ceph object user %q: %s, retrying in %q", u.Name, message, readOnlyRequeueAfter.String())
+	setCondition(u, cephv1.ConditionObjectStoreReadOnly, v1.ConditionTrue, reasonObjectStoreReadOnly, message)
+	setPhase(u, cephv1.ObjectStoreUserPhaseReconciling, reasonObjectStoreReadOnly, message)
+	errStatus := r.updateStatus(u)
+	if errStatus != nil {
+		return reconcile.Result{}, errors.Wrap(errStatus, "failed to set status")
+	}
+	return reconcile.Result{Requeue: true, RequeueAfter: readOnlyRequeueAfter}, nil
+}
+
+// reportPaused reports that the reconcile of the user is paused by its annotation. The status is only
+// updated when the user is paused, not on every resync while it stays paused.
+func (r *ReconcileObjectStoreUser) reportPaused(u *cephv1.CephObjectStoreUser) error {
+	r.log.Infof("reconcile is paused by the %q annotation, the user is not changed", pausedAnnotation)
+	if u.Status == nil {
+		u.Status = &cephv1.ObjectStoreUserStatus{}
+	}
+	if paused := findCondition(u.Status.Conditions, cephv1.ConditionPaused); paused != nil && paused.Status == v1.ConditionTrue {
+		return nil
+	}
+	setCondition(u, cephv1.ConditionPaused, v1.ConditionTrue, reasonReconcilePaused, fmt.Sprintf("the reconcile is paused by the %s annotation", pausedAnnotation))
+	err := r.updateStatus(u)
+	if err != nil {
+		return errors.Wrap(err, "failed to set status")
+	}
+	return nil
+}
+
+func (r *ReconcileObjectStoreUser) reconcileCephUser(cephObjectStoreUser *cephv1.CephObjectStoreUser) (reconcile.Result, error) {
+	// The referenced keys must be verified before anything is changed in RGW
+	err := r.readKeyRefs(cephObjectStoreUser)
+	if err == nil {
+		err = r.readDeterministicKey(cephObjectStoreUser)
+	}
+	if err == nil {
+		err = r.readRestoredKeys(cephObjectStoreUser)
+	}
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to read the keys of object store user %q", cephObjectStoreUser.Name)
+	}
+
+	err = r.createCephUser(cephObjectStoreUser)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to create object store user %q", cephObjectStoreUser.Name)
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (r *ReconcileObjectStoreUser) createCephUser(u *cephv1.CephObjectStoreUser) error {
+	if r.dryRun {
+		return r.planCephUser(u)
+	}
+
+	objectUser, err := r.lookupReconciledUser(u)
+	if err != nil {
+		return err
+	}
+	if objectUser != nil {
+		return r.updateExistingUser(u, objectUser)
+	}
+
+	// A user whose keys are not generated is created with a provided key, an existing user keeps its keys
+	createConfig := r.userConfig
+	if !generatesKeys(r.userSpec) {
+		key, ok := r.providedKey()
+		if !ok {
+			_, rgwerr, err := object.GetUser(r.objContext, r.userConfig.UserID)
+			if err != nil {
+				if rgwerr != object.RGWErrorNotFound {
+					return errors.Wrapf(err, "failed to get details from ceph object user %q", r.userConfig.UserID)
+				}
+				return &KeyNotProvidedError{User: r.userConfig.UserID}
+			}
+		} else {
+			createConfig.AccessKey = &key.accessKey
+			createConfig.SecretKey = &key.secretKey
+		}
+	}
+
+	r.log.Infof("creating ceph object user %q in namespace %q", r.userConfig.UserID, u.Namespace)
+	user, rgwerr, err := object.CreateUser(r.objContext, createConfig)
+	if err != nil {
+		// The user may have been created by a previous reconcile or concurrently, it is updated instead
+		if rgwerr == object.ErrorCodeFileExists {
+			r.log.Debugf("ceph object user %q already exists, updating it", r.userConfig.UserID)
+			objectUser, _, err := object.GetUser(r.objContext, r.userConfig.UserID)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get details from ceph object user %q", r.userConfig.UserID)
+			}
+			return r.updateExistingUser(u, objectUser)
+		}
+		return errors.Wrapf(err, "failed to create ceph object user %q. error code %d", r.userConfig.UserID, rgwerr)
 	}
 
 	// Set access and secret key
 	r.userConfig.AccessKey = user.AccessKey
 	r.userConfig.SecretKey = user.SecretKey
+	r.recordUserCreated()
+	r.log.Infof("created ceph object user %q", r.userConfig.UserID)
+
+	// Settings that cannot be passed on creation, like the quota, are applied as an update
+	return r.updateCephUser(user)
+}
+
+// lookupReconciledUser returns the ceph user of a user that is resynced, nil if the user is not resynced
+// or its ceph user does not exist anymore. The ceph user is looked up rather than created, which would
+// only fail because it exists, so that a resync of an unchanged user makes no changes at all.
+func (r *ReconcileObjectStoreUser) lookupReconciledUser(u *cephv1.CephObjectStoreUser) (*object.ObjectUser, error) {
+	if !r.resyncing || r.recreatingUser {
+		return nil, nil
+	}
+	objectUser, rgwerr, err := object.GetUser(r.objContext, r.userConfig.UserID)
+	if err != nil {
+		if rgwerr == object.RGWErrorNotFound {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get details from ceph object user %q", r.userConfig.UserID)
+	}
+	return objectUser, nil
+}
+
+// updateExistingUser updates a ceph user that exists already to the spec, once its ownership is checked
+func (r *ReconcileObjectStoreUser) updateExistingUser(u *cephv1.CephObjectStoreUser, objectUser *object.ObjectUser) error {
+	err := r.checkUserConflict(u, objectUser)
+	if err != nil {
+		return err
+	}
+
+	// Set access and secret key
+	r.userConfig.AccessKey = objectUser.AccessKey
+	r.userConfig.SecretKey = objectUser.SecretKey
+	err = r.ensureUserKey(objectUser)
+	if err != nil {
+		return err
+	}
+	// The key is rotated on its schedule even if the drift of the user is not corrected
+	err = r.reconcileKeyRotation(u, objectUser)
+	if err != nil {
+		return err
+	}
+
+	if r.skipDriftCorrection {
+		r.log.Debugf("drift correction of ceph object user %q is disabled and its spec did not change", r.userConfig.UserID)
+		// Still resolve the named keys for the secret
+		_, err := r.reconcileKeys(objectUser, false)
+		if err != nil {
+			return err
+		}
+		_, err = r.reconcileSwiftKeys(objectUser, false)
+		if err != nil {
+			return err
+		}
+		_, err = r.reconcileSubuserKeys(objectUser, false)
+		if err != nil {
+			return err
+		}
+		_, err = r.reconcileTempURLKeys(objectUser, false)
+		if err != nil {
+			return err
+		}
+		// The user still expires while its drift is not corrected
+		if !r.managesSuspension() {
+			return nil
+		}
+		return r.runStep("suspend", func() error {
+			_, err := r.reconcileSuspension(objectUser)
+			return err
+		})
+	}
+	changes, completedSteps := len(r.changes), len(r.completedSteps)
+	err = r.updateCephUser(objectUser)
+	// The user was deleted out of band since it was looked up, it is created again once
+	if isUserNotFound(err) && !r.recreatingUser {
+		r.log.Warningf("ceph object user %q was deleted while it was updated, creating it again", r.userConfig.UserID)
+		r.changes, r.completedSteps = r.changes[:changes], r.completedSteps[:completedSteps]
+		r.recreatingUser = true
+		return r.createCephUser(u)
+	}
+	return err
+}
+
+// checkUserConflict fails with a UserConflictError if the existing user is not managed by the CR yet and
+// its display name or email differ from the spec, which hints at an unrelated user with the same uid.
+// The spec of a user that was reconciled before may change both, and an adopted user is taken over.
+func (r *ReconcileObjectStoreUser) checkUserConflict(u *cephv1.CephObjectStoreUser, objectUser *object.ObjectUser) error {
+	if r.userSpec.Adopt || (u.Status != nil && u.Status.ObservedSpecHash != "") {
+		return nil
+	}
+	differences := []string{}
+	if objectUser.DisplayName != nil && r.userConfig.DisplayName != nil && *objectUser.DisplayName != *r.userConfig.DisplayName {
+		differences = append(differences, fmt.Sprintf("display name %q instead of %q", *objectUser.DisplayName, *r.userConfig.DisplayName))
+	}
+	if objectUser.Email != nil && *objectUser.Email != "" && (r.userConfig.Email == nil || *objectUser.Email != *r.userConfig.Email) {
+		differences = append(differences, fmt.Sprintf("email %q", *objectUser.Email))
+	}
+	if len(differences) > 0 {
+		return &UserConflictError{User: r.userConfig.UserID, Differences: differences}
+	}
+	return nil
+}
+
+// planCephUser records the changes creating or updating the ceph user would make, without making them
+func (r *ReconcileObjectStoreUser) planCephUser(u *cephv1.CephObjectStoreUser) error {
+	r.log.Infof("dry run: planning ceph object user %q in namespace %q", u.Name, u.Namespace)
+	objectUser, rgwerr, err := object.GetUser(r.objContext, r.userConfig.UserID)
+	if err != nil {
+		if rgwerr != object.RGWErrorNotFound {
+			return errors.Wrapf(err, "failed to get details from ceph object user %q", r.userConfig.UserID)
+		}
+
+		if _, ok := r.providedKey(); !ok && !generatesKeys(r.userSpec) {
+			return &KeyNotProvidedError{User: r.userConfig.UserID}
+		}
+		// The user would be created with the display name and email, the rest is applied as an update
+		r.recordUserCreated()
+		objectUser = &object.ObjectUser{UserID: r.userConfig.UserID, DisplayName: r.userConfig.DisplayName, Email: r.userConfig.Email}
+	} else {
+		err = r.checkUserConflict(u, objectUser)
+		if err != nil {
+			return err
+		}
+		err = r.ensureUserKey(objectUser)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.skipDriftCorrection {
+		return nil
+	}
+	return r.updateCephUser(objectUser)
+}
+
+// ensureUserKey generates a key for an existing user without top-level keys, e.g. a user migrated
+// without its keys, if the spec allows it. Otherwise the secret of the user would silently lack its
+// keys. Users with named keys or suppressed keys get their keys from the spec instead.
+func (r *ReconcileObjectStoreUser) ensureUserKey(objectUser *object.ObjectUser) error {
+	if len(objectUser.Keys) > 0 || len(r.userSpec.Keys) > 0 || r.userSpec.SuppressUserKeys {
+		return nil
+	}
+	if !generatesKeys(r.userSpec) {
+		return &KeyNotProvidedError{User: r.userConfig.UserID}
+	}
+	if !r.userSpec.GenerateMissingKey {
+		return &NoKeysError{User: r.userConfig.UserID}
+	}
+
+	r.log.Infof("ceph object user %q has no keys, generating one", r.userConfig.UserID)
+	r.recordChange("accessKey", "created")
+	if r.dryRun {
+		return nil
+	}
+	user, _, err := object.CreateKey(r.objContext, r.userConfig.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate missing key of ceph object user %q", r.userConfig.UserID)
+	}
+	if len(user.Keys) == 0 {
+		return errors.Errorf("ceph object user %q still has no keys after generating one", r.userConfig.UserID)
+	}
+	objectUser.Keys = user.Keys
+	r.userConfig.AccessKey = &user.Keys[0].AccessKey
+	r.userConfig.SecretKey = &user.Keys[0].SecretKey
+	return nil
+}
+
+// generatesKeys returns whether the operator may generate the S3 keys of the user
+func generatesKeys(spec cephv1.ObjectStoreUserSpec) bool {
+	return spec.GenerateKey == nil || *spec.GenerateKey
+}
+
+// providedKey returns the first S3 key of the spec that is read from a secret
+func (r *ReconcileObjectStoreUser) providedKey() (namedKey, bool) {
+	for i, key := range r.userSpec.Keys {
+		if isSwiftKey(key) {
+			continue
+		}
+		if ref, ok := r.keyRefs[keyName(i, key)]; ok {
+			return ref, true
+		}
+	}
+	return namedKey{}, false
+}
+
+// recordUserCreated records the creation of the ceph user for the audit log
+func (r *ReconcileObjectStoreUser) recordUserCreated() {
+	r.recordChange("user", "created")
+	r.recordChange("displayName", *r.userConfig.DisplayName)
+	if *r.userConfig.Email != "" {
+		r.recordChange("email", *r.userConfig.Email)
+	}
+	r.recordChange("accessKey", auditRedacted)
+	r.recordChange("secretKey", auditRedacted)
+}
+
+// applyChange makes the given change to the ceph user, unless this reconcile is a dry run
+func (r *ReconcileObjectStoreUser) applyChange(change func() error) error {
+	if r.dryRun {
+		return nil
+	}
+	return change()
+}
+
+// updateCephUser modifies the existing ceph user if its settings diverge from the desired ones
+func (r *ReconcileObjectStoreUser) updateCephUser(objectUser *object.ObjectUser) error {
+	update := object.ObjectUser{UserID: r.userConfig.UserID}
+	changed := false
+
+	// The display name and email of an adopted user are left untouched unless the spec sets them
+	adoptName, adoptEmail := r.userSpec.Adopt && r.userSpec.DisplayName == "", r.userSpec.Adopt && r.userSpec.Email == ""
+	if !adoptName && (objectUser.DisplayName == nil || *objectUser.DisplayName != *r.userConfig.DisplayName) {
+		update.DisplayName = r.userConfig.DisplayName
+		r.recordChange("displayName", *update.DisplayName)
+		changed = true
+	}
+	if !adoptEmail && (objectUser.Email == nil || *objectUser.Email != *r.userConfig.Email) {
+		update.Email = r.userConfig.Email
+		r.recordChange("email", *update.Email)
+		changed = true
+	}
+	// An op mask that is not set in the spec is left untouched
+	if r.userConfig.OpMask != nil && (objectUser.OpMask == nil || normalizeOpMask(*objectUser.OpMask) != normalizeOpMask(*r.userConfig.OpMask)) {
+		update.OpMask = r.userConfig.OpMask
+		r.recordChange("opMask", *update.OpMask)
+		changed = true
+	}
+
+	// A default placement that is not set in the spec is left untouched
+	placement, storageClass, err := r.defaultPlacement(objectUser)
+	if err != nil {
+		return err
+	}
+	if placement != nil {
+		update.DefaultPlacement = placement
+		update.DefaultStorageClass = storageClass
+		r.recordChange("defaultPlacement", fmt.Sprintf("%s/%s", *placement, *storageClass))
+		changed = true
+	}
+
+	// Placement tags that are not set in the spec are left untouched
+	placementTags, err := r.desiredPlacementTags(objectUser)
+	if err != nil {
+		return err
+	}
+	if placementTags != nil {
+		update.PlacementTags = placementTags
+		r.recordChange("placementTags", strings.Join(placementTags, ","))
+		changed = true
+	}
+	r.placementTags = sortedTags(objectUser.PlacementTags)
+
+	// Quotas that are not set in the spec are left untouched, but a max buckets that was set and then
+	// cleared from the spec is restored to the RGW default rather than keeping the old limit
+	maxBuckets := r.userConfig.MaxBuckets
+	if maxBuckets == nil && r.maxBucketsSet {
+		defaultMaxBuckets := rgwDefaultMaxBuckets
+		maxBuckets = &defaultMaxBuckets
+	}
+	if maxBuckets != nil && (objectUser.MaxBuckets == nil || *objectUser.MaxBuckets != *maxBuckets) {
+		update.MaxBuckets = maxBuckets
+		r.recordChange("maxBuckets", strconv.Itoa(*update.MaxBuckets))
+		changed = true
+	}
+
+	if changed {
+		r.log.Infof("updating ceph object user %q", r.userConfig.UserID)
+		err := r.runStep("user", func() error {
+			err := r.applyChange(func() error {
+				_, rgwerr, err := object.UpdateUser(r.objContext, update)
+				if rgwerr == object.RGWErrorNotFound {
+					return &UserNotFoundError{User: r.userConfig.UserID}
+				}
+				return err
+			})
+			return errors.Wrapf(err, "failed to update ceph object user %q", r.userConfig.UserID)
+		})
+		if err != nil {
+			return err
+		}
+		if update.PlacementTags != nil && !r.dryRun {
+			r.placementTags = update.PlacementTags
+		}
+	}
+	if !r.dryRun {
+		r.maxBucketsSet = r.userConfig.MaxBuckets != nil
+	}
+
+	suspensionChanged := false
+	if r.managesSuspension() {
+		err = r.runStep("suspend", func() (err error) {
+			suspensionChanged, err = r.reconcileSuspension(objectUser)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	capsChanged := false
+	err = r.runStep("caps", func() (err error) {
+		capsChanged, err = r.reconcileCaps(objectUser)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	quotaChanged := false
+	if r.userConfig.UserQuota != nil && (objectUser.UserQuota == nil || !quotaMatches(*objectUser.UserQuota, *r.userConfig.UserQuota)) {
+		err := r.runStep("quota", func() error {
+			err := r.applyChange(func() error {
+				_, _, err := object.SetUserQuota(r.objContext, r.userConfig.UserID, *r.userConfig.UserQuota)
+				return err
+			})
+			if err != nil {
+				return errors.Wrapf(err, "failed to set quota of ceph object user %q", r.userConfig.UserID)
+			}
+			// RGW enforces the new quota against stats that may be outdated until they are synced
+			if r.userSpec.Quotas != nil && r.userSpec.Quotas.SyncStats {
+				err = r.applyChange(func() error {
+					return object.SyncUserStats(r.objContext, r.userConfig.UserID)
+				})
+				if err != nil {
+					return errors.Wrapf(err, "failed to sync stats of ceph object user %q after setting its quota", r.userConfig.UserID)
+				}
+				r.recordChange("stats", "synced")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		r.recordChange("userQuota", fmt.Sprintf("maxSize:%d,maxObjects:%d", r.userConfig.UserQuota.MaxSize, r.userConfig.UserQuota.MaxObjects))
+		changed = true
+		quotaChanged = true
+	}
+
+	// A quota without limits in the spec is only enabled or disabled, its limits are left to RGW
+	quotaEnabled := r.userConfig.UserQuota == nil && r.userSpec.Quotas != nil && r.userSpec.Quotas.Enabled != nil
+	if quotaEnabled && (objectUser.UserQuota == nil || objectUser.UserQuota.Enabled != *r.userSpec.Quotas.Enabled) {
+		enabled := *r.userSpec.Quotas.Enabled
+		err := r.runStep("quota", func() error {
+			err := r.applyChange(func() error {
+				_, _, err := object.EnableUserQuota(r.objContext, r.userConfig.UserID, enabled)
+				return err
+			})
+			return errors.Wrapf(err, "failed to enable or disable the quota of ceph object user %q", r.userConfig.UserID)
+		})
+		if err != nil {
+			return err
+		}
+		r.recordChange("userQuotaEnabled", strconv.FormatBool(enabled))
+		changed = true
+		quotaChanged = true
+	}
+
+	// The bucket scope quota applies to each bucket on its own, apart from the user scope quota
+	if r.userConfig.BucketQuota != nil && (objectUser.BucketQuota == nil || !quotaMatches(*objectUser.BucketQuota, *r.userConfig.BucketQuota)) {
+		err := r.runStep("bucketQuota", func() error {
+			err := r.applyChange(func() error {
+				_, _, err := object.SetBucketQuota(r.objContext, r.userConfig.UserID, *r.userConfig.BucketQuota)
+				return err
+			})
+			return errors.Wrapf(err, "failed to set bucket quota of ceph object user %q", r.userConfig.UserID)
+		})
+		if err != nil {
+			return err
+		}
+		r.recordChange("bucketQuota", fmt.Sprintf("maxSize:%d,maxObjects:%d", r.userConfig.BucketQuota.MaxSize, r.userConfig.BucketQuota.MaxObjects))
+		changed = true
+	}
+
+	// The enforcement follows the live enabled flags, unless the quotas were just set
+	r.quotaStatus = &cephv1.ObjectUserQuotaStatus{
+		UserEnforced:   objectUser.UserQuota != nil && objectUser.UserQuota.Enabled,
+		BucketEnforced: objectUser.BucketQuota != nil && objectUser.BucketQuota.Enabled,
+	}
+	if r.userConfig.UserQuota != nil {
+		r.quotaStatus.UserEnforced = r.userConfig.UserQuota.Enabled
+	}
+	if r.userConfig.BucketQuota != nil {
+		r.quotaStatus.BucketEnforced = r.userConfig.BucketQuota.Enabled
+	}
+	if quotaEnabled {
+		r.quotaStatus.UserEnforced = *r.userSpec.Quotas.Enabled
+	}
+
+	// The quota may have been lowered below the current usage, and a user reported over quota is
+	// checked until its usage is within the quota again. A user suspended on a breach is always checked.
+	if quotaChanged || r.overQuotaReported || r.managesBreachSuspension() {
+		quota := objectUser.UserQuota
+		if r.userConfig.UserQuota != nil {
+			quota = r.userConfig.UserQuota
+		}
+		if quota != nil {
+			enforcedQuota := *quota
+			enforcedQuota.Enabled = r.quotaStatus.UserEnforced
+			quota = &enforcedQuota
+		}
+		r.checkQuotaUsage(quota)
+	}
+	if r.managesBreachSuspension() {
+		suspended := objectUser.Suspended != nil && *objectUser.Suspended
+		if suspensionChanged {
+			suspended = isExpired(r.userSpec)
+		}
+		err = r.runStep("suspendOnBreach", func() error {
+			return r.reconcileBreachSuspension(suspended)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	subusersChanged := false
+	err = r.runStep("subusers", func() (err error) {
+		subusersChanged, err = r.reconcileSubusers(objectUser)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	swiftKeysChanged := false
+	err = r.runStep("swiftKeys", func() (err error) {
+		swiftKeysChanged, err = r.reconcileSwiftKeys(objectUser, true)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	subuserKeysChanged := false
+	err = r.runStep("subuserKeys", func() (err error) {
+		subuserKeysChanged, err = r.reconcileSubuserKeys(objectUser, true)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	tempURLKeysChanged := false
+	err = r.runStep("tempURLKeys", func() (err error) {
+		tempURLKeysChanged, err = r.reconcileTempURLKeys(objectUser, true)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	keysChanged := false
+	err = r.runStep("keys", func() error {
+		// The keys of an adopted user are only resolved, its live keys are not replaced
+		if r.userSpec.Adopt {
+			_, err := r.reconcileKeys(objectUser, false)
+			return err
+		}
+		keysRemoved, err := r.removeUserKeys(objectUser)
+		if err != nil {
+			return err
+		}
+		keysChanged, err = r.reconcileKeys(objectUser, true)
+		keysChanged = keysChanged || keysRemoved
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The keys of the subusers rotate after the keys of the user
+	subuserKeysRotated := false
+	err = r.runStep("subuserKeyRotation", func() (err error) {
+		subuserKeysRotated, err = r.rotateSubuserKeys(objectUser)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	bucketsChanged := false
+	err = r.runStep("bucketLinks", func() (err error) {
+		bucketsChanged, err = r.reconcileBucketLinks()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !changed && !suspensionChanged && !capsChanged && !subusersChanged && !swiftKeysChanged && !subuserKeysChanged && !tempURLKeysChanged && !keysChanged && !subuserKeysRotated && !bucketsChanged {
+		r.log.Debugf("ceph object user %q is up to date", r.userConfig.UserID)
+	}
+
+	return nil
+}
+
+// runStep runs a step of the update of the ceph user. A failed step is returned as a StepError
+// naming the step and the steps that were completed before it.
+func (r *ReconcileObjectStoreUser) runStep(step string, run func() error) error {
+	err := run()
+	if err != nil {
+		return &StepError{Step: step, Completed: append([]string{}, r.completedSteps...), Err: err}
+	}
+	r.completedSteps = append(r.completedSteps, step)
+	return nil
+}
+
+// componentStatus returns the outcome of the steps of the update of the ceph user run by this reconcile,
+// "Applied" for the completed steps and "Failed: <error>" for a failed step. The steps after a failed
+// step are not run, and are not reported.
+func (r *ReconcileObjectStoreUser) componentStatus(err error) map[string]string {
+	components := map[string]string{}
+	for _, step := range r.completedSteps {
+		components[step] = "Applied"
+	}
+	if stepErr, ok := errors.Cause(err).(*StepError); ok {
+		components[stepErr.Step] = fmt.Sprintf("Failed: %v", stepErr.Err)
+	}
+	if len(components) == 0 {
+		return nil
+	}
+	return components
+}
+
+// defaultPlacement returns the default placement target and storage class to set on the user, or nil if
+// the live ones already match the spec. The placement target must exist in the zone group of the store.
+func (r *ReconcileObjectStoreUser) defaultPlacement(objectUser *object.ObjectUser) (*string, *string, error) {
+	if r.userSpec.DefaultPlacement == "" && r.userSpec.DefaultStorageClass == "" {
+		return nil, nil, nil
+	}
+
+	livePlacement, liveStorageClass := "", ""
+	if objectUser.DefaultPlacement != nil {
+		livePlacement = *objectUser.DefaultPlacement
+	}
+	if objectUser.DefaultStorageClass != nil {
+		liveStorageClass = *objectUser.DefaultStorageClass
+	}
+
+	// RGW sets both at once, so keep the live value of the one that is not in the spec
+	placement, storageClass := r.userSpec.DefaultPlacement, r.userSpec.DefaultStorageClass
+	if placement == "" {
+		placement = livePlacement
+	}
+	if storageClass == "" {
+		storageClass = liveStorageClass
+	}
+	if placement == livePlacement && storageClass == liveStorageClass {
+		return nil, nil, nil
+	}
+
+	targets, err := object.GetPlacementTargets(r.objContext)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to get placement targets of object store %q", r.objContext.Name)
+	}
+	target := placement
+	if target == "" {
+		target = defaultPlacementTarget
+	}
+	storageClasses, ok := targets[target]
+	if !ok {
+		return nil, nil, errors.Errorf("placement target %q of ceph object user %q does not exist in the zone group of object store %q", target, r.userConfig.UserID, r.objContext.Name)
+	}
+	if storageClass != "" && !contains(storageClasses, storageClass) {
+		return nil, nil, errors.Errorf("storage class %q of ceph object user %q does not exist in placement target %q of object store %q, must be one of %v", storageClass, r.userConfig.UserID, target, r.objContext.Name, storageClasses)
+	}
+
+	return &placement, &storageClass, nil
+}
+
+// desiredPlacementTags returns the placement tags to set on the user, or nil if the live ones already
+// match the spec. Each tag must be the tag of a placement target in the zone group of the store.
+func (r *ReconcileObjectStoreUser) desiredPlacementTags(objectUser *object.ObjectUser) ([]string, error) {
+	if len(r.userSpec.PlacementTags) == 0 {
+		return nil, nil
+	}
+	tags := sortedTags(r.userSpec.PlacementTags)
+	if reflect.DeepEqual(tags, sortedTags(objectUser.PlacementTags)) {
+		return nil, nil
+	}
+
+	zoneGroupTags, err := object.GetPlacementTags(r.objContext)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get placement tags of object store %q", r.objContext.Name)
+	}
+	for _, tag := range tags {
+		if !zoneGroupTags[tag] {
+			return nil, errors.Errorf("placement tag %q of ceph object user %q is not the tag of a placement target in the zone group of object store %q", tag, r.userConfig.UserID, r.objContext.Name)
+		}
+	}
+	return tags, nil
+}
+
+// sortedTags returns the given placement tags sorted and without duplicates
+func sortedTags(tags []string) []string {
+	sorted := []string{}
+	for _, tag := range tags {
+		if !contains(sorted, tag) {
+			sorted = append(sorted, tag)
+		}
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func contains(list []string, item string) bool {
+	for _, i := range list {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// removeUserKeys removes the top-level keys of a user whose spec suppresses them. RGW always generates
+// a key when creating a user, so it is removed right after.
+func (r *ReconcileObjectStoreUser) removeUserKeys(objectUser *object.ObjectUser) (bool, error) {
+	if !r.userSpec.SuppressUserKeys {
+		return false, nil
+	}
+
+	r.userConfig.AccessKey = nil
+	r.userConfig.SecretKey = nil
+	for _, key := range objectUser.Keys {
+		err := r.applyChange(func() error {
+			_, _, err := object.RemoveKey(r.objContext, r.userConfig.UserID, key.AccessKey)
+			return err
+		})
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to remove key of ceph object user %q", r.userConfig.UserID)
+		}
+		r.recordChange("accessKey", "removed")
+	}
+
+	return len(objectUser.Keys) > 0, nil
+}
+
+// reconcileKeys makes the top-level keys of the user match the named keys of the spec. The keys are
+// tracked by name in the status. Live keys that are not tracked are adopted by the names without a key
+// before new keys are generated, the keys that remain untracked are removed. Without drift correction,
+// the tracked keys are only resolved.
+func (r *ReconcileObjectStoreUser) reconcileKeys(objectUser *object.ObjectUser, correctDrift bool) (bool, error) {
+	if len(r.userSpec.Keys) == 0 {
+		return false, nil
+	}
+
+	live := map[string]string{}
+	for _, key := range objectUser.Keys {
+		live[key.AccessKey] = key.SecretKey
+	}
+
+	// Only keep the tracked keys of the names in the spec that still exist. The Swift keys are the keys of
+	// subusers, which are reconciled with the subusers.
+	desired := map[string]bool{}
+	for i, key := range r.userSpec.Keys {
+		if !isSwiftKey(key) {
+			desired[keyName(i, key)] = true
+		}
+	}
+	tracked := map[string]string{}
+	used := map[string]bool{}
+	// A referenced key is tracked once RGW has its secret key, it is set otherwise
+	for name, key := range r.keyRefs {
+		if secretKey, ok := live[key.accessKey]; ok && secretKey == key.secretKey {
+			tracked[name] = key.accessKey
+		}
+		used[key.accessKey] = true
+	}
+	for _, key := range r.keyStatus {
+		if _, referenced := r.keyRefs[key.Name]; referenced {
+			continue
+		}
+		if _, ok := live[key.AccessKey]; ok && desired[key.Name] {
+			tracked[key.Name] = key.AccessKey
+			used[key.AccessKey] = true
+		}
+	}
+	untracked := []string{}
+	for _, key := range objectUser.Keys {
+		if !used[key.AccessKey] {
+			untracked = append(untracked, key.AccessKey)
+		}
+	}
+
+	changed := false
+	r.keys = nil
+	for i, key := range r.userSpec.Keys {
+		if isSwiftKey(key) {
+			continue
+		}
+		name := keyName(i, key)
+		accessKey, ok := tracked[name]
+		if ref, referenced := r.keyRefs[name]; referenced && !ok {
+			if !correctDrift {
+				continue
+			}
+			err := r.applyChange(func() error {
+				_, _, err := object.SetKey(r.objContext, r.userConfig.UserID, ref.accessKey, ref.secretKey)
+				return err
+			})
+			if err != nil {
+				return changed, errors.Wrapf(err, "failed to set key %q of ceph object user %q", name, r.userConfig.UserID)
+			}
+			r.recordChange("key", fmt.Sprintf("%s:set", name))
+			changed = true
+			accessKey, ok = ref.accessKey, true
+			live[accessKey] = ref.secretKey
+		}
+		if !ok && len(untracked) > 0 {
+			accessKey, untracked = untracked[0], untracked[1:]
+			ok = true
+		}
+		if !ok {
+			if !correctDrift {
+				continue
+			}
+			// There is no key to track until it is actually created
+			if r.dryRun {
+				r.recordChange("key", fmt.Sprintf("%s:created", name))
+				changed = true
+				continue
+			}
+			user, _, err := object.CreateKey(r.objContext, r.userConfig.UserID)
+			if err != nil {
+				return changed, errors.Wrapf(err, "failed to create key %q of ceph object user %q", name, r.userConfig.UserID)
+			}
+			for _, newKey := range user.Keys {
+				if _, exists := live[newKey.AccessKey]; !exists {
+					accessKey = newKey.AccessKey
+					live[newKey.AccessKey] = newKey.SecretKey
+				}
+			}
+			if accessKey == "" {
+				return changed, errors.Errorf("failed to find the created key %q of ceph object user %q", name, r.userConfig.UserID)
+			}
+			r.recordChange("key", fmt.Sprintf("%s:created", name))
+			changed = true
+		}
+		r.keys = append(r.keys, namedKey{name: name, accessKey: accessKey, secretKey: live[accessKey]})
+	}
+
+	// Removing every key the user had would lock it out if the keys that replace them are not in RGW,
+	// e.g. after a key was set from a secret that RGW did not take
+	if correctDrift && !r.dryRun && len(desired) > 0 && len(untracked) > 0 && len(untracked) == len(objectUser.Keys) {
+		err := r.verifyReplacementKeys()
+		if err != nil {
+			return changed, err
+		}
+	}
+
+	if correctDrift {
+		for _, accessKey := range untracked {
+			err := r.applyChange(func() error {
+				_, _, err := object.RemoveKey(r.objContext, r.userConfig.UserID, accessKey)
+				return err
+			})
+			if err != nil {
+				return changed, errors.Wrapf(err, "failed to remove key of ceph object user %q", r.userConfig.UserID)
+			}
+			r.recordChange("key", "removed")
+			changed = true
+		}
+	}
+
+	// The first key is the main key of the user, a user with only Swift keys has no S3 keys
+	if len(r.keys) > 0 {
+		r.userConfig.AccessKey = &r.keys[0].accessKey
+		r.userConfig.SecretKey = &r.keys[0].secretKey
+	} else if len(desired) == 0 {
+		r.userConfig.AccessKey = nil
+		r.userConfig.SecretKey = nil
+	}
+	return changed, nil
+}
+
+// verifyReplacementKeys checks that RGW holds one of the keys of the spec before the last of the other
+// keys of the user is removed
+func (r *ReconcileObjectStoreUser) verifyReplacementKeys() error {
+	objectUser, _, err := object.GetUser(object.NewReadOnlyContext(r.objContext), r.userConfig.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the keys of ceph object user %q", r.userConfig.UserID)
+	}
+	for _, key := range objectUser.Keys {
+		for _, replacement := range r.keys {
+			if key.AccessKey == replacement.accessKey {
+				return nil
+			}
+		}
+	}
+	return errors.Errorf("refusing to remove the last key of ceph object user %q, none of the keys of the spec is in RGW", r.userConfig.UserID)
+}
+
+// readKeyRefs reads the keys, the Swift keys and the temp URL keys of the spec that reference a secret
+// and verifies the keys against their expected hash
+func (r *ReconcileObjectStoreUser) readKeyRefs(u *cephv1.CephObjectStoreUser) error {
+	for _, key := range u.Spec.TempURLKeys {
+		if key.SecretName == "" {
+			continue
+		}
+		secret := &v1.Secret{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: key.SecretName, Namespace: u.Namespace}, secret)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get secret %q of temp url key %d", key.SecretName, key.ID)
+		}
+		tempURLKey := secretValue(secret, "TempURLKey")
+		if tempURLKey == "" {
+			return errors.Errorf("secret %q of temp url key %d must hold a TempURLKey", key.SecretName, key.ID)
+		}
+		if r.tempURLKeyRefs == nil {
+			r.tempURLKeyRefs = map[int]string{}
+		}
+		r.tempURLKeyRefs[key.ID] = tempURLKey
+	}
+
+	for _, subuser := range u.Spec.Subusers {
+		if subuser.SecretName == "" {
+			continue
+		}
+		secret := &v1.Secret{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: subuser.SecretName, Namespace: u.Namespace}, secret)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get secret %q of subuser %q", subuser.SecretName, subuser.Name)
+		}
+		if isS3Subuser(subuser) {
+			accessKey, secretKey := secretValue(secret, "AccessKey"), secretValue(secret, "SecretKey")
+			if accessKey == "" || secretKey == "" {
+				return errors.Errorf("secret %q of subuser %q must hold an AccessKey and a SecretKey", subuser.SecretName, subuser.Name)
+			}
+			if r.subuserKeyRefs == nil {
+				r.subuserKeyRefs = map[string]namedKey{}
+			}
+			id := fmt.Sprintf("%s:%s", r.userConfig.UserID, subuser.Name)
+			r.subuserKeyRefs[id] = namedKey{name: id, accessKey: accessKey, secretKey: secretKey}
+			continue
+		}
+		secretKey := secretValue(secret, "SecretKey")
+		if secretKey == "" {
+			return errors.Errorf("secret %q of subuser %q must hold a SecretKey", subuser.SecretName, subuser.Name)
+		}
+		if r.swiftKeyRefs == nil {
+			r.swiftKeyRefs = map[string]string{}
+		}
+		r.swiftKeyRefs[fmt.Sprintf("%s:%s", r.userConfig.UserID, subuser.Name)] = secretKey
+	}
+
+	for i, key := range u.Spec.Keys {
+		if key.SecretName == "" {
+			continue
+		}
+		name := keyName(i, key)
+
+		secret := &v1.Secret{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: key.SecretName, Namespace: u.Namespace}, secret)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get secret %q of key %q", key.SecretName, name)
+		}
+		// A Swift key is set as the key of its subuser
+		if isSwiftKey(key) {
+			secretKey := secretValue(secret, "SecretKey")
+			if secretKey == "" {
+				return errors.Errorf("secret %q of swift key %q must hold a SecretKey", key.SecretName, name)
+			}
+			if r.swiftKeyRefs == nil {
+				r.swiftKeyRefs = map[string]string{}
+			}
+			r.swiftKeyRefs[fmt.Sprintf("%s:%s", r.userConfig.UserID, name)] = secretKey
+			continue
+		}
+		accessKey, secretKey := secretValue(secret, "AccessKey"), secretValue(secret, "SecretKey")
+		if accessKey == "" || secretKey == "" {
+			return errors.Errorf("secret %q of key %q must hold an AccessKey and a SecretKey", key.SecretName, name)
+		}
+
+		if key.SHA256 != "" {
+			sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%s", accessKey, secretKey)))
+			if !strings.EqualFold(hex.EncodeToString(sum[:]), key.SHA256) {
+				return &KeyIntegrityError{Key: name, Secret: key.SecretName}
+			}
+		}
+
+		if r.keyRefs == nil {
+			r.keyRefs = map[string]namedKey{}
+		}
+		r.keyRefs[name] = namedKey{name: name, accessKey: accessKey, secretKey: secretKey}
+	}
+	return nil
+}
+
+// secretValue returns the value of the given key of the secret
+func secretValue(secret *v1.Secret, key string) string {
+	if value, ok := secret.Data[key]; ok {
+		return string(value)
+	}
+	return secret.StringData[key]
+}
+
+// isS3Subuser returns whether the subuser of the spec holds an S3 key rather than a Swift key
+func isS3Subuser(subuser cephv1.SubuserSpec) bool {
+	return subuser.KeyType == cephv1.UserKeyTypeS3
+}
+
+// isSwiftKey returns whether the key of the spec is a Swift key rather than an S3 key
+func isSwiftKey(key cephv1.UserKeySpec) bool {
+	return key.Type == cephv1.UserKeyTypeSwift
+}
+
+// swiftKeyNames returns the names of the Swift keys of the spec, which are the names of their subusers
+func swiftKeyNames(spec cephv1.ObjectStoreUserSpec) map[string]bool {
+	names := map[string]bool{}
+	for _, key := range spec.Keys {
+		if isSwiftKey(key) {
+			names[key.Name] = true
+		}
+	}
+	return names
+}
+
+// keyName returns the name of the key at the given index of the spec
+func keyName(index int, key cephv1.UserKeySpec) string {
+	if key.Name != "" {
+		return key.Name
+	}
+	return fmt.Sprintf("key-%d", index)
+}
+
+// reconcileCaps makes the admin capabilities of the user match the spec. RGW adds a permission to the
+// existing one and removes a permission from it, so only the missing permissions are added and the
+// surplus ones removed. Capabilities that do not change are never touched.
+func (r *ReconcileObjectStoreUser) reconcileCaps(objectUser *object.ObjectUser) (bool, error) {
+	if specCapabilities(r.userSpec) == nil {
+		return r.removeAllCaps(objectUser)
+	}
+
+	r.managedCaps = nil
+	changedCaps := []string{}
+	for _, capType := range orderedCapTypes(r.userConfig.Caps) {
+		perm, ok := r.userConfig.Caps[capType]
+		if !ok {
+			continue
+		}
+		r.managedCaps = append(r.managedCaps, capType)
+		if normalizeCapPerm(objectUser.Caps[capType]) != perm {
+			changedCaps = append(changedCaps, fmt.Sprintf("%s=%s", capType, perm))
+		}
+	}
+	added, removed := diffCaps(objectUser.Caps, r.userConfig.Caps)
+	if len(added) == 0 && len(removed) == 0 {
+		return false, nil
+	}
+
+	// The missing permissions are added before the surplus ones are removed, so that a capability whose
+	// permission changes, e.g. from read to write, is never missing in between
+	if len(added) > 0 {
+		err := r.applyChange(func() error {
+			_, _, err := object.AddCaps(r.objContext, r.userConfig.UserID, strings.Join(added, ";"))
+			return err
+		})
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to add caps of ceph object user %q", r.userConfig.UserID)
+		}
+	}
+	if len(removed) > 0 {
+		err := r.applyChange(func() error {
+			_, _, err := object.RemoveCaps(r.objContext, r.userConfig.UserID, strings.Join(removed, ";"))
+			return err
+		})
+		if err != nil {
+			return len(added) > 0, errors.Wrapf(err, "failed to remove caps of ceph object user %q", r.userConfig.UserID)
+		}
+	}
+	r.recordChange("caps", strings.Join(changedCaps, ";"))
+
+	// The caps can be left partially applied, e.g. if removing them failed after the addition
+	if !r.dryRun {
+		err := r.verifyCaps()
+		if err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// diffCaps returns the capabilities to add to and to remove from the existing ones to get the desired
+// ones, in the "<type>=<perm>" form of radosgw-admin and in the order of the capability types. Only the
+// missing and the surplus permissions of each capability are returned, and the existing capabilities of
+// types that are not desired are left alone.
+func diffCaps(existing, desired map[string]string) ([]string, []string) {
+	toAdd, toRemove := []string{}, []string{}
+	for _, capType := range orderedCapTypes(desired) {
+		perm, ok := desired[capType]
+		if !ok {
+			continue
+		}
+		add, remove := capPermDiff(existing[capType], perm)
+		if add != "" {
+			toAdd = append(toAdd, fmt.Sprintf("%s=%s", capType, add))
+		}
+		if remove != "" {
+			toRemove = append(toRemove, fmt.Sprintf("%s=%s", capType, remove))
+		}
+	}
+	return toAdd, toRemove
+}
+
+// removeAllCaps removes all the admin capabilities of a user whose capabilities block was cleared from
+// the spec. The capabilities of a user that never had the block are left untouched.
+func (r *ReconcileObjectStoreUser) removeAllCaps(objectUser *object.ObjectUser) (bool, error) {
+	if len(r.managedCaps) == 0 {
+		return false, nil
+	}
+	removed := []string{}
+	for capType, perm := range objectUser.Caps {
+		removed = append(removed, fmt.Sprintf("%s=%s", capType, perm))
+	}
+	sort.Strings(removed)
+
+	changed := false
+	if len(removed) > 0 {
+		err := r.applyChange(func() error {
+			_, _, err := object.RemoveCaps(r.objContext, r.userConfig.UserID, strings.Join(removed, ";"))
+			return err
+		})
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to remove caps of ceph object user %q", r.userConfig.UserID)
+		}
+		r.recordChange("caps", "removed")
+		changed = true
+	}
+	r.managedCaps = nil
+	return changed, nil
+}
+
+// verifyCaps checks that RGW reports the admin capabilities of the spec after they were written
+func (r *ReconcileObjectStoreUser) verifyCaps() error {
+	objectUser, _, err := object.GetUser(object.NewReadOnlyContext(r.objContext), r.userConfig.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the caps of ceph object user %q", r.userConfig.UserID)
+	}
+	for _, capType := range orderedCapTypes(r.userConfig.Caps) {
+		perm, ok := r.userConfig.Caps[capType]
+		if !ok {
+			continue
+		}
+		if livePerm := objectUser.Caps[capType]; normalizeCapPerm(livePerm) != perm {
+			return errors.Errorf("%s capability of ceph object user %q is %q after it was set to %q", capType, r.userConfig.UserID, livePerm, perm)
+		}
+	}
+	return nil
+}
+
+// specCaps returns the capabilities of the spec by RGW capability type. Raw capabilities replace the
+// capability fields, they are validated with the spec.
+func specCaps(caps *cephv1.ObjectUserCapSpec) map[string]string {
+	if caps.Raw != "" {
+		raw, _ := parseRawCaps(caps.Raw)
+		return raw
+	}
+	return map[string]string{
+		"users":    caps.User,
+		"buckets":  caps.Bucket,
+		"metadata": caps.Metadata,
+		"usage":    caps.Usage,
+		"zone":     caps.Zone,
+		"roles":    caps.Roles,
+		"info":     caps.Info,
+	}
+}
+
+// parseRawCaps parses raw capabilities in the "<type>=<perm>;<type>=<perm>" form of radosgw-admin. The
+// type is not checked against the types of RGW, so that new types can be set before the spec has a
+// field for them.
+func parseRawCaps(raw string) (map[string]string, error) {
+	caps := map[string]string{}
+	for _, entry := range strings.Split(raw, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		capType := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || !rawCapTypeRegexp.MatchString(capType) {
+			return nil, errors.Errorf("invalid raw capability %q, must be in the <type>=<perm> form", entry)
+		}
+		if _, ok := caps[capType]; ok {
+			return nil, errors.Errorf("duplicate raw capability %q", capType)
+		}
+		perm := strings.TrimSpace(parts[1])
+		if err := validateCapPerm(capType, perm); err != nil {
+			return nil, err
+		}
+		caps[capType] = perm
+	}
+	if len(caps) == 0 {
+		return nil, errors.Errorf("invalid raw capabilities %q, no capability is set", raw)
+	}
+	return caps, nil
+}
+
+// orderedCapTypes returns the types of the capabilities in the order of the spec, followed by the types
+// of raw capabilities the spec has no field for in alphabetical order
+func orderedCapTypes(caps map[string]string) []string {
+	ordered := []string{}
+	for _, capType := range capTypes {
+		if _, ok := caps[capType]; ok {
+			ordered = append(ordered, capType)
+		}
+	}
+	others := []string{}
+	for capType := range caps {
+		if !contains(capTypes, capType) {
+			others = append(others, capType)
+		}
+	}
+	sort.Strings(others)
+	return append(ordered, others...)
+}
+
+// specCapabilities returns the admin capabilities of the spec, those of its preset overridden by the
+// capabilities it sets. Nil if the spec neither sets a preset nor capabilities.
+func specCapabilities(spec cephv1.ObjectStoreUserSpec) *cephv1.ObjectUserCapSpec {
+	preset, ok := capPresets[spec.Preset]
+	if !ok {
+		return spec.Capabilities
+	}
+	if spec.Capabilities == nil {
+		return &preset
+	}
+	caps := preset
+	for _, override := range []struct {
+		perm  string
+		field *string
+	}{
+		{spec.Capabilities.User, &caps.User},
+		{spec.Capabilities.Bucket, &caps.Bucket},
+		{spec.Capabilities.Metadata, &caps.Metadata},
+		{spec.Capabilities.Usage, &caps.Usage},
+		{spec.Capabilities.Zone, &caps.Zone},
+		{spec.Capabilities.Roles, &caps.Roles},
+		{spec.Capabilities.Info, &caps.Info},
+	} {
+		if override.perm != "" {
+			*override.field = override.perm
+		}
+	}
+	return &caps
+}
+
+// normalizeCapPerm returns the capability permission as RGW reports it, i.e. "*" for "read, write"
+func normalizeCapPerm(perm string) string {
+	return capPermOf(capPermBits(perm))
+}
+
+// capPermDiff returns the permissions to add to and to remove from the live permission of a capability
+// to get the desired one, each one of read, write or * and empty if there is none
+func capPermDiff(live, desired string) (string, string) {
+	liveRead, liveWrite := capPermBits(live)
+	read, write := capPermBits(desired)
+	return capPermOf(read && !liveRead, write && !liveWrite), capPermOf(liveRead && !read, liveWrite && !write)
+}
+
+// capPermBits returns whether the capability permission grants read and write
+func capPermBits(perm string) (bool, bool) {
+	read, write := false, false
+	for _, p := range strings.Split(perm, ",") {
+		switch strings.TrimSpace(p) {
+		case "read":
+			read = true
+		case "write":
+			write = true
+		case "*":
+			read, write = true, true
+		}
+	}
+	return read, write
+}
+
+// capPermOf returns the capability permission granting the given read and write, empty for none
+func capPermOf(read, write bool) string {
+	switch {
+	case read && write:
+		return "*"
+	case read:
+		return "read"
+	case write:
+		return "write"
+	}
+	return ""
+}
+
+// validateCapPerm validates that the capability permission only contains permissions known to RGW
+func validateCapPerm(capType, perm string) error {
+	for _, p := range strings.Split(perm, ",") {
+		p = strings.TrimSpace(p)
+		if p != "read" && p != "write" && p != "*" {
+			return errors.Errorf("invalid %s capability %q, permission %q must be one of read, write or *", capType, perm, p)
+		}
+	}
+	return nil
+}
+
+// reconcileBucketLinks links the buckets of the spec to the user and unlinks the buckets that were
+// linked by a previous reconcile but are no longer in the spec. A bucket that does not exist or that
+// is still linked to another user is not linked, it is reported as a conflict instead.
+func (r *ReconcileObjectStoreUser) reconcileBucketLinks() (bool, error) {
+	changed := false
+	linked := []string{}
+	ownerBuckets := map[string][]string{}
+	for _, bucket := range r.userSpec.LinkBuckets {
+		objectBucket, rgwerr, err := object.GetBucket(r.objContext, bucket)
+		if err != nil {
+			if rgwerr == object.RGWErrorNotFound {
+				r.bucketConflicts = append(r.bucketConflicts, fmt.Sprintf("bucket %q does not exist", bucket))
+				continue
+			}
+			return false, errors.Wrapf(err, "failed to get bucket %q", bucket)
+		}
+		if objectBucket.Owner != r.userConfig.UserID {
+			// The owner stays set when a bucket is unlinked, only the buckets of the owner tell whether
+			// the bucket is still linked to it
+			if _, ok := ownerBuckets[objectBucket.Owner]; !ok {
+				buckets, err := object.ListUserBuckets(r.objContext, objectBucket.Owner)
+				if err != nil {
+					return false, err
+				}
+				ownerBuckets[objectBucket.Owner] = buckets
+			}
+			if contains(ownerBuckets[objectBucket.Owner], bucket) {
+				r.bucketConflicts = append(r.bucketConflicts, fmt.Sprintf("bucket %q is linked to user %q", bucket, objectBucket.Owner))
+				continue
+			}
+
+			r.log.Infof("linking bucket %q to ceph object user %q", bucket, r.userConfig.UserID)
+			err = r.applyChange(func() error {
+				_, _, err := object.LinkUser(r.objContext, r.userConfig.UserID, bucket)
+				return err
+			})
+			if err != nil {
+				return false, errors.Wrapf(err, "failed to link bucket %q to ceph object user %q", bucket, r.userConfig.UserID)
+			}
+			r.recordChange("linkBucket", bucket)
+			changed = true
+		}
+		linked = append(linked, bucket)
+	}
+
+	for _, bucket := range r.linkedBuckets {
+		if contains(r.userSpec.LinkBuckets, bucket) {
+			continue
+		}
+		r.log.Infof("unlinking bucket %q from ceph object user %q", bucket, r.userConfig.UserID)
+		err := r.applyChange(func() error {
+			_, _, err := object.UnlinkUser(r.objContext, r.userConfig.UserID, bucket)
+			return err
+		})
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to unlink bucket %q from ceph object user %q", bucket, r.userConfig.UserID)
+		}
+		r.recordChange("unlinkBucket", bucket)
+		changed = true
+	}
+
+	r.linkedBuckets = linked
+	return changed, nil
+}
+
+// reconcileSubusers creates the subusers of the spec that are missing, corrects the access of the
+// existing ones and removes the subusers that are not in the spec. Only actual differences are applied.
+func (r *ReconcileObjectStoreUser) reconcileSubusers(objectUser *object.ObjectUser) (bool, error) {
+	live := map[string]string{}
+	for _, subuser := range objectUser.Subusers {
+		live[subuser.ID] = subuser.Permissions
+	}
+
+	changed := false
+	desired := map[string]bool{}
+	for _, subuser := range r.userSpec.Subusers {
+		id := fmt.Sprintf("%s:%s", r.userConfig.UserID, subuser.Name)
+		desired[id] = true
+		access := rgwSubuserAccess[subuser.Access]
+		permissions, exists := live[id]
+		if !exists {
+			r.log.Infof("creating subuser %q with access %q", id, subuser.Access)
+			err := r.applyChange(func() error {
+				_, _, err := object.CreateSubuser(r.objContext, r.userConfig.UserID, id, access.token)
+				return err
+			})
+			if err != nil {
+				return changed, errors.Wrapf(err, "failed to create subuser %q", id)
+			}
+		} else if subuser.Access != "" && permissions != access.permission {
+			r.log.Infof("modifying the access of subuser %q from %q to %q", id, permissions, subuser.Access)
+			err := r.applyChange(func() error {
+				_, _, err := object.ModifySubuser(r.objContext, r.userConfig.UserID, id, access.token)
+				return err
+			})
+			if err != nil {
+				return changed, errors.Wrapf(err, "failed to modify subuser %q", id)
+			}
+		} else {
+			continue
+		}
+		r.recordChange("subuser", fmt.Sprintf("%s:%s", id, subuser.Access))
+		changed = true
+	}
+
+	// The subusers of an adopted user are left untouched unless the spec sets them
+	if r.userSpec.Adopt && len(r.userSpec.Subusers) == 0 {
+		return changed, nil
+	}
+	for _, subuser := range objectUser.Subusers {
+		if desired[subuser.ID] {
+			continue
+		}
+		r.log.Infof("removing subuser %q", subuser.ID)
+		err := r.applyChange(func() error {
+			_, _, err := object.RemoveSubuser(r.objContext, r.userConfig.UserID, subuser.ID)
+			return err
+		})
+		if err != nil {
+			return changed, errors.Wrapf(err, "failed to remove subuser %q", subuser.ID)
+		}
+		r.recordChange("subuser", fmt.Sprintf("%s:removed", subuser.ID))
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// reconcileSwiftKeys generates the Swift keys of the subusers of the spec that have none and sets the
+// Swift keys read from a secret. Without drift correction, the Swift keys are only resolved.
+func (r *ReconcileObjectStoreUser) reconcileSwiftKeys(objectUser *object.ObjectUser, correctDrift bool) (bool, error) {
+	live := map[string]string{}
+	for _, subuser := range objectUser.Subusers {
+		live[subuser.ID] = subuser.SwiftKey
+	}
+
+	changed := false
+	r.swiftKeys = nil
+	swiftKeys := swiftKeyNames(r.userSpec)
+	for _, subuser := range r.userSpec.Subusers {
+		if isS3Subuser(subuser) || (!subuser.GenerateKey && subuser.SecretName == "" && !swiftKeys[subuser.Name]) {
+			continue
+		}
+		id := fmt.Sprintf("%s:%s", r.userConfig.UserID, subuser.Name)
+		secretKey := live[id]
+		ref, referenced := r.swiftKeyRefs[id]
+		if referenced && secretKey != ref {
+			if !correctDrift {
+				continue
+			}
+			err := r.applyChange(func() error {
+				_, _, err := object.SetSwiftKey(r.objContext, r.userConfig.UserID, id, ref)
+				return err
+			})
+			if err != nil {
+				return changed, errors.Wrapf(err, "failed to set swift key of subuser %q", id)
+			}
+			secretKey = ref
+			r.recordChange("swiftKey", fmt.Sprintf("%s:set", id))
+			changed = true
+		} else if secretKey == "" {
+			if !correctDrift {
+				continue
+			}
+			// There is no key to track until it is actually created
+			if r.dryRun {
+				r.recordChange("swiftKey", fmt.Sprintf("%s:created", id))
+				changed = true
+				continue
+			}
+			user, _, err := object.CreateSwiftKey(r.objContext, r.userConfig.UserID, id)
+			if err != nil {
+				return changed, errors.Wrapf(err, "failed to create swift key of subuser %q", id)
+			}
+			for _, created := range user.Subusers {
+				if created.ID == id {
+					secretKey = created.SwiftKey
+				}
+			}
+			if secretKey == "" {
+				return changed, errors.Errorf("failed to find the created swift key of subuser %q", id)
+			}
+			r.recordChange("swiftKey", fmt.Sprintf("%s:created", id))
+			changed = true
+		}
+		r.swiftKeys = append(r.swiftKeys, namedKey{name: subuser.Name, accessKey: id, secretKey: secretKey})
+	}
+
+	return changed, nil
+}
+
+// reconcileTempURLKeys sets the temp URL keys of the spec that differ from their secret and generates
+// the ones that are not set, then removes the keys that were removed from the spec. Without drift
+// correction, the temp URL keys are only resolved.
+func (r *ReconcileObjectStoreUser) reconcileTempURLKeys(objectUser *object.ObjectUser, correctDrift bool) (bool, error) {
+	changed := false
+	setKey := func(keyID int, key, change string) error {
+		err := r.applyChange(func() error {
+			_, _, err := object.SetTempURLKey(r.objContext, r.userConfig.UserID, keyID, key)
+			return err
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to set temp url key %d of ceph object user %q", keyID, r.userConfig.UserID)
+		}
+		r.recordChange("tempURLKey", fmt.Sprintf("%d:%s", keyID, change))
+		changed = true
+		return nil
+	}
+
+	r.tempURLKeys = map[int]string{}
+	specIDs := []int{}
+	inSpec := map[int]bool{}
+	for _, key := range r.userSpec.TempURLKeys {
+		specIDs = append(specIDs, key.ID)
+		inSpec[key.ID] = true
+		value := objectUser.TempURLKeys[key.ID]
+		ref, referenced := r.tempURLKeyRefs[key.ID]
+		if referenced && value != ref {
+			if !correctDrift {
+				continue
+			}
+			if err := setKey(key.ID, ref, "set"); err != nil {
+				return changed, err
+			}
+			value = ref
+		} else if value == "" {
+			if !correctDrift {
+				continue
+			}
+			generated, err := generateTempURLKey()
+			if err != nil {
+				return changed, err
+			}
+			if err := setKey(key.ID, generated, "created"); err != nil {
+				return changed, err
+			}
+			value = generated
+		}
+		r.tempURLKeys[key.ID] = value
+	}
+	if !correctDrift {
+		return changed, nil
+	}
+
+	for _, keyID := range r.tempURLKeyIDs {
+		if inSpec[keyID] || objectUser.TempURLKeys[keyID] == "" {
+			continue
+		}
+		if err := setKey(keyID, "", "removed"); err != nil {
+			return changed, err
+		}
+	}
+	sort.Ints(specIDs)
+	r.tempURLKeyIDs = specIDs
+
+	return changed, nil
+}
+
+// generateTempURLKey returns a random temp URL key, as long as the secret keys RGW generates
+func generateTempURLKey() (string, error) {
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return "", errors.Wrapf(err, "failed to generate temp url key")
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// normalizeOpMask returns the op mask as RGW reports it, e.g. "read, write, delete" for "*"
+func normalizeOpMask(opMask string) string {
+	requested := map[string]bool{}
+	for _, op := range strings.Split(opMask, ",") {
+		op = strings.TrimSpace(op)
+		if op == "*" {
+			return strings.Join(opMaskOperations, ", ")
+		}
+		requested[op] = true
+	}
+
+	ops := []string{}
+	for _, op := range opMaskOperations {
+		if requested[op] {
+			ops = append(ops, op)
+		}
+	}
+	return strings.Join(ops, ", ")
+}
+
+// validateUID validates the uid of an RGW user, an optional tenant followed by "$" and the user name.
+// The colon separates the subusers of a user, and radosgw-admin does not take blanks or control
+// characters in a uid.
+func validateUID(uid string) error {
+	parts := strings.Split(uid, "$")
+	if len(parts) > 2 {
+		return errors.Errorf("invalid uid %q, must be <user> or <tenant>$<user>", uid)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return errors.Errorf("invalid uid %q, must be <user> or <tenant>$<user>", uid)
+		}
+	}
+	for _, c := range uid {
+		if c == ':' || c == '/' || !unicode.IsPrint(c) || unicode.IsSpace(c) {
+			return errors.Errorf("invalid uid %q, must not contain %q", uid, c)
+		}
+	}
+	return nil
+}
+
+// validateSubuserName validates the name of a subuser, which RGW appends to the uid of the user after
+// a colon. Like in a uid, radosgw-admin does not take blanks or control characters, and the colon, the
+// slash and the dollar of the tenant would make the subuser ID ambiguous.
+func validateSubuserName(name string) error {
+	if len(name) > maxSubuserNameLength {
+		return errors.Errorf("invalid subuser name %q, must be at most %d characters", name, maxSubuserNameLength)
+	}
+	for _, c := range name {
+		if c == ':' || c == '/' || c == '$' || !unicode.IsPrint(c) || unicode.IsSpace(c) {
+			return errors.Errorf("invalid subuser name %q, must not contain %q", name, c)
+		}
+	}
+	return nil
+}
+
+// validateOpMask validates that the op mask only contains operations known to RGW
+func validateOpMask(opMask string) error {
+	for _, op := range strings.Split(opMask, ",") {
+		op = strings.TrimSpace(op)
+		if op == "*" {
+			continue
+		}
+		valid := false
+		for _, known := range opMaskOperations {
+			if op == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return errors.Errorf("invalid op mask %q, operation %q must be one of read, write, delete or *", opMask, op)
+		}
+	}
+	return nil
+}
+
+// newObjectContext returns the context of the object store of the user, with the timeout and the
+// retries of the radosgw-admin commands
+func (r *ReconcileObjectStoreUser) newObjectContext(u *cephv1.CephObjectStoreUser) *object.Context {
+	objContext := object.NewContext(r.context, storeName(u), clusterNamespace(u))
+	objContext.Timeout = r.adminTimeout
+	objContext.ReadRetries = r.adminReadRetries
+	objContext.TraceArgs = r.traceAdminArgs
+	objContext.Stats = r.adminStats
+	return objContext
+}
+
+// updateStatus updates the status of the user with the counts of the radosgw-admin commands run by
+// this reconcile so far
+func (r *ReconcileObjectStoreUser) updateStatus(u *cephv1.CephObjectStoreUser) error {
+	r.recordAdminStats(u)
+	return opcontroller.UpdateStatus(r.client, u)
+}
+
+// recordAdminStats records the counts of the radosgw-admin commands run by this reconcile in the status
+// of the user, keeping the counts of the last reconciles only. A reconcile that did not run any command
+// is not recorded.
+func (r *ReconcileObjectStoreUser) recordAdminStats(u *cephv1.CephObjectStoreUser) {
+	if r.adminStats == nil || r.adminStats.Calls == 0 || u.Status == nil {
+		return
+	}
+	if u.Status.AdminCommandStats == nil {
+		u.Status.AdminCommandStats = &cephv1.ObjectUserAdminCommandStats{}
+	}
+	stats := u.Status.AdminCommandStats
+	counts := cephv1.ObjectUserAdminCommandCounts{Calls: r.adminStats.Calls, Failures: r.adminStats.Failures}
+	// The status may be updated several times by a reconcile, which is only recorded once
+	if r.adminStatsRecorded && len(stats.Reconciles) > 0 {
+		stats.Reconciles[len(stats.Reconciles)-1] = counts
+	} else {
+		stats.Reconciles = append(stats.Reconciles, counts)
+		if len(stats.Reconciles) > maxAdminStatsReconciles {
+			stats.Reconciles = stats.Reconciles[len(stats.Reconciles)-maxAdminStatsReconciles:]
+		}
+		r.adminStatsRecorded = true
+	}
+	if r.adminStats.LastError != "" {
+		stats.LastError = r.adminStats.LastError
+	}
+}
+
+func (r *ReconcileObjectStoreUser) isObjectStoreInitialized(u *cephv1.CephObjectStoreUser) (*object.Context, *cephv1.CephObjectStore, error) {
+	objContext := r.newObjectContext(u)
+	objectStore, err := r.objectStoreInitialized(u)
+	if err != nil {
+		return objContext, nil, errors.Wrap(err, "failed to detect if object store is initialized")
+	}
+
+	return objContext, objectStore, nil
+}
+
+// generateUserConfig returns the desired ceph user for the CR, with its quotas clamped to the
+// maximum quotas of the object store. The quotas that had to be clamped are returned as well. A CR
+// without a uid fails before radosgw-admin is run for an empty uid.
+func generateUserConfig(user *cephv1.CephObjectStoreUser, maxQuotas *cephv1.ObjectUserQuotaSpec) (object.ObjectUser, []string, error) {
+	if userID(user) == "" {
+		return object.ObjectUser{}, nil, errors.New("missing uid, the user has neither a name nor a uid")
+	}
+
+	// Set DisplayName to match Name if DisplayName is not set
+	displayName := user.Spec.DisplayName
+	if len(displayName) == 0 {
+		displayName = user.Name
+	}
+
+	// create the user
+	userConfig := object.ObjectUser{
+		UserID:      userID(user),
+		DisplayName: &displayName,
+	}
+
+	// An empty email clears the email of the user
+	email := user.Spec.Email
+	userConfig.Email = &email
+
+	// Leave the RGW default op mask untouched if not set
+	if user.Spec.OpMask != "" {
+		opMask := user.Spec.OpMask
+		userConfig.OpMask = &opMask
+	}
+
+	// Capabilities that are not set are left untouched
+	if caps := specCapabilities(user.Spec); caps != nil {
+		userConfig.Caps = map[string]string{}
+		for capType, perm := range specCaps(caps) {
+			if perm != "" {
+				userConfig.Caps[capType] = normalizeCapPerm(perm)
+			}
+		}
+	}
+
+	quotas, clamped := clampQuotas(user.Spec.Quotas, maxQuotas)
+	if quotas != nil {
+		if quotas.MaxBuckets != nil {
+			maxBuckets := rgwMaxBuckets(*quotas.MaxBuckets)
+			userConfig.MaxBuckets = &maxBuckets
+		}
+		if quotas.MaxSize != nil || quotas.MaxSizeKB != nil || quotas.MaxObjects != nil {
+			quota := object.ObjectUserQuota{Enabled: true, MaxSize: -1, MaxObjects: -1}
+			if quotas.Enabled != nil {
+				quota.Enabled = *quotas.Enabled
+			}
+			if quotas.MaxSize != nil {
+				quota.MaxSize = quotaBytes(*quotas.MaxSize)
+			}
+			if quotas.MaxSizeKB != nil {
+				quota.MaxSize = kbBytes(*quotas.MaxSizeKB)
+				quota.SizeInKB = true
+			}
+			if quotas.MaxObjects != nil {
+				quota.MaxObjects = *quotas.MaxObjects
+			}
+			userConfig.UserQuota = &quota
+		}
+		if quotas.DefaultBucketMaxSize != nil || quotas.DefaultBucketMaxObjects != nil {
+			quota := object.ObjectUserQuota{Enabled: true, MaxSize: -1, MaxObjects: -1}
+			if quotas.DefaultBucketMaxSize != nil {
+				quota.MaxSize = quotaBytes(*quotas.DefaultBucketMaxSize)
+			}
+			if quotas.DefaultBucketMaxObjects != nil {
+				quota.MaxObjects = *quotas.DefaultBucketMaxObjects
+			}
+			userConfig.BucketQuota = &quota
+		}
+	}
+
+	return userConfig, clamped, nil
+}
+
+// quotaBytes returns the size quota in bytes, the max-size RGW expects. The decimal suffixes are powers
+// of 10, e.g. "10G" is 10000000000 bytes, and the binary suffixes are powers of 2, e.g. "10Gi" is
+// 10737418240 bytes. A size that does not fit in the int64 of RGW is capped to the largest one when
+// it is parsed, such a size is rejected by the validation of the user.
+func quotaBytes(size resource.Quantity) int64 {
+	return size.Value()
+}
+
+// kbBytes returns the size quota in KB in bytes, a negative size is unlimited
+func kbBytes(sizeKB int64) int64 {
+	if sizeKB < 0 {
+		return -1
+	}
+	return sizeKB * 1024
+}
+
+// maxSizeBytes returns the size quota in bytes of the max size or the max size in KB, nil if neither
+// is set
+func maxSizeBytes(quotas *cephv1.ObjectUserQuotaSpec) *int64 {
+	var bytes int64
+	switch {
+	case quotas.MaxSizeKB != nil:
+		bytes = kbBytes(*quotas.MaxSizeKB)
+	case quotas.MaxSize != nil:
+		bytes = quotaBytes(*quotas.MaxSize)
+	default:
+		return nil
+	}
+	return &bytes
+}
+
+// quotaMatches returns whether the live quota enforces the desired quota. RGW reports the size in
+// bytes, whether it was set in KB or not.
+func quotaMatches(live, desired object.ObjectUserQuota) bool {
+	return live.Enabled == desired.Enabled && live.MaxSize == desired.MaxSize && live.MaxObjects == desired.MaxObjects
+}
+
+// validateMaxSizeKB validates that the size quota in KB is -1 for unlimited or a number of KB whose
+// bytes fit in the int64 of RGW
+func validateMaxSizeKB(sizeKB int64) error {
+	if sizeKB < -1 {
+		return errors.Errorf("invalid max size %d KB, must be -1 for unlimited or a positive size", sizeKB)
+	}
+	if sizeKB > math.MaxInt64/1024 {
+		return errors.Errorf("invalid max size %d KB, must be at most %d KB", sizeKB, int64(math.MaxInt64/1024))
+	}
+	return nil
+}
+
+// validateMaxSize validates that the size quota is -1 for unlimited or a whole number of bytes that
+// fits in the int64 of RGW
+func validateMaxSize(size resource.Quantity) error {
+	bytes := quotaBytes(size)
+	if bytes == math.MaxInt64 {
+		return errors.Errorf("invalid max size %s, must be less than %d bytes", size.String(), int64(math.MaxInt64))
+	}
+	// The bytes are rounded up, e.g. for "100m"
+	if size.Cmp(*resource.NewQuantity(bytes, size.Format)) != 0 {
+		return errors.Errorf("invalid max size %s, must be a whole number of bytes", size.String())
+	}
+	if bytes < -1 {
+		return errors.Errorf("invalid max size %s, must be -1 for unlimited or a positive size", size.String())
+	}
+	return nil
+}
+
+// validateMaxObjects validates that the objects quota is -1 for unlimited or a number of objects. A
+// number that does not fit in the int64 of RGW is rejected when the CR is decoded.
+func validateMaxObjects(maxObjects int64) error {
+	if maxObjects < -1 {
+		return errors.Errorf("invalid max objects %d, must be -1 for unlimited or a positive limit", maxObjects)
+	}
+	return nil
+}
+
+// rgwMaxBuckets returns the max buckets RGW expects for the max buckets of the spec. RGW takes 0 as
+// unlimited and a negative value as disabled, while the spec takes -1 as unlimited and 0 as disabled.
+func rgwMaxBuckets(maxBuckets int) int {
+	switch maxBuckets {
+	case -1:
+		return 0
+	case 0:
+		return -1
+	}
+	return maxBuckets
+}
+
+// specMaxBuckets returns the max buckets of the spec for the max buckets RGW reports, the reverse of
+// rgwMaxBuckets. Any negative value disables the buckets in RGW.
+func specMaxBuckets(maxBuckets int) int {
+	switch {
+	case maxBuckets == 0:
+		return -1
+	case maxBuckets < 0:
+		return 0
+	}
+	return maxBuckets
+}
+
+// withStoreDefaults returns the spec of a user with the default quotas and capabilities of its object
+// store for the ones the spec does not set. The quotas and capabilities of the spec, including the
+// capabilities of its preset, take precedence over the defaults.
+func withStoreDefaults(spec cephv1.ObjectStoreUserSpec, storeSpec cephv1.ObjectStoreSpec) cephv1.ObjectStoreUserSpec {
+	spec = *spec.DeepCopy()
+	if defaults := storeSpec.DefaultUserQuotas; defaults != nil {
+		quotas := &cephv1.ObjectUserQuotaSpec{}
+		if spec.Quotas != nil {
+			quotas = spec.Quotas
+		}
+		if quotas.MaxBuckets == nil && defaults.MaxBuckets != nil {
+			maxBuckets := *defaults.MaxBuckets
+			quotas.MaxBuckets = &maxBuckets
+		}
+		// Only one of the sizes may be set
+		if quotas.MaxSize == nil && quotas.MaxSizeKB == nil && quotas.MaxSizePercent == nil {
+			if defaults.MaxSize != nil {
+				maxSize := defaults.MaxSize.DeepCopy()
+				quotas.MaxSize = &maxSize
+			}
+			if defaults.MaxSizeKB != nil {
+				maxSizeKB := *defaults.MaxSizeKB
+				quotas.MaxSizeKB = &maxSizeKB
+			}
+		}
+		if quotas.MaxObjects == nil && defaults.MaxObjects != nil {
+			maxObjects := *defaults.MaxObjects
+			quotas.MaxObjects = &maxObjects
+		}
+		if quotas.Enabled == nil && defaults.Enabled != nil {
+			enabled := *defaults.Enabled
+			quotas.Enabled = &enabled
+		}
+		spec.Quotas = quotas
+	}
+	// The default capabilities are not merged into raw capabilities, which are set as they are
+	if defaults := storeSpec.DefaultUserCapabilities; defaults != nil && (spec.Capabilities == nil || spec.Capabilities.Raw == "") {
+		caps := &cephv1.ObjectUserCapSpec{}
+		if specCaps := specCapabilities(spec); specCaps != nil {
+			caps = specCaps.DeepCopy()
+		}
+		for _, capDefault := range []struct {
+			perm  string
+			field *string
+		}{
+			{defaults.User, &caps.User},
+			{defaults.Bucket, &caps.Bucket},
+			{defaults.Metadata, &caps.Metadata},
+			{defaults.Usage, &caps.Usage},
+			{defaults.Zone, &caps.Zone},
+			{defaults.Roles, &caps.Roles},
+			{defaults.Info, &caps.Info},
+		} {
+			if *capDefault.field == "" {
+				*capDefault.field = capDefault.perm
+			}
+		}
+		// The capabilities of the preset are merged already
+		spec.Capabilities = caps
+		spec.Preset = ""
+	}
+	return spec
+}
+
+// clampQuotas limits the requested quotas to the maximum quotas of the object store. A quota the
+// user does not request is set to the maximum, so that no user of the store can exceed it.
+func clampQuotas(quotas, maxQuotas *cephv1.ObjectUserQuotaSpec) (*cephv1.ObjectUserQuotaSpec, []string) {
+	if maxQuotas == nil {
+		return quotas, nil
+	}
+
+	clamped := []string{}
+	result := &cephv1.ObjectUserQuotaSpec{}
+	if quotas != nil {
+		result = quotas.DeepCopy()
+	}
+
+	// An unlimited maximum does not limit the buckets and an unlimited user is limited to the maximum
+	if maxQuotas.MaxBuckets != nil && *maxQuotas.MaxBuckets >= 0 && (result.MaxBuckets == nil || *result.MaxBuckets < 0 || *result.MaxBuckets > *maxQuotas.MaxBuckets) {
+		if result.MaxBuckets != nil {
+			clamped = append(clamped, fmt.Sprintf("maxBuckets from %d to %d", *result.MaxBuckets, *maxQuotas.MaxBuckets))
+		}
+		maxBuckets := *maxQuotas.MaxBuckets
+		result.MaxBuckets = &maxBuckets
+	}
+	// A negative size or object limit means unlimited
+	// The sizes are compared in bytes, either of them may be set in KB
+	if maxSize, size := maxSizeBytes(maxQuotas), maxSizeBytes(result); maxSize != nil && *maxSize >= 0 && (size == nil || *size < 0 || *size > *maxSize) {
+		switch {
+		case result.MaxSizeKB != nil:
+			clamped = append(clamped, fmt.Sprintf("maxSizeKB from %d to %s", *result.MaxSizeKB, sizeString(maxQuotas)))
+		case result.MaxSize != nil:
+			clamped = append(clamped, fmt.Sprintf("maxSize from %s to %s", result.MaxSize.String(), sizeString(maxQuotas)))
+		}
+		result.MaxSize = nil
+		result.MaxSizeKB = nil
+		if maxQuotas.MaxSizeKB != nil {
+			maxSizeKB := *maxQuotas.MaxSizeKB
+			result.MaxSizeKB = &maxSizeKB
+		} else {
+			maxSize := maxQuotas.MaxSize.DeepCopy()
+			result.MaxSize = &maxSize
+		}
+	}
+	if maxQuotas.MaxObjects != nil && *maxQuotas.MaxObjects >= 0 && (result.MaxObjects == nil || *result.MaxObjects < 0 || *result.MaxObjects > *maxQuotas.MaxObjects) {
+		if result.MaxObjects != nil {
+			clamped = append(clamped, fmt.Sprintf("maxObjects from %d to %d", *result.MaxObjects, *maxQuotas.MaxObjects))
+		}
+		maxObjects := *maxQuotas.MaxObjects
+		result.MaxObjects = &maxObjects
+	}
+	// The maximum quotas of the store are only enforced if the quota of the user is
+	if (maxSizeBytes(maxQuotas) != nil && *maxSizeBytes(maxQuotas) >= 0 || maxQuotas.MaxObjects != nil && *maxQuotas.MaxObjects >= 0) && result.Enabled != nil && !*result.Enabled {
+		clamped = append(clamped, "enabled from false to true")
+		enabled := true
+		result.Enabled = &enabled
+	}
+
+	return result, clamped
+}
+
+// sizeString returns the size quota as set in the spec, e.g. "10G" or "1024KB"
+func sizeString(quotas *cephv1.ObjectUserQuotaSpec) string {
+	if quotas.MaxSizeKB != nil {
+		return fmt.Sprintf("%dKB", *quotas.MaxSizeKB)
+	}
+	return quotas.MaxSize.String()
+}
+
+// userSecretKeys are the keys of the user stored in its secret
+type userSecretKeys struct {
+	// accessKey and secretKey are the top-level keys of the user, nil for a user with suppressed keys
+	accessKey *string
+	secretKey *string
+	// keys are the named keys of the spec, in the order of the spec
+	keys []namedKey
+	// swiftKeys are the Swift keys of the subusers, in the order of the spec
+	swiftKeys []namedKey
+	// subuserKeys are the S3 keys of the subusers, in the order of the spec
+	subuserKeys []namedKey
+	// tempURLKeys are the temp URL keys, by ID
+	tempURLKeys map[int]string
+}
+
+// secretKeys returns the keys of the user this reconcile resolved
+func (r *ReconcileObjectStoreUser) secretKeys() userSecretKeys {
+	return userSecretKeys{
+		accessKey:   r.userConfig.AccessKey,
+		secretKey:   r.userConfig.SecretKey,
+		keys:        r.keys,
+		swiftKeys:   r.swiftKeys,
+		subuserKeys: r.subuserKeys,
+		tempURLKeys: r.tempURLKeys,
+	}
+}
+
+// secretKeyNamesOf returns the names of the keys in the secret of the user for the format of the spec
+func secretKeyNamesOf(spec cephv1.ObjectStoreUserSpec) secretKeyNames {
+	if secretFormat(spec) == secretFormatStandard {
+		return standardSecretKeyNames
+	}
+	return rookSecretKeyNames
+}
+
+// buildUserSecret returns the secret with the keys of the user, owned by the user so that it is garbage
+// collected with it. The keys and the endpoint of the object store are the contract with the apps
+// consuming the secret.
+func buildUserSecret(u *cephv1.CephObjectStoreUser, keys userSecretKeys, store *cephv1.CephObjectStore) (*v1.Secret, error) {
+	format := secretFormat(u.Spec)
+	names := secretKeyNamesOf(u.Spec)
+
+	// Store the keys in a secret, a user with suppressed keys has none
+	secrets := map[string]string{}
+	if keys.accessKey != nil && keys.secretKey != nil {
+		secrets[names.accessKey] = *keys.accessKey
+		secrets[names.secretKey] = *keys.secretKey
+		// The names the AWS SDKs and object bucket claims use, e.g. to consume the secret as env vars
+		if u.Spec.AWSKeyNames {
+			secrets[awsAccessKeyIDKey] = *keys.accessKey
+			secrets[awsSecretAccessKeyKey] = *keys.secretKey
+		}
+
+		// The named keys are indexed in the order of the spec
+		for i, key := range keys.keys {
+			secrets[fmt.Sprintf("%s_%d", names.keyName, i)] = key.name
+			secrets[fmt.Sprintf("%s_%d", names.accessKey, i)] = key.accessKey
+			secrets[fmt.Sprintf("%s_%d", names.secretKey, i)] = key.secretKey
+		}
+
+		if format == secretFormatMC {
+			config, err := generateMCConfig(storeName(u), objectStoreEndpoint(store), *keys.accessKey, *keys.secretKey)
+			if err != nil {
+				return nil, err
+			}
+			secrets[mcConfigKey] = config
+		}
+	}
+	if format == secretFormatStandard {
+		secrets[endpointKey] = objectStoreEndpoint(store)
+	}
+
+	// The Swift keys of the subusers are indexed in the order of the spec, also without user keys
+	for i, key := range keys.swiftKeys {
+		secrets[fmt.Sprintf("%s_%d", names.swiftUser, i)] = key.accessKey
+		secrets[fmt.Sprintf("%s_%d", names.swiftKey, i)] = key.secretKey
+	}
+	// The S3 keys of the subusers are indexed in the order of the spec as well
+	for i, key := range keys.subuserKeys {
+		secrets[fmt.Sprintf("%s_%d", names.subuser, i)] = key.name
+		secrets[fmt.Sprintf("%s_%d", names.subuserAccessKey, i)] = key.accessKey
+		secrets[fmt.Sprintf("%s_%d", names.subuserSecretKey, i)] = key.secretKey
+	}
+	// The temp URL keys are indexed by their ID
+	for keyID, key := range keys.tempURLKeys {
+		secrets[fmt.Sprintf("%s_%d", names.tempURLKey, keyID)] = key
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      userSecretName(u),
+			Namespace: u.Namespace,
+			Labels: map[string]string{
+				"app":               appName,
+				"user":              u.Name,
+				"rook_cluster":      clusterNamespace(u),
+				"rook_object_store": storeName(u),
+				managedByLabel:      managedByValue,
+			},
+			Annotations: map[string]string{
+				secretHashAnnotation:   secretDataHash(secrets),
+				secretSchemaAnnotation: fmt.Sprintf("%s/%s", format, secretSchemaVersion),
+				userIDAnnotation:       userID(u),
+			},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(u, cephv1.SchemeGroupVersion.WithKind("CephObjectStoreUser"))},
+		},
+		StringData: secrets,
+		Type:       secretType(u.Spec),
+	}
+	for key, value := range u.Spec.Tags {
+		secret.Annotations[tagAnnotationPrefix+key] = value
+	}
+	// The labels and annotations of the spec never replace those of the operator
+	for key, value := range u.Spec.SecretLabels {
+		if _, ok := secret.Labels[key]; !ok {
+			secret.Labels[key] = value
+		}
+	}
+	for key, value := range u.Spec.SecretAnnotations {
+		if _, ok := secret.Annotations[key]; !ok {
+			secret.Annotations[key] = value
+		}
+	}
+
+	return secret, nil
+}
+
+// ListManagedSecrets lists the secrets holding the credentials of object store users in the given
+// namespace, in all namespaces if empty. The copies of a secret in its secret namespaces are listed too.
+func ListManagedSecrets(c client.Client, namespace string) (*v1.SecretList, error) {
+	secrets := &v1.SecretList{}
+	listOpts := []client.ListOption{client.MatchingLabels{managedByLabel: managedByValue}}
+	if namespace != "" {
+		listOpts = append(listOpts, client.InNamespace(namespace))
+	}
+	err := c.List(context.TODO(), secrets, listOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the secrets of the object store users")
+	}
+	return secrets, nil
+}
+
+// secretType returns the type of the secret of the user
+func secretType(spec cephv1.ObjectStoreUserSpec) v1.SecretType {
+	if spec.SecretType == "" {
+		return k8sutil.RookType
+	}
+	return spec.SecretType
+}
+
+// validateSecretType validates the type of the secret of the user. The built-in types other than Opaque
+// require keys the secret of a user does not have, so the API server would reject the secret.
+func validateSecretType(secretType v1.SecretType) error {
+	switch secretType {
+	case v1.SecretTypeServiceAccountToken, v1.SecretTypeDockercfg, v1.SecretTypeDockerConfigJson, v1.SecretTypeBasicAuth, v1.SecretTypeSSHAuth, v1.SecretTypeTLS, v1.SecretTypeBootstrapToken:
+		return errors.Errorf("invalid secret type %q, the built-in secret types other than %q are not supported", secretType, v1.SecretTypeOpaque)
+	}
+	if errs := validation.IsQualifiedName(string(secretType)); len(errs) > 0 {
+		return errors.Errorf("invalid secret type %q, %s", secretType, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// refreshBuckets reports the buckets owned by the user in its status. Failing to list them does not
+// fail the reconcile, the buckets of the previous reconcile are kept instead.
+func (r *ReconcileObjectStoreUser) refreshBuckets(u *cephv1.CephObjectStoreUser) {
+	buckets, err := object.ListUserBuckets(object.NewReadOnlyContext(r.objContext), r.userConfig.UserID)
+	if err != nil {
+		r.log.Warningf("failed to refresh the buckets of ceph object user %q. %v", r.userConfig.UserID, err)
+		return
+	}
+
+	sort.Strings(buckets)
+	u.Status.BucketCount = len(buckets)
+	if len(buckets) > maxStatusBuckets {
+		buckets = buckets[:maxStatusBuckets]
+	}
+	u.Status.Buckets = buckets
+}
+
+// refreshEffectiveConfig reports the quotas and admin capabilities RGW holds for the user in its status,
+// also those left at the RGW defaults because the spec does not set them. Like the buckets they are
+// only reported, a failure to read them does not fail the reconcile.
+func (r *ReconcileObjectStoreUser) refreshEffectiveConfig(u *cephv1.CephObjectStoreUser) {
+	objectUser, _, err := object.GetUser(object.NewReadOnlyContext(r.objContext), r.userConfig.UserID)
+	if err != nil {
+		r.log.Warningf("failed to refresh the effective quotas and capabilities of ceph object user %q. %v", r.userConfig.UserID, err)
+		return
+	}
+
+	quota := &cephv1.ObjectUserEffectiveQuotaStatus{}
+	if objectUser.MaxBuckets != nil {
+		quota.MaxBuckets = specMaxBuckets(*objectUser.MaxBuckets)
+	}
+	if objectUser.UserQuota != nil {
+		quota.User = cephv1.ObjectUserQuotaLimits{Enabled: objectUser.UserQuota.Enabled, MaxSize: objectUser.UserQuota.MaxSize, MaxObjects: objectUser.UserQuota.MaxObjects}
+	}
+	if objectUser.BucketQuota != nil {
+		quota.Bucket = cephv1.ObjectUserQuotaLimits{Enabled: objectUser.BucketQuota.Enabled, MaxSize: objectUser.BucketQuota.MaxSize, MaxObjects: objectUser.BucketQuota.MaxObjects}
+	}
+	u.Status.EffectiveQuota = quota
+	u.Status.EffectiveCapabilities = nil
+	if len(objectUser.Caps) > 0 {
+		u.Status.EffectiveCapabilities = objectUser.Caps
+	}
+	r.refreshCreationInfo(u, objectUser)
+}
+
+// userSecretName returns the name of the secret holding the keys of the user
+func userSecretName(u *cephv1.CephObjectStoreUser) string {
+	if u.Status != nil && u.Status.SecretName != "" {
+		return u.Status.SecretName
+	}
+	return fmt.Sprintf("rook-ceph-object-user-%s-%s", storeName(u), u.Name)
+}
+
+// collisionSafeSecretName returns the name of the secret of a user whose secret name is taken, e.g. by
+// user "b-c" of store "a" and user "c" of store "a-b". The name is suffixed with a hash of the store and
+// the user, so the names of distinct users never collide. The name before the hash is cut to keep it a
+// valid secret name.
+func collisionSafeSecretName(u *cephv1.CephObjectStoreUser) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s", storeName(u), u.Name)))
+	hash := hex.EncodeToString(sum[:4])
+	prefix := fmt.Sprintf("rook-ceph-object-user-%s-%s", storeName(u), u.Name)
+	if maxLength := validation.DNS1123SubdomainMaxLength - len(hash) - 1; len(prefix) > maxLength {
+		// The dash of the hash cannot follow a dot, which would leave an empty label
+		prefix = strings.TrimRight(prefix[:maxLength], ".")
+	}
+	return fmt.Sprintf("%s-%s", prefix, hash)
+}
+
+// resolveSecretName sets the name of the secret of the user in its status. The name is kept once it
+// is set, a name that is taken by the secret of another user is replaced by a collision safe name.
+func (r *ReconcileObjectStoreUser) resolveSecretName(u *cephv1.CephObjectStoreUser) error {
+	if u.Status.SecretName != "" {
+		return nil
+	}
+	name := userSecretName(u)
+	existing := &v1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: u.Namespace}, existing)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to get secret %q", name)
+	}
+	if err == nil && (existing.Labels["user"] != u.Name || existing.Labels["rook_object_store"] != storeName(u)) {
+		safeName := collisionSafeSecretName(u)
+		message := fmt.Sprintf("secret %q belongs to user %q of store %q, using secret %q instead", name, existing.Labels["user"], existing.Labels["rook_object_store"], safeName)
+		r.log.Warningf("ceph object user %q %s", u.Name, message)
+		r.recorder.Event(u, v1.EventTypeWarning, "SecretNameCollision", message)
+		setCondition(u, cephv1.ConditionSecretNameCollision, v1.ConditionTrue, "SecretNameTaken", message)
+		name = safeName
+	}
+	u.Status.SecretName = name
+	return nil
+}
+
+// mcConfig is the config file of the MinIO client
+type mcConfig struct {
+	Version string                   `json:"version"`
+	Aliases map[string]mcAliasConfig `json:"aliases"`
+}
 
-	logger.Infof("created ceph object user %q", u.Name)
-	return nil
+type mcAliasConfig struct {
+	URL       string `json:"url"`
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+	API       string `json:"api"`
+	Path      string `json:"path"`
 }
 
-func (r *ReconcileObjectStoreUser) isObjectStoreInitialized(u *cephv1.CephObjectStoreUser) (*object.Context, error) {
-	objContext := object.NewContext(r.context, u.Spec.Store, u.Namespace)
-	err := r.objectStoreInitialized(u)
+// generateMCConfig returns a MinIO client config with an alias named after the object store
+func generateMCConfig(alias, endpoint, accessKey, secretKey string) (string, error) {
+	config := mcConfig{
+		Version: "10",
+		Aliases: map[string]mcAliasConfig{
+			alias: {URL: endpoint, AccessKey: accessKey, SecretKey: secretKey, API: "s3v4", Path: "auto"},
+		},
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
-		return objContext, errors.Wrap(err, "failed to detect if object store is initialized")
+		return "", errors.Wrap(err, "failed to generate mc config")
 	}
-
-	return objContext, nil
+	return string(data), nil
 }
 
-func generateUserConfig(user *cephv1.CephObjectStoreUser) object.ObjectUser {
-	// Set DisplayName to match Name if DisplayName is not set
-	displayName := user.Spec.DisplayName
-	if len(displayName) == 0 {
-		displayName = user.Name
+// objectStoreEndpoint returns the S3 endpoint of the object store, the in-cluster one preferring the non
+// secure port unless the store is external
+func objectStoreEndpoint(store *cephv1.CephObjectStore) string {
+	if store.Spec.Gateway.ExternalEndpoint != "" {
+		return store.Spec.Gateway.ExternalEndpoint
 	}
-
-	// create the user
-	userConfig := object.ObjectUser{
-		UserID:      user.Name,
-		DisplayName: &displayName,
+	service := fmt.Sprintf("%s-%s.%s", appName, store.Name, store.Namespace)
+	if store.Spec.Gateway.Port == 0 && store.Spec.Gateway.SecurePort != 0 {
+		return fmt.Sprintf("https://%s:%d", service, store.Spec.Gateway.SecurePort)
 	}
-
-	return userConfig
+	return fmt.Sprintf("http://%s:%d", service, store.Spec.Gateway.Port)
 }
 
-func (r *ReconcileObjectStoreUser) generateCephUserSecret(u *cephv1.CephObjectStoreUser) *v1.Secret {
-	// Store the keys in a secret
-	secrets := map[string]string{
-		"AccessKey": *r.userConfig.AccessKey,
-		"SecretKey": *r.userConfig.SecretKey,
+func (r *ReconcileObjectStoreUser) reconcileCephUserSecret(cephObjectStoreUser *cephv1.CephObjectStoreUser) (reconcile.Result, error) {
+	err := r.resolveSecretName(cephObjectStoreUser)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to resolve the secret name of ceph object user %q", cephObjectStoreUser.Name)
 	}
 
-	secretName := fmt.Sprintf("rook-ceph-object-user-%s-%s", u.Spec.Store, u.Name)
-	secret := &v1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretName,
-			Namespace: u.Namespace,
-			Labels: map[string]string{
-				"app":               appName,
-				"user":              u.Name,
-				"rook_cluster":      u.Namespace,
-				"rook_object_store": u.Spec.Store,
-			},
-		},
-		StringData: secrets,
-		Type:       k8sutil.RookType,
+	// Generate Kubernetes Secret
+	secret, err := buildUserSecret(cephObjectStoreUser, r.secretKeys(), r.objectStore)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to generate ceph object user %q secret", cephObjectStoreUser.Name)
 	}
 
-	return secret
-}
-
-func (r *ReconcileObjectStoreUser) reconcileCephUserSecret(cephObjectStoreUser *cephv1.CephObjectStoreUser) (reconcile.Result, error) {
-	// Generate Kubernetes Secret
-	secret := r.generateCephUserSecret(cephObjectStoreUser)
+	// Check whether the secret content is about to change
+	changed, err := r.secretChanged(secret)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get ceph object user %q secret", secret.Name)
+	}
 
-	// Set owner ref to the object store user object
-	err := controllerutil.SetControllerReference(cephObjectStoreUser, secret, r.scheme)
+	// The keys of the user may have been replaced outside of the operator, which the secret must follow
+	drifted, err := r.secretKeysDrifted(secret, secretKeyNamesOf(cephObjectStoreUser.Spec).accessKey)
 	if err != nil {
-		return reconcile.Result{}, errors.Wrapf(err, "failed to set owner reference for ceph object user %q secret", secret.Name)
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get ceph object user %q secret", secret.Name)
 	}
 
 	// Create Kubernetes Secret
+	err = r.deleteSecretOfOtherType(secret)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to replace ceph object user %q secret", secret.Name)
+	}
 	err = opcontroller.CreateOrUpdateObject(r.client, secret)
 	if err != nil {
 		return reconcile.Result{}, errors.Wrapf(err, "failed to create or update ceph object user %q secret", secret.Name)
 	}
+	if changed {
+		r.recordChange("secret", secret.Name)
+	}
+	if drifted {
+		message := fmt.Sprintf("the access key in secret %q was no longer a key of the user in RGW, e.g. after a rotation with radosgw-admin, the secret was updated with the current keys", secret.Name)
+		r.log.Warningf("ceph object user %q: %s", cephObjectStoreUser.Name, message)
+		r.recorder.Event(cephObjectStoreUser, v1.EventTypeWarning, reasonSecretKeysRepaired, message)
+	}
+	r.log.Infof("created ceph object user secret %q", secret.Name)
+	cephObjectStoreUser.Status.SecretHash = secret.Annotations[secretHashAnnotation]
+
+	err = r.replicateSecret(cephObjectStoreUser, secret)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to replicate ceph object user %q secret", secret.Name)
+	}
 
-	logger.Infof("created ceph object user secret %q", secret.Name)
 	return reconcile.Result{}, nil
 }
 
-func (r *ReconcileObjectStoreUser) objectStoreInitialized(cephObjectStoreUser *cephv1.CephObjectStoreUser) error {
-	err := r.getObjectStore(cephObjectStoreUser)
+// replicateSecret copies the secret of the user into the secret namespaces of the spec and removes the
+// copies from the namespaces that are no longer in the spec. The copies cannot be owned by the user in
+// another namespace, so their namespaces are tracked in the status for the cleanup.
+func (r *ReconcileObjectStoreUser) replicateSecret(u *cephv1.CephObjectStoreUser, secret *v1.Secret) error {
+	namespaces := []string{}
+	for _, namespace := range u.Spec.SecretNamespaces {
+		if namespace == u.Namespace || contains(namespaces, namespace) {
+			continue
+		}
+		replica := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        secret.Name,
+				Namespace:   namespace,
+				Labels:      secret.Labels,
+				Annotations: secret.Annotations,
+			},
+			StringData: secret.StringData,
+			Type:       secret.Type,
+		}
+		changed, err := r.secretChanged(replica)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the secret in namespace %q", namespace)
+		}
+		err = r.deleteSecretOfOtherType(replica)
+		if err != nil {
+			return errors.Wrapf(err, "failed to replace the secret in namespace %q", namespace)
+		}
+		err = opcontroller.CreateOrUpdateObject(r.client, replica)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create or update the secret in namespace %q", namespace)
+		}
+		if changed {
+			r.recordChange("secret", fmt.Sprintf("%s/%s", namespace, secret.Name))
+		}
+		namespaces = append(namespaces, namespace)
+	}
+
+	removed := []string{}
+	for _, namespace := range u.Status.SecretNamespaces {
+		if !contains(namespaces, namespace) {
+			removed = append(removed, namespace)
+		}
+	}
+	err := r.deleteSecretReplicas(u, removed)
 	if err != nil {
 		return err
 	}
-	logger.Debug("CephObjectStore exists")
 
-	pods, err := r.getRgwPodList(cephObjectStoreUser)
+	u.Status.SecretNamespaces = namespaces
+	if len(namespaces) == 0 {
+		u.Status.SecretNamespaces = nil
+	}
+	return nil
+}
+
+// deleteSecretReplicas deletes the copies of the secret of the user in the given namespaces
+func (r *ReconcileObjectStoreUser) deleteSecretReplicas(u *cephv1.CephObjectStoreUser, namespaces []string) error {
+	for _, namespace := range namespaces {
+		replica := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: userSecretName(u), Namespace: namespace}}
+		err := r.client.Delete(context.TODO(), replica)
+		if err != nil && !kerrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete the secret %q in namespace %q", replica.Name, namespace)
+		}
+		r.log.Infof("deleted ceph object user secret %q in namespace %q", replica.Name, namespace)
+	}
+	return nil
+}
+
+// secretChanged returns whether the given secret is missing or holds different data than the current one
+func (r *ReconcileObjectStoreUser) secretChanged(secret *v1.Secret) (bool, error) {
+	existing := &v1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, existing)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	// The API server converts StringData into Data, but the data may not have been converted yet
+	current := map[string]string{}
+	for k, v := range existing.Data {
+		current[k] = string(v)
+	}
+	for k, v := range existing.StringData {
+		current[k] = v
+	}
+
+	return existing.Type != secret.Type || !reflect.DeepEqual(current, secret.StringData), nil
+}
+
+// secretKeysDrifted returns whether the existing secret holds an access key that differs from the one of
+// the secret and that this reconcile did not replace, i.e. the key of the user was changed outside of
+// the operator
+func (r *ReconcileObjectStoreUser) secretKeysDrifted(secret *v1.Secret, accessKeyName string) (bool, error) {
+	existing := &v1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, existing)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	current, desired := secretValue(existing, accessKeyName), secret.StringData[accessKeyName]
+	if current == "" || desired == "" || current == desired {
+		return false, nil
+	}
+	for _, change := range r.changes {
+		if strings.HasPrefix(change, "accessKey=") || strings.HasPrefix(change, "key=") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// deleteSecretOfOtherType deletes the existing secret if its type differs from the type of the secret,
+// so that the secret can be created again with its type. The type of a secret cannot be updated.
+func (r *ReconcileObjectStoreUser) deleteSecretOfOtherType(secret *v1.Secret) error {
+	existing := &v1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, existing)
 	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if existing.Type == secret.Type {
+		return nil
+	}
+	r.log.Infof("recreating secret %q in namespace %q to change its type from %q to %q", secret.Name, secret.Namespace, existing.Type, secret.Type)
+	err = r.client.Delete(context.TODO(), existing)
+	if err != nil && !kerrors.IsNotFound(err) {
 		return err
 	}
+	return nil
+}
+
+// secretDataHash returns the hex encoded SHA-256 of the data of a secret. The keys of the data are
+// sorted when they are marshalled, so the hash only changes with the data.
+func secretDataHash(data map[string]string) string {
+	// Marshalling a map of strings cannot fail
+	content, _ := json.Marshal(data)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashUserSpec returns a hash of the spec of the user to detect spec changes between reconciles, given
+// the user with the defaults of its object store
+func hashUserSpec(u *cephv1.CephObjectStoreUser) string {
+	spec, err := json.Marshal(u.Spec)
+	if err != nil {
+		// Never match a previous hash, the drift is corrected then
+		logger.Warningf("failed to hash the spec of ceph object user %q. %v", u.Name, err)
+		return ""
+	}
+	return k8sutil.Hash(string(spec))
+}
+
+// recordChange keeps track of a field changed by the current reconcile for the audit log
+func (r *ReconcileObjectStoreUser) recordChange(field, value string) {
+	r.changes = append(r.changes, fmt.Sprintf("%s=%s", field, value))
+}
+
+// logAudit emits a single audit log entry listing the changes made by the current reconcile.
+// Nothing is logged if the reconcile did not change anything.
+func (r *ReconcileObjectStoreUser) logAudit() {
+	if len(r.changes) == 0 {
+		return
+	}
+	// The logger of the user adds its uid, store and namespace
+	r.log.Infof("audit: admin=%q changes=%q", cephclient.AdminUsername, strings.Join(r.changes, ","))
+}
+
+func (r *ReconcileObjectStoreUser) objectStoreInitialized(cephObjectStoreUser *cephv1.CephObjectStoreUser) (*cephv1.CephObjectStore, error) {
+	objectStore, err := r.getObjectStore(cephObjectStoreUser)
+	if err != nil {
+		return nil, err
+	}
+	r.log.Debug("CephObjectStore exists")
+
+	// The rgw of an external store does not run in the cluster, it must be reachable instead
+	if objectStore.Spec.Gateway.ExternalEndpoint != "" {
+		err := probeEndpoint(objectStore.Spec.Gateway.ExternalEndpoint)
+		if err != nil {
+			return nil, errors.Wrapf(object.ErrObjectStoreNotReady, "external endpoint %q of CephObjectStore %q is unreachable. %v", objectStore.Spec.Gateway.ExternalEndpoint, objectStore.Name, err)
+		}
+		return objectStore, nil
+	}
+
+	pods, err := r.getRgwPodList(cephObjectStoreUser)
+	if err != nil {
+		return nil, err
+	}
 
 	// check if at least one pod is running
 	if len(pods.Items) > 0 {
-		logger.Debugf("CephObjectStore %q is running with %d pods. %v", cephObjectStoreUser.Name, len(pods.Items), pods)
-		return nil
+		r.log.Debugf("CephObjectStore %q is running with %d pods. %v", cephObjectStoreUser.Name, len(pods.Items), pods)
+		return objectStore, nil
+	}
+
+	return nil, errors.Wrapf(object.ErrRGWNotRunning, "no rgw pod of CephObjectStore %q found", objectStore.Name)
+}
+
+// refreshObjectStoreProgressing reports whether the object store of the user is not ready. The phase of
+// the store does not hold the user back, e.g. while the store is updated: its users are reconciled as
+// long as an rgw pod runs and the admin commands respond.
+func refreshObjectStoreProgressing(u *cephv1.CephObjectStoreUser, store *cephv1.CephObjectStore) {
+	phase := ""
+	if store.Status != nil {
+		phase = store.Status.Phase
+	}
+	if phase != "" && phase != k8sutil.ReadyStatus {
+		message := fmt.Sprintf("CephObjectStore %q is %s, the user is reconciled as the rgw pods run", store.Name, phase)
+		setCondition(u, cephv1.ConditionObjectStoreProgressing, v1.ConditionTrue, "ObjectStoreNotReady", message)
+		return
+	}
+	if findCondition(u.Status.Conditions, cephv1.ConditionObjectStoreProgressing) != nil {
+		setCondition(u, cephv1.ConditionObjectStoreProgressing, v1.ConditionFalse, "ObjectStoreReady", fmt.Sprintf("CephObjectStore %q is ready", store.Name))
 	}
+}
+
+// adminTarget describes what the radosgw-admin commands of the user run against, so that the message
+// of a failure shows whether the operator reached the right cluster, e.g. with outdated mon endpoints
+func (r *ReconcileObjectStoreUser) adminTarget(u *cephv1.CephObjectStoreUser) string {
+	target := fmt.Sprintf("realm %q of the ceph cluster in namespace %q", storeName(u), clusterNamespace(u))
+	endpoints := &v1.ConfigMap{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: mon.EndpointConfigMapName, Namespace: clusterNamespace(u)}, endpoints)
+	if err != nil {
+		r.log.Debugf("failed to get the mon endpoints of the ceph cluster in namespace %q. %v", clusterNamespace(u), err)
+	} else if mons := endpoints.Data[mon.EndpointDataKey]; mons != "" {
+		target = fmt.Sprintf("%s with mons %s", target, mons)
+	}
+	if r.objectStore != nil && r.objectStore.Spec.Gateway.ExternalEndpoint != "" {
+		target = fmt.Sprintf("%s, external gateway %q", target, r.objectStore.Spec.Gateway.ExternalEndpoint)
+	}
+	return target
+}
 
-	return errors.New("no rgw pod found")
+// cephClusterHealth returns the health of the CephCluster in the given namespace, empty if the cluster
+// did not report its ceph status yet
+func (r *ReconcileObjectStoreUser) cephClusterHealth(namespace string) (string, error) {
+	cephCluster := &cephv1.CephCluster{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: namespace, Namespace: namespace}, cephCluster)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get CephCluster in namespace %q", namespace)
+	}
+	if cephCluster.Status.CephStatus == nil {
+		return "", nil
+	}
+	return cephCluster.Status.CephStatus.Health, nil
 }
 
-func (r *ReconcileObjectStoreUser) getObjectStore(cephObjectStoreUser *cephv1.CephObjectStoreUser) error {
+func (r *ReconcileObjectStoreUser) getObjectStore(cephObjectStoreUser *cephv1.CephObjectStoreUser) (*cephv1.CephObjectStore, error) {
 	// check if CephObjectStore CR is created
-	objectStores := &cephv1.CephObjectStoreList{}
-	err := r.client.List(context.TODO(), objectStores)
+	objectStore := &cephv1.CephObjectStore{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: storeName(cephObjectStoreUser), Namespace: clusterNamespace(cephObjectStoreUser)}, objectStore)
 	if err != nil {
 		if kerrors.IsNotFound(err) {
-			return errors.Wrapf(err, "CephObjectStore %q could not be found", cephObjectStoreUser.Name)
+			return nil, errors.Wrapf(object.ErrObjectStoreNotFound, "CephObjectStore %q could not be found", storeName(cephObjectStoreUser))
+		}
+		// The store of another namespace can only be read if the operator watches that namespace
+		if kerrors.IsForbidden(err) {
+			return nil, errors.Wrapf(err, "the operator is not allowed to read CephObjectStore %q in namespace %q, its service account needs access to the namespace of the store", storeName(cephObjectStoreUser), clusterNamespace(cephObjectStoreUser))
 		}
-		return errors.Wrap(err, "failed to get CephObjectStore")
+		return nil, errors.Wrap(err, "failed to get CephObjectStore")
 	}
 
-	return nil
+	return objectStore, nil
 }
 
 func (r *ReconcileObjectStoreUser) getRgwPodList(cephObjectStoreUser *cephv1.CephObjectStoreUser) (*corev1.PodList, error) {
@@ -417,8 +3940,8 @@ func (r *ReconcileObjectStoreUser) getRgwPodList(cephObjectStoreUser *cephv1.Cep
 	// check if ObjectStore is initialized
 	// rook does this by starting the RGW pod(s)
 	listOpts := []client.ListOption{
-		client.InNamespace(cephObjectStoreUser.Namespace),
-		client.MatchingLabels(labelsForRgw(cephObjectStoreUser.Spec.Store)),
+		client.InNamespace(clusterNamespace(cephObjectStoreUser)),
+		client.MatchingLabels(labelsForRgw(storeName(cephObjectStoreUser))),
 	}
 
 	err := r.client.List(context.TODO(), pods, listOpts...)
@@ -432,19 +3955,51 @@ func (r *ReconcileObjectStoreUser) getRgwPodList(cephObjectStoreUser *cephv1.Cep
 	return pods, nil
 }
 
+// logDebugReconcile logs the radosgw-admin commands run by a debug reconcile of the user and its outcome
+func (r *ReconcileObjectStoreUser) logDebugReconcile(u *cephv1.CephObjectStoreUser, err error) {
+	outcome := fmt.Sprintf("phase %q", u.Status.Phase)
+	if condition := findCondition(u.Status.Conditions, cephv1.ConditionFailure); condition != nil && condition.Status == v1.ConditionTrue {
+		outcome = fmt.Sprintf("%s, failure %q: %s", outcome, condition.Reason, condition.Message)
+	}
+	if err != nil {
+		outcome = fmt.Sprintf("%s, error: %v", outcome, err)
+	}
+	r.log.Infof("debug reconcile of ceph object user %q ran %d radosgw-admin commands and ended with %s", u.Name, len(r.adminStats.Commands), outcome)
+	for i, command := range r.adminStats.Commands {
+		r.log.Infof("debug reconcile of ceph object user %q command %d: %s", u.Name, i+1, command)
+	}
+}
+
+// deletionGraceRemaining returns how long the RGW user of a deleted CR is kept until its deletion
+// grace period passed, zero or less once it can be deleted
+func deletionGraceRemaining(u *cephv1.CephObjectStoreUser) time.Duration {
+	if u.Spec.DeletionGracePeriod == nil || u.GetAnnotations()[forceDeleteAnnotation] == "true" {
+		return 0
+	}
+	return time.Until(u.GetDeletionTimestamp().Add(u.Spec.DeletionGracePeriod.Duration))
+}
+
 // Delete the user
-func deleteUser(context *clusterd.Context, u *cephv1.CephObjectStoreUser) error {
-	objContext := object.NewContext(context, u.Spec.Store, u.Namespace)
-	_, rgwerr, err := object.DeleteUser(objContext, u.Name)
+func (r *ReconcileObjectStoreUser) deleteUser(objContext *object.Context, u *cephv1.CephObjectStoreUser) error {
+	var opts []string
+	if u.Spec.PurgeDataOnDeletion {
+		opts = append(opts, "--purge-data")
+	}
+	// The uid the user was created with, even if the spec was changed since
+	uid := observedUserID(u)
+	if uid == "" {
+		uid = userID(u)
+	}
+	_, rgwerr, err := object.DeleteUser(objContext, uid, opts...)
 	if err != nil {
-		if rgwerr == 3 {
-			logger.Infof("ceph object user %q does not exist in store %q", u.Name, u.Spec.Store)
-		} else {
-			return errors.Wrapf(err, "failed to delete ceph object user %q", u.Name)
+		if rgwerr == object.RGWErrorNotFound {
+			r.log.Infof("ceph object user %q does not exist in store %q", uid, storeName(u))
+			return nil
 		}
+		return errors.Wrapf(err, "failed to delete ceph object user %q", uid)
 	}
 
-	logger.Infof("ceph object user %q deleted successfully", u.Name)
+	r.log.Infof("ceph object user %q deleted successfully", uid)
 	return nil
 }
 
@@ -459,9 +4014,332 @@ func ValidateUser(u *cephv1.CephObjectStoreUser) error {
 	if u.Spec.Store == "" {
 		return errors.New("missing store")
 	}
+	if i := strings.Index(u.Spec.Store, "/"); i >= 0 {
+		storeNamespace := u.Spec.Store[:i]
+		if errs := validation.IsDNS1123Label(storeNamespace); len(errs) > 0 {
+			return errors.Errorf("invalid namespace of store %q, %s", u.Spec.Store, strings.Join(errs, ", "))
+		}
+		if storeName(u) == "" || strings.Contains(storeName(u), "/") {
+			return errors.Errorf("invalid store %q, must be a name or <namespace>/<name>", u.Spec.Store)
+		}
+		if u.Spec.ClusterNamespace != "" && u.Spec.ClusterNamespace != storeNamespace {
+			return errors.Errorf("namespace of store %q does not match cluster namespace %q", u.Spec.Store, u.Spec.ClusterNamespace)
+		}
+	}
+	if u.Spec.DisableSecretGeneration && len(u.Spec.SecretNamespaces) > 0 {
+		return errors.New("secretNamespaces cannot be set when disableSecretGeneration is set")
+	}
+	for _, namespace := range u.Spec.SecretNamespaces {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return errors.Errorf("invalid secret namespace %q, %s", namespace, strings.Join(errs, ", "))
+		}
+	}
+	users := map[string]bool{}
+	for _, user := range u.Spec.Users {
+		if users[user] {
+			return errors.Errorf("duplicate user %q in the template", user)
+		}
+		users[user] = true
+		if errs := validation.IsDNS1123Subdomain(templateUserName(u, user)); len(errs) > 0 {
+			return errors.Errorf("invalid user %q in the template, %s", user, strings.Join(errs, ", "))
+		}
+	}
+	for key := range u.Spec.Tags {
+		// The tags are annotations of the secret
+		if errs := validation.IsQualifiedName(tagAnnotationPrefix + key); len(errs) > 0 {
+			return errors.Errorf("invalid tag %q, %s", key, strings.Join(errs, ", "))
+		}
+	}
+	for key, value := range u.Spec.SecretLabels {
+		if errs := append(validation.IsQualifiedName(key), validation.IsValidLabelValue(value)...); len(errs) > 0 {
+			return errors.Errorf("invalid secret label %q, %s", key, strings.Join(errs, ", "))
+		}
+		if contains(managedSecretLabels, key) {
+			return errors.Errorf("invalid secret label %q, the label is set by the operator", key)
+		}
+	}
+	for key := range u.Spec.SecretAnnotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return errors.Errorf("invalid secret annotation %q, %s", key, strings.Join(errs, ", "))
+		}
+		if contains(managedSecretAnnotations, key) || strings.HasPrefix(key, tagAnnotationPrefix) {
+			return errors.Errorf("invalid secret annotation %q, the annotation is set by the operator", key)
+		}
+	}
+	if u.Spec.DeletionGracePeriod != nil && u.Spec.DeletionGracePeriod.Duration < 0 {
+		return errors.Errorf("invalid deletion grace period %q, must not be negative", u.Spec.DeletionGracePeriod.Duration)
+	}
+	if u.Spec.UID != "" {
+		if len(u.Spec.Users) > 0 {
+			return errors.New("uid cannot be set on a template, its users would share the RGW user")
+		}
+		if err := validateUID(u.Spec.UID); err != nil {
+			return err
+		}
+	}
+	if u.Spec.PreservePolicy != "" && u.Spec.PreservePolicy != cephv1.PreservePolicyDelete && u.Spec.PreservePolicy != cephv1.PreservePolicyRetain {
+		return errors.Errorf("invalid preserve policy %q, must be %q or %q", u.Spec.PreservePolicy, cephv1.PreservePolicyDelete, cephv1.PreservePolicyRetain)
+	}
+	if u.Spec.OpMask != "" {
+		if err := validateOpMask(u.Spec.OpMask); err != nil {
+			return err
+		}
+	}
+	for _, tag := range u.Spec.PlacementTags {
+		// RGW takes the tags as a comma separated list
+		if tag == "" || strings.Contains(tag, ",") {
+			return errors.Errorf("invalid placement tag %q, must not be empty or contain a comma", tag)
+		}
+	}
+	if u.Spec.SecretType != "" {
+		if err := validateSecretType(u.Spec.SecretType); err != nil {
+			return err
+		}
+	}
+	if err := validateInitialBuckets(u.Spec); err != nil {
+		return err
+	}
+	switch u.Spec.SecretFormat {
+	case "", secretFormatRook, secretFormatMC, secretFormatStandard:
+	default:
+		return errors.Errorf("invalid secret format %q, must be %q, %q, %q or empty", u.Spec.SecretFormat, secretFormatRook, secretFormatMC, secretFormatStandard)
+	}
+	subusers := map[string]cephv1.SubuserSpec{}
+	for _, subuser := range u.Spec.Subusers {
+		subusers[subuser.Name] = subuser
+	}
+	keyNames := map[string]bool{}
+	for i, key := range u.Spec.Keys {
+		name := keyName(i, key)
+		if keyNames[name] {
+			return errors.Errorf("duplicate key name %q", name)
+		}
+		keyNames[name] = true
+		if key.SHA256 != "" && key.SecretName == "" {
+			return errors.Errorf("sha256 of key %q requires a secret name", name)
+		}
+		switch key.Type {
+		case "", cephv1.UserKeyTypeS3:
+			if u.Spec.SuppressUserKeys {
+				return errors.New("s3 keys cannot be set when the user keys are suppressed")
+			}
+		case cephv1.UserKeyTypeSwift:
+			subuser, ok := subusers[key.Name]
+			if !ok {
+				return errors.Errorf("swift key %q must be named after a subuser of the spec, RGW only holds swift keys for subusers", name)
+			}
+			if subuser.GenerateKey || subuser.SecretName != "" {
+				return errors.Errorf("swift key %q is also set by subuser %q, only one of them may set it", name, subuser.Name)
+			}
+			if key.SHA256 != "" {
+				return errors.Errorf("sha256 of swift key %q is not supported, only s3 keys are verified", name)
+			}
+		default:
+			return errors.Errorf("invalid type %q of key %q, must be %q or %q", key.Type, name, cephv1.UserKeyTypeS3, cephv1.UserKeyTypeSwift)
+		}
+	}
+	if u.Spec.DeterministicKey != nil {
+		if err := validateDeterministicKey(u.Spec); err != nil {
+			return err
+		}
+	}
+	if u.Spec.RestoreKeysFrom != "" {
+		if err := validateRestoreKeys(u.Spec); err != nil {
+			return err
+		}
+	}
+	if u.Spec.KeyRotation != nil {
+		if err := validateKeyRotation(u.Spec); err != nil {
+			return err
+		}
+	}
+	if !generatesKeys(u.Spec) {
+		if u.Spec.SuppressUserKeys {
+			return errors.New("a user with suppressed keys has no keys to provide, generateKey must not be false")
+		}
+		if u.Spec.GenerateMissingKey {
+			return errors.New("generateMissingKey requires the keys to be generated, generateKey must not be false")
+		}
+		for i, key := range u.Spec.Keys {
+			if !isSwiftKey(key) && key.SecretName == "" {
+				return errors.Errorf("key %q must be read from a secret, the keys are not generated", keyName(i, key))
+			}
+		}
+	}
+	if u.Spec.Adopt && u.Spec.SuppressUserKeys {
+		return errors.New("an adopted user keeps its keys, they must not be suppressed")
+	}
+	if u.Spec.AWSKeyNames && u.Spec.SuppressUserKeys {
+		return errors.New("aws key names require the user keys, they must not be suppressed")
+	}
+	if u.Spec.SecretFormat == secretFormatMC && u.Spec.SuppressUserKeys {
+		return errors.New("secret format mc requires the user keys, they must not be suppressed")
+	}
+	if u.Spec.Quotas != nil && u.Spec.Quotas.MaxSize != nil && u.Spec.Quotas.MaxSizeKB != nil {
+		return errors.New("max size and max size in KB are mutually exclusive, only one of maxSize and maxSizeKB may be set")
+	}
+	if u.Spec.Quotas != nil && u.Spec.Quotas.MaxSize != nil {
+		if err := validateMaxSize(*u.Spec.Quotas.MaxSize); err != nil {
+			return err
+		}
+	}
+	if u.Spec.Quotas != nil && u.Spec.Quotas.MaxSizeKB != nil {
+		if err := validateMaxSizeKB(*u.Spec.Quotas.MaxSizeKB); err != nil {
+			return err
+		}
+	}
+	if u.Spec.Quotas != nil && u.Spec.Quotas.MaxSizePercent != nil {
+		if u.Spec.Quotas.MaxSize != nil || u.Spec.Quotas.MaxSizeKB != nil {
+			return errors.New("max size percent and max size are mutually exclusive, only one of maxSize, maxSizeKB and maxSizePercent may be set")
+		}
+		if err := validateMaxSizePercent(*u.Spec.Quotas.MaxSizePercent); err != nil {
+			return err
+		}
+	}
+	if u.Spec.Quotas != nil && u.Spec.Quotas.MaxObjects != nil {
+		if err := validateMaxObjects(*u.Spec.Quotas.MaxObjects); err != nil {
+			return err
+		}
+	}
+	if u.Spec.Quotas != nil && u.Spec.Quotas.DefaultBucketMaxSize != nil {
+		if err := validateMaxSize(*u.Spec.Quotas.DefaultBucketMaxSize); err != nil {
+			return errors.Wrap(err, "invalid default bucket max size")
+		}
+	}
+	if u.Spec.Quotas != nil && u.Spec.Quotas.DefaultBucketMaxObjects != nil {
+		if err := validateMaxObjects(*u.Spec.Quotas.DefaultBucketMaxObjects); err != nil {
+			return errors.Wrap(err, "invalid default bucket max objects")
+		}
+	}
+	if u.Spec.Quotas != nil && u.Spec.Quotas.MaxBuckets != nil && *u.Spec.Quotas.MaxBuckets < -1 {
+		return errors.Errorf("invalid max buckets %d, must be -1 for unlimited, 0 to disable buckets or a positive limit", *u.Spec.Quotas.MaxBuckets)
+	}
+	if _, ok := capPresets[u.Spec.Preset]; u.Spec.Preset != "" && !ok {
+		return errors.Errorf("invalid preset %q, must be %q", u.Spec.Preset, cephv1.ObjectUserPresetMonitoring)
+	}
+	if u.Spec.Capabilities != nil && u.Spec.Capabilities.Raw != "" {
+		raw := *u.Spec.Capabilities
+		raw.Raw = ""
+		if raw != (cephv1.ObjectUserCapSpec{}) || u.Spec.Preset != "" {
+			return errors.New("raw capabilities and the capability fields are mutually exclusive, the capability fields and the preset must not be set with raw")
+		}
+		if _, err := parseRawCaps(u.Spec.Capabilities.Raw); err != nil {
+			return err
+		}
+	} else if u.Spec.Capabilities != nil {
+		for _, capType := range capTypes {
+			if perm := specCaps(u.Spec.Capabilities)[capType]; perm != "" {
+				if err := validateCapPerm(capType, perm); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	tempURLKeyIDs := map[int]bool{}
+	for _, key := range u.Spec.TempURLKeys {
+		if key.ID != 0 && key.ID != 1 {
+			return errors.Errorf("invalid temp url key id %d, must be 0 or 1", key.ID)
+		}
+		if tempURLKeyIDs[key.ID] {
+			return errors.Errorf("duplicate temp url key id %d", key.ID)
+		}
+		tempURLKeyIDs[key.ID] = true
+		if key.Generate == (key.SecretName != "") {
+			return errors.Errorf("temp url key %d must either be generated or read from a secret", key.ID)
+		}
+	}
+	for _, subuser := range u.Spec.Subusers {
+		if subuser.Name == "" {
+			return errors.New("missing subuser name")
+		}
+		if err := validateSubuserName(subuser.Name); err != nil {
+			return err
+		}
+		if _, ok := rgwSubuserAccess[subuser.Access]; subuser.Access != "" && !ok {
+			return errors.Errorf("invalid access %q of subuser %q, must be one of read, write, readwrite or full", subuser.Access, subuser.Name)
+		}
+		if subuser.GenerateKey && subuser.SecretName != "" {
+			return errors.Errorf("subuser %q cannot both generate a key and read it from secret %q", subuser.Name, subuser.SecretName)
+		}
+		if subuser.RotateWithUser && !subuser.GenerateKey {
+			return errors.Errorf("subuser %q can only rotate a generated key with the user, generateKey must be set", subuser.Name)
+		}
+		switch subuser.KeyType {
+		case "", cephv1.UserKeyTypeSwift:
+		case cephv1.UserKeyTypeS3:
+			if swiftKeyNames(u.Spec)[subuser.Name] {
+				return errors.Errorf("swift key %q cannot be set on subuser %q, it holds an s3 key", subuser.Name, subuser.Name)
+			}
+		default:
+			return errors.Errorf("invalid key type %q of subuser %q, must be %q or %q", subuser.KeyType, subuser.Name, cephv1.UserKeyTypeSwift, cephv1.UserKeyTypeS3)
+		}
+	}
 	return nil
 }
 
+// clusterNamespace returns the namespace of the CephCluster and the CephObjectStore of the user, which
+// is the namespace of a store given as "<namespace>/<name>"
+func clusterNamespace(u *cephv1.CephObjectStoreUser) string {
+	if u.Spec.ClusterNamespace != "" {
+		return u.Spec.ClusterNamespace
+	}
+	if i := strings.Index(u.Spec.Store, "/"); i >= 0 {
+		return u.Spec.Store[:i]
+	}
+	return u.Namespace
+}
+
+// userID returns the uid of the RGW user of the CR, the name of the CR unless the spec sets one
+func userID(u *cephv1.CephObjectStoreUser) string {
+	if u.Spec.UID != "" {
+		return u.Spec.UID
+	}
+	return u.Name
+}
+
+// observedUserID returns the uid the RGW user of the CR was reconciled with, empty if it was not
+// reconciled yet. The users reconciled before the uid was reported have the name of the CR.
+func observedUserID(u *cephv1.CephObjectStoreUser) string {
+	if u.Status == nil {
+		return ""
+	}
+	if u.Status.UID == "" && u.Status.ObservedSpecHash != "" {
+		return u.Name
+	}
+	return u.Status.UID
+}
+
+// storeName returns the name of the CephObjectStore of the user, without the namespace of a store
+// given as "<namespace>/<name>"
+func storeName(u *cephv1.CephObjectStoreUser) string {
+	if i := strings.Index(u.Spec.Store, "/"); i >= 0 {
+		return u.Spec.Store[i+1:]
+	}
+	return u.Spec.Store
+}
+
+// probeEndpoint checks that a TCP connection can be opened to the host of the given endpoint
+func probeEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse endpoint %q", endpoint)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	conn, err := net.DialTimeout("tcp", host, endpointProbeTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
 func labelsForRgw(name string) map[string]string {
 	return map[string]string{"rgw": name, k8sutil.AppAttr: appName}
 }
@@ -18,28 +18,54 @@ limitations under the License.
 package objectuser
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	osexec "os/exec"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/coreos/pkg/capnslog"
+	bktv1alpha1 "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	rookclient "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/operator/ceph/object"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 
 	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/exec"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
-	userCreateJSON = `{
+	userExistsOutput = "could not create user: unable to create user, user: my-user exists"
+	userCreateJSON   = `{
 	"user_id": "my-user",
 	"display_name": "my-user",
 	"email": "",
@@ -141,7 +167,7 @@ func TestCephObjectStoreUserController(t *testing.T) {
 	// Create a fake client to mock API calls.
 	cl := fake.NewFakeClientWithScheme(s, object...)
 	// Create a ReconcileObjectStoreUser object with the scheme and fake client.
-	r := &ReconcileObjectStoreUser{client: cl, scheme: s, context: c}
+	r := &ReconcileObjectStoreUser{client: cl, scheme: s, context: c, recorder: record.NewFakeRecorder(10)}
 
 	// Mock request to simulate Reconcile() being called on an event for a
 	// watched resource .
@@ -175,7 +201,7 @@ func TestCephObjectStoreUserController(t *testing.T) {
 	// Create a fake client to mock API calls.
 	cl = fake.NewFakeClientWithScheme(s, object...)
 	// Create a ReconcileObjectStoreUser object with the scheme and fake client.
-	r = &ReconcileObjectStoreUser{client: cl, scheme: s, context: c}
+	r = &ReconcileObjectStoreUser{client: cl, scheme: s, context: c, recorder: record.NewFakeRecorder(10)}
 	logger.Info("STARTING PHASE 2")
 	res, err = r.Reconcile(req)
 	assert.NoError(t, err)
@@ -188,10 +214,11 @@ func TestCephObjectStoreUserController(t *testing.T) {
 	// FAILURE! The CephCluster is ready but NO rgw object
 	//
 	cephCluster.Status.Phase = k8sutil.ReadyStatus
+	cephCluster.Status.CephStatus = &cephv1.CephStatus{Health: "HEALTH_OK"}
 	// Create a fake client to mock API calls.
 	cl = fake.NewFakeClientWithScheme(s, object...)
 	// Create a ReconcileObjectStoreUser object with the scheme and fake client.
-	r = &ReconcileObjectStoreUser{client: cl, scheme: s, context: c}
+	r = &ReconcileObjectStoreUser{client: cl, scheme: s, context: c, recorder: record.NewFakeRecorder(10)}
 
 	logger.Info("STARTING PHASE 3")
 	res, err = r.Reconcile(req)
@@ -221,7 +248,7 @@ func TestCephObjectStoreUserController(t *testing.T) {
 	// Create a fake client to mock API calls.
 	cl = fake.NewFakeClientWithScheme(s, object...)
 	// Create a ReconcileObjectStoreUser object with the scheme and fake client.
-	r = &ReconcileObjectStoreUser{client: cl, scheme: s, context: c}
+	r = &ReconcileObjectStoreUser{client: cl, scheme: s, context: c, recorder: record.NewFakeRecorder(10)}
 
 	logger.Info("STARTING PHASE 4")
 	err = r.client.Get(context.TODO(), types.NamespacedName{Name: store, Namespace: namespace}, cephObjectStore)
@@ -250,6 +277,6364 @@ func TestCephObjectStoreUserController(t *testing.T) {
 	assert.NoError(t, err)
 	assert.False(t, res.Requeue)
 	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
-	assert.Equal(t, "Ready", objectUser.Status.Phase, objectUser)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase, objectUser)
 	logger.Info("PHASE 5 DONE")
 }
+
+// newReadyReconciler returns a reconciler whose CephCluster, CephObjectStore and rgw pod are all ready
+// so that Reconcile() goes all the way to the ceph user creation
+func newReadyReconciler(executor *exectest.MockExecutor, objects ...runtime.Object) *ReconcileObjectStoreUser {
+	if executor.MockExecuteCommandWithOutputFile == nil {
+		executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName, command, outfile string, args ...string) (string, error) {
+			if args[0] == "status" {
+				return `{"pgmap":{"num_pgs":100,"pgs_by_state":[{"state_name":"active+clean","count":100}]}}`, nil
+			}
+			return "", nil
+		}
+	}
+	c := &clusterd.Context{
+		Executor:      executor,
+		RookClientset: rookclient.NewSimpleClientset()}
+
+	cephCluster := &cephv1.CephCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      namespace,
+			Namespace: namespace,
+		},
+		Status: cephv1.ClusterStatus{
+			Phase:      k8sutil.ReadyStatus,
+			CephStatus: &cephv1.CephStatus{Health: "HEALTH_OK"},
+		},
+	}
+	cephObjectStore := &cephv1.CephObjectStore{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      store,
+			Namespace: namespace,
+		},
+		TypeMeta: metav1.TypeMeta{
+			Kind: "CephObjectStore",
+		},
+	}
+	rgwPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "rook-ceph-rgw-my-store-a-5fd6fb4489-xv65v",
+		Namespace: namespace,
+		Labels:    map[string]string{k8sutil.AppAttr: appName, "rgw": store}}}
+	objects = append(objects, cephCluster, cephObjectStore, rgwPod)
+
+	s := scheme.Scheme
+	s.AddKnownTypes(cephv1.SchemeGroupVersion, &cephv1.CephObjectStoreUser{}, &cephv1.CephObjectStoreUserList{})
+	s.AddKnownTypes(cephv1.SchemeGroupVersion, &cephv1.CephCluster{})
+	s.AddKnownTypes(cephv1.SchemeGroupVersion, &cephv1.CephObjectStore{})
+	s.AddKnownTypes(cephv1.SchemeGroupVersion, &cephv1.CephObjectStoreList{})
+	s.AddKnownTypes(bktv1alpha1.SchemeGroupVersion, &bktv1alpha1.ObjectBucket{}, &bktv1alpha1.ObjectBucketList{})
+	cl := fake.NewFakeClientWithScheme(s, objects...)
+
+	return &ReconcileObjectStoreUser{client: cl, scheme: s, context: c, recorder: record.NewFakeRecorder(10), locks: &userLocks{}}
+}
+
+// newObjectUser returns a CephObjectStoreUser named after the test user
+func newObjectUser() *cephv1.CephObjectStoreUser {
+	return &cephv1.CephObjectStoreUser{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: cephv1.ObjectStoreUserSpec{
+			Store: store,
+		},
+		TypeMeta: metav1.TypeMeta{
+			Kind: "CephObjectStoreUser",
+		},
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	capnslog.SetFormatter(capnslog.NewStringFormatter(&buf))
+	defer capnslog.SetFormatter(capnslog.NewDefaultFormatter(os.Stderr))
+
+	userExists := false
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" && userExists {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	r := newReadyReconciler(executor, newObjectUser())
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	t.Run("audit entry on change", func(t *testing.T) {
+		buf.Reset()
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+
+		var audit []string
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.Contains(line, "audit:") {
+				audit = append(audit, line)
+			}
+		}
+		assert.Equal(t, 1, len(audit), buf.String())
+		assert.Contains(t, audit[0], `uid="my-user"`)
+		assert.Contains(t, audit[0], `store="my-store"`)
+		assert.Contains(t, audit[0], `namespace="rook-ceph"`)
+		assert.Contains(t, audit[0], `admin="client.admin"`)
+		assert.Contains(t, audit[0], "user=created")
+		assert.Contains(t, audit[0], "secret=rook-ceph-object-user-my-store-my-user")
+		// keys must never appear in the audit log
+		assert.Contains(t, audit[0], "accessKey=<redacted>")
+		assert.NotContains(t, audit[0], "EOE7FYCNOBZJ5VFV909G")
+		assert.NotContains(t, audit[0], "qmIqpWm8HxCzmynCrD6U6vKWi4hnDBndOnmxXNsV")
+	})
+
+	t.Run("no audit entry on no-op", func(t *testing.T) {
+		userExists = true
+		buf.Reset()
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.NotContains(t, buf.String(), "audit:")
+	})
+
+	t.Run("every line names the user", func(t *testing.T) {
+		buf.Reset()
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		lines := 0
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.Contains(line, controllerName+": ") {
+				assert.Contains(t, line, `uid="my-user" store="my-store" namespace="rook-ceph": `)
+				lines++
+			}
+		}
+		assert.NotZero(t, lines, buf.String())
+	})
+}
+
+func TestCreateRace(t *testing.T) {
+	// radosgw-admin exits with EEXIST if the user was created concurrently
+	_, exitErr := osexec.Command("sh", "-c", "echo 'could not create user: unable to create user, user: my-user exists' >&2; exit 17").Output()
+	createErr := &exec.CommandError{ActionName: "radosgw-admin", Err: exitErr}
+	var modifyArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return "", createErr
+			}
+			if args[0] == "user" && args[1] == "modify" {
+				modifyArgs = args
+			}
+			if args[0] == "user" {
+				return strings.Replace(userCreateJSON, `"display_name": "my-user"`, `"display_name": "other"`, 1), nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	// the user was reconciled before, so its display name may be corrected
+	objectUser.Status = &cephv1.ObjectStoreUserStatus{ObservedSpecHash: "previous"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the existing user is updated rather than failing the reconcile
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user", "modify", "--uid", name, "--display-name", name}, modifyArgs[:6])
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+
+	t.Run("email in use", func(t *testing.T) {
+		_, exitErr := osexec.Command("sh", "-c", "echo 'could not create user: unable to create user, email: a@example.com is the email address an existing user' >&2; exit 17").Output()
+		createErr = &exec.CommandError{ActionName: "radosgw-admin", Err: exitErr}
+		err := r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		objectUser.Spec.Email = "a@example.com"
+		err = r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+		_, err = r.reconcile(req)
+		assert.Error(t, err)
+	})
+}
+
+func TestModifyUserNotFound(t *testing.T) {
+	// radosgw-admin exits with EEXIST if the user exists, and with ENOENT if it does not
+	_, exitErr := osexec.Command("sh", "-c", "echo 'could not create user: unable to create user, user: my-user exists' >&2; exit 17").Output()
+	createErr := &exec.CommandError{ActionName: "radosgw-admin", Err: exitErr}
+	_, exitErr = osexec.Command("sh", "-c", "echo 'could not modify user: unable to modify user, user not found' >&2; exit 2").Output()
+	modifyErr := &exec.CommandError{ActionName: "radosgw-admin", Err: exitErr}
+	deleted := false
+	creates := 0
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				creates++
+				if !deleted {
+					return "", createErr
+				}
+			}
+			if args[0] == "user" && args[1] == "modify" && !deleted {
+				// the user is deleted out of band after it was looked up
+				deleted = true
+				return "", modifyErr
+			}
+			if args[0] == "user" {
+				return strings.Replace(userCreateJSON, `"display_name": "my-user"`, `"display_name": "other"`, 1), nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Status = &cephv1.ObjectStoreUserStatus{ObservedSpecHash: "previous"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the user is created again in the same reconcile rather than failing it
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, creates)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+
+	t.Run("not found again", func(t *testing.T) {
+		// a user that keeps vanishing is only created again once, the resynced user is looked up first
+		deleted = false
+		creates = 0
+		executor.MockExecuteCommandWithOutput = func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				creates++
+				return "", createErr
+			}
+			if args[0] == "user" && args[1] == "modify" {
+				return "", modifyErr
+			}
+			if args[0] == "user" {
+				return strings.Replace(userCreateJSON, `"display_name": "my-user"`, `"display_name": "other"`, 1), nil
+			}
+			return "", nil
+		}
+		_, err := r.reconcile(req)
+		assert.Error(t, err)
+		assert.Equal(t, 1, creates)
+	})
+}
+
+func TestUpdateDisplayName(t *testing.T) {
+	var modifyArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" && args[1] == "modify" {
+				modifyArgs = args
+			}
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	t.Run("display name unchanged", func(t *testing.T) {
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.Nil(t, modifyArgs)
+	})
+
+	t.Run("display name changed", func(t *testing.T) {
+		err := r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		objectUser.Spec.DisplayName = "my-new-display-name"
+		err = r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+
+		_, err = r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"user", "modify", "--uid", "my-user", "--display-name", "my-new-display-name"}, modifyArgs[:6])
+	})
+}
+
+func TestOpMask(t *testing.T) {
+	var createArgs, modifyArgs []string
+	userExists := false
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				createArgs = args
+				if userExists {
+					return userExistsOutput, nil
+				}
+			}
+			if args[0] == "user" && args[1] == "modify" {
+				modifyArgs = args
+			}
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	setOpMask := func(opMask string) {
+		err := r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		objectUser.Spec.OpMask = opMask
+		err = r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+		createArgs, modifyArgs = nil, nil
+	}
+
+	t.Run("empty op mask keeps the rgw default", func(t *testing.T) {
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.NotContains(t, createArgs, "--op-mask")
+		assert.Nil(t, modifyArgs)
+	})
+
+	t.Run("op mask on create", func(t *testing.T) {
+		setOpMask("read")
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.Contains(t, strings.Join(createArgs, " "), "--op-mask read")
+	})
+
+	t.Run("op mask on modify", func(t *testing.T) {
+		userExists = true
+		setOpMask("read")
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.Contains(t, strings.Join(modifyArgs, " "), "--op-mask read")
+	})
+
+	t.Run("equivalent op mask is not modified", func(t *testing.T) {
+		setOpMask("*")
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.Nil(t, modifyArgs)
+	})
+
+	t.Run("invalid op mask", func(t *testing.T) {
+		setOpMask("read,list")
+		_, err := r.reconcile(req)
+		assert.Error(t, err)
+		assert.Nil(t, createArgs)
+	})
+}
+
+func TestNormalizeOpMask(t *testing.T) {
+	assert.Equal(t, "read, write, delete", normalizeOpMask("*"))
+	assert.Equal(t, "read, write, delete", normalizeOpMask("delete,read, write"))
+	assert.Equal(t, "read", normalizeOpMask("read"))
+	assert.NoError(t, validateOpMask("read, write"))
+	assert.NoError(t, validateOpMask("*"))
+	assert.Error(t, validateOpMask("read,list"))
+	assert.Error(t, validateOpMask(""))
+}
+
+func TestQuotaClamped(t *testing.T) {
+	var quotaArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			if args[0] == "quota" && args[1] == "set" {
+				quotaArgs = args
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	maxSize := resource.MustParse("20G")
+	maxObjects := int64(500)
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxSize: &maxSize, MaxObjects: &maxObjects}
+	r := newReadyReconciler(executor, objectUser)
+
+	// Limit the size of the users of the store
+	objectStore := &cephv1.CephObjectStore{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: store, Namespace: namespace}, objectStore)
+	assert.NoError(t, err)
+	storeMaxSize := resource.MustParse("10G")
+	objectStore.Spec.MaxUserQuotas = &cephv1.ObjectUserQuotaSpec{MaxSize: &storeMaxSize}
+	err = r.client.Update(context.TODO(), objectStore)
+	assert.NoError(t, err)
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"quota", "set", "--uid", name, "--quota-scope", "user", "--max-size", "10000000000", "--max-objects", "500"}, quotaArgs[:10])
+
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	condition := findCondition(objectUser.Status.Conditions, cephv1.ConditionQuotaClamped)
+	assert.NotNil(t, condition)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+	assert.Contains(t, condition.Message, "maxSize from 20G to 10G")
+	assert.True(t, objectUser.Status.Quota.UserEnforced)
+}
+
+func TestStoreDefaults(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	int64Ptr := func(i int64) *int64 { return &i }
+	tests := []struct {
+		name         string
+		quotas       *cephv1.ObjectUserQuotaSpec
+		caps         *cephv1.ObjectUserCapSpec
+		maxBuckets   string
+		quotaArgs    []string
+		expectedCaps map[string]string
+	}{
+		{"no quota or caps", nil, nil, "5", []string{"--max-size", "-1", "--max-objects", "1000"}, map[string]string{"usage": "read", "metadata": "read"}},
+		{"spec takes precedence", &cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(10), MaxObjects: int64Ptr(50)}, &cephv1.ObjectUserCapSpec{Usage: "*"}, "10", []string{"--max-size", "-1", "--max-objects", "50"}, map[string]string{"usage": "*", "metadata": "read"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var modifyArgs, quotaArgs []string
+			liveCaps := map[string]string{}
+			executor := &exectest.MockExecutor{
+				MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+					switch {
+					case args[0] == "user" && args[1] == "create":
+						return userExistsOutput, nil
+					case args[0] == "user" && args[1] == "modify":
+						modifyArgs = args
+					case args[0] == "caps":
+						liveCaps = applyCaps(liveCaps, args[1], args[5])
+						return "", nil
+					case args[0] == "quota" && args[1] == "set":
+						quotaArgs = args
+						return "", nil
+					case args[0] == "quota":
+						return "", nil
+					}
+					return capsUserJSON(liveCaps), nil
+				},
+			}
+			objectUser := newObjectUser()
+			objectUser.Spec.Quotas = test.quotas
+			objectUser.Spec.Capabilities = test.caps
+			r := newReadyReconciler(executor, objectUser)
+
+			// The store sets a baseline for all of its users
+			objectStore := &cephv1.CephObjectStore{}
+			err := r.client.Get(context.TODO(), types.NamespacedName{Name: store, Namespace: namespace}, objectStore)
+			assert.NoError(t, err)
+			objectStore.Spec.DefaultUserQuotas = &cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(5), MaxObjects: int64Ptr(1000)}
+			objectStore.Spec.DefaultUserCapabilities = &cephv1.ObjectUserCapSpec{Usage: "read", Metadata: "read"}
+			err = r.client.Update(context.TODO(), objectStore)
+			assert.NoError(t, err)
+
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+			_, err = r.reconcile(req)
+			assert.NoError(t, err)
+			assert.Equal(t, test.maxBuckets, modifyArgs[indexOf(modifyArgs, "--max-buckets")+1])
+			assert.Equal(t, test.quotaArgs, quotaArgs[6:10])
+			assert.Equal(t, test.expectedCaps, liveCaps)
+			err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+			assert.NoError(t, err)
+			assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+			// the defaults are not written to the spec of the user
+			assert.Equal(t, test.quotas, objectUser.Spec.Quotas)
+			assert.Equal(t, test.caps, objectUser.Spec.Capabilities)
+		})
+	}
+
+	t.Run("invalid default", func(t *testing.T) {
+		objectUser := newObjectUser()
+		r := newReadyReconciler(&exectest.MockExecutor{}, objectUser)
+		objectStore := &cephv1.CephObjectStore{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: store, Namespace: namespace}, objectStore)
+		assert.NoError(t, err)
+		objectStore.Spec.DefaultUserCapabilities = &cephv1.ObjectUserCapSpec{Usage: "all"}
+		err = r.client.Update(context.TODO(), objectStore)
+		assert.NoError(t, err)
+		req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+		_, err = r.reconcile(req)
+		assert.Error(t, err)
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Equal(t, reasonInvalidSpec, findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure).Reason)
+	})
+}
+
+func TestClampQuotas(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	int64Ptr := func(i int64) *int64 { return &i }
+	maxSize := resource.MustParse("10G")
+	maxQuotas := &cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(10), MaxSize: &maxSize, MaxObjects: int64Ptr(1000)}
+
+	// no store maximum
+	quotas := &cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(20)}
+	result, clamped := clampQuotas(quotas, nil)
+	assert.Equal(t, quotas, result)
+	assert.Empty(t, clamped)
+
+	// unset quotas default to the store maximum without being reported as clamped
+	result, clamped = clampQuotas(nil, maxQuotas)
+	assert.Equal(t, 10, *result.MaxBuckets)
+	assert.Equal(t, int64(10000000000), result.MaxSize.Value())
+	assert.Equal(t, int64(1000), *result.MaxObjects)
+	assert.Empty(t, clamped)
+
+	// quotas within the maximum are kept
+	size := resource.MustParse("1G")
+	result, clamped = clampQuotas(&cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(5), MaxSize: &size, MaxObjects: int64Ptr(10)}, maxQuotas)
+	assert.Equal(t, 5, *result.MaxBuckets)
+	assert.Equal(t, int64(1000000000), result.MaxSize.Value())
+	assert.Equal(t, int64(10), *result.MaxObjects)
+	assert.Empty(t, clamped)
+
+	// unlimited and excessive quotas are clamped
+	result, clamped = clampQuotas(&cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(50), MaxObjects: int64Ptr(-1)}, maxQuotas)
+	assert.Equal(t, 10, *result.MaxBuckets)
+	assert.Equal(t, int64(1000), *result.MaxObjects)
+	assert.Equal(t, []string{"maxBuckets from 50 to 10", "maxObjects from -1 to 1000"}, clamped)
+}
+
+func TestUserConflict(t *testing.T) {
+	var modifyArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" && args[1] == "modify" {
+				modifyArgs = args
+			}
+			if args[0] == "user" {
+				// an unrelated user has the uid
+				user := strings.Replace(userCreateJSON, `"display_name": "my-user"`, `"display_name": "Billing"`, 1)
+				return strings.Replace(user, `"email": ""`, `"email": "billing@example.com"`, 1), nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the user is not taken over
+	_, err := r.reconcile(req)
+	assert.Error(t, err)
+	assert.Empty(t, modifyArgs)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+	failure := findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure)
+	assert.Equal(t, reasonUserConflict, failure.Reason)
+	assert.Contains(t, failure.Message, `ceph object user "my-user" already exists with display name "Billing" instead of "my-user" and email "billing@example.com", set adopt to take it over`)
+
+	// a dry run reports the conflict too
+	objectUser.Annotations = map[string]string{dryRunAnnotation: "true"}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.Error(t, err)
+	assert.Empty(t, modifyArgs)
+
+	// an adopted user is taken over, keeping the display name and email the spec does not set
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	objectUser.Annotations = nil
+	objectUser.Spec.Adopt = true
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, modifyArgs)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+}
+
+func TestEmailDrift(t *testing.T) {
+	var modifyArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" && args[1] == "modify" {
+				modifyArgs = args
+			}
+			if args[0] == "user" {
+				// the live email was changed out of band
+				return strings.Replace(userCreateJSON, `"email": ""`, `"email": "other@example.com"`, 1), nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Email = "my-user@example.com"
+	// the user was reconciled before, the email was changed out of band since
+	objectUser.Status = &cephv1.ObjectStoreUserStatus{ObservedSpecHash: "previous"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user", "modify", "--uid", "my-user", "--email", "my-user@example.com"}, modifyArgs[:6])
+
+	// clearing the email in the spec clears it in RGW
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	objectUser.Spec.Email = ""
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+
+	modifyArgs = nil
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user", "modify", "--uid", "my-user", "--email", ""}, modifyArgs[:6])
+}
+
+func TestFailureBackoff(t *testing.T) {
+	objectUser := newObjectUser()
+	objectUser.Spec.OpMask = "invalid"
+	r := newReadyReconciler(&exectest.MockExecutor{}, objectUser)
+	r.maxBackoff = 30 * time.Second
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the interval grows with every consecutive failure and then caps at the maximum
+	expected := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second}
+	for _, backoff := range expected {
+		res, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.True(t, res.Requeue)
+		assert.Equal(t, backoff, res.RequeueAfter)
+	}
+
+	// a successful reconcile resets the backoff
+	err := r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	objectUser.Spec.OpMask = ""
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	r.context.Executor = &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			return userCreateJSON, nil
+		},
+	}
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, r.failures)
+	assert.Equal(t, 5*time.Second, r.failureBackoff(req.NamespacedName))
+
+	// a maximum that is not positive falls back to the default instead of requeueing right away
+	for _, maxBackoff := range []time.Duration{0, -time.Minute} {
+		r.maxBackoff = maxBackoff
+		r.failures = nil
+		assert.Equal(t, initialFailureBackoff, r.failureBackoff(req.NamespacedName))
+		for i := 0; i < 10; i++ {
+			r.failureBackoff(req.NamespacedName)
+		}
+		assert.Equal(t, defaultMaxFailureBackoff, r.failureBackoff(req.NamespacedName))
+	}
+}
+
+func TestReconcileMetrics(t *testing.T) {
+	counterValue := func(c prometheus.Counter) float64 {
+		m := &dto.Metric{}
+		assert.NoError(t, c.Write(m))
+		return m.GetCounter().GetValue()
+	}
+	gaugeValue := func(phase cephv1.ObjectStoreUserPhase) float64 {
+		m := &dto.Metric{}
+		assert.NoError(t, userPhase.WithLabelValues(namespace, store, name, string(phase)).Write(m))
+		return m.GetGauge().GetValue()
+	}
+
+	objectUser := newObjectUser()
+	objectUser.Spec.OpMask = "invalid"
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			return userCreateJSON, nil
+		},
+	}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	total := counterValue(reconcileTotal.WithLabelValues(namespace, store))
+	invalid := counterValue(reconcileErrors.WithLabelValues(namespace, store, failureReasonInvalidSpec))
+
+	_, err := r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, total+1, counterValue(reconcileTotal.WithLabelValues(namespace, store)))
+	assert.Equal(t, invalid+1, counterValue(reconcileErrors.WithLabelValues(namespace, store, failureReasonInvalidSpec)))
+	assert.Equal(t, 1.0, gaugeValue(cephv1.ObjectStoreUserPhaseReconcileFailed))
+	assert.Equal(t, 0.0, gaugeValue(cephv1.ObjectStoreUserPhaseReady))
+
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	objectUser.Spec.OpMask = ""
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, total+2, counterValue(reconcileTotal.WithLabelValues(namespace, store)))
+	assert.Equal(t, invalid+1, counterValue(reconcileErrors.WithLabelValues(namespace, store, failureReasonInvalidSpec)))
+	assert.Equal(t, 0.0, gaugeValue(cephv1.ObjectStoreUserPhaseReconcileFailed))
+	assert.Equal(t, 1.0, gaugeValue(cephv1.ObjectStoreUserPhaseReady))
+}
+
+func TestSubuserAccess(t *testing.T) {
+	var subuserArgs [][]string
+	liveSubusers := `"subusers": []`
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" {
+				return strings.Replace(userCreateJSON, `"subusers": []`, liveSubusers, 1), nil
+			}
+			if args[0] == "subuser" {
+				subuserArgs = append(subuserArgs, args)
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Subusers = []cephv1.SubuserSpec{{Name: "swift", Access: cephv1.AccessSpecFull}}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	t.Run("full access is passed as the full token", func(t *testing.T) {
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(subuserArgs))
+		assert.Equal(t, []string{"subuser", "create", "--uid", name, "--subuser", "my-user:swift", "--access", "full"}, subuserArgs[0][:8])
+	})
+
+	t.Run("full access matches the full-control permission", func(t *testing.T) {
+		subuserArgs = nil
+		liveSubusers = `"subusers": [{"id": "my-user:swift", "permissions": "full-control"}]`
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.Nil(t, subuserArgs)
+	})
+
+	t.Run("invalid access is rejected", func(t *testing.T) {
+		err := r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		objectUser.Spec.Subusers[0].Access = "full-control"
+		assert.Error(t, ValidateUser(objectUser))
+		err = r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+
+		subuserArgs = nil
+		_, err = r.reconcile(req)
+		assert.Error(t, err)
+		assert.Nil(t, subuserArgs)
+	})
+
+	t.Run("invalid name is rejected", func(t *testing.T) {
+		err := r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		objectUser.Spec.Subusers[0].Access = ""
+		for _, invalid := range []string{"my swift", "my:swift", "my/swift", "my$swift", "my\tswift", strings.Repeat("s", 65)} {
+			objectUser.Spec.Subusers[0].Name = invalid
+			assert.Error(t, ValidateUser(objectUser), invalid)
+		}
+		objectUser.Spec.Subusers[0].Name = "my swift"
+		err = r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+
+		// the name is rejected before any admin command is issued
+		subuserArgs = nil
+		_, err = r.reconcile(req)
+		assert.Error(t, err)
+		assert.Nil(t, subuserArgs)
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+		assert.Contains(t, findCondition(objectUser.Status.Conditions, cephv1.ConditionReady).Message, `invalid subuser name "my swift"`)
+
+		objectUser.Spec.Subusers[0].Name = "swift"
+		err = r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+	})
+
+	t.Run("readwrite access is passed as the readwrite token", func(t *testing.T) {
+		err := r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		objectUser.Spec.Subusers[0].Access = cephv1.AccessSpecReadWrite
+		err = r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+
+		// the full-control subuser is modified to read-write
+		subuserArgs = nil
+		_, err = r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(subuserArgs))
+		assert.Equal(t, []string{"subuser", "modify", "--uid", name, "--subuser", "my-user:swift", "--access", "readwrite"}, subuserArgs[0][:8])
+
+		subuserArgs = nil
+		liveSubusers = `"subusers": [{"id": "my-user:swift", "permissions": "read-write"}]`
+		_, err = r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Nil(t, subuserArgs)
+	})
+}
+
+func TestQuotaStatus(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" {
+				// only the bucket quota is enabled
+				return strings.Replace(userCreateJSON, `"bucket_quota": {
+		"enabled": false`, `"bucket_quota": {
+		"enabled": true`, 1), nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.Reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, &cephv1.ObjectUserQuotaStatus{UserEnforced: false, BucketEnforced: true}, objectUser.Status.Quota)
+}
+
+func TestQuotaEnabled(t *testing.T) {
+	boolPtr := func(b bool) *bool { return &b }
+	int64Ptr := func(i int64) *int64 { return &i }
+	tests := []struct {
+		name         string
+		quotas       cephv1.ObjectUserQuotaSpec
+		liveEnabled  bool
+		expected     []string
+		userEnforced bool
+	}{
+		{"enabled without limits", cephv1.ObjectUserQuotaSpec{Enabled: boolPtr(true)}, false, []string{"quota enable"}, true},
+		{"disabled without limits", cephv1.ObjectUserQuotaSpec{Enabled: boolPtr(false)}, true, []string{"quota disable"}, false},
+		{"enabled without limits unchanged", cephv1.ObjectUserQuotaSpec{Enabled: boolPtr(true)}, true, nil, true},
+		{"disabled with limits", cephv1.ObjectUserQuotaSpec{MaxObjects: int64Ptr(100), Enabled: boolPtr(false)}, false, []string{"quota set", "quota disable"}, false},
+		{"limits enable by default", cephv1.ObjectUserQuotaSpec{MaxObjects: int64Ptr(100)}, false, []string{"quota set", "quota enable"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var quotaCommands []string
+			executor := &exectest.MockExecutor{
+				MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+					if args[0] == "user" && args[1] == "create" {
+						return userExistsOutput, nil
+					}
+					if args[0] == "user" {
+						return strings.Replace(userCreateJSON, `"user_quota": {
+		"enabled": false`, fmt.Sprintf(`"user_quota": {
+		"enabled": %t`, test.liveEnabled), 1), nil
+					}
+					if args[0] == "quota" {
+						quotaCommands = append(quotaCommands, strings.Join(args[:2], " "))
+					}
+					return "", nil
+				},
+			}
+			objectUser := newObjectUser()
+			quotas := test.quotas
+			objectUser.Spec.Quotas = &quotas
+			r := newReadyReconciler(executor, objectUser)
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+			_, err := r.reconcile(req)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, quotaCommands)
+			err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+			assert.NoError(t, err)
+			assert.Equal(t, test.userEnforced, objectUser.Status.Quota.UserEnforced)
+		})
+	}
+
+	t.Run("store maximum", func(t *testing.T) {
+		maxObjects := int64(1000)
+		result, clamped := clampQuotas(&cephv1.ObjectUserQuotaSpec{Enabled: boolPtr(false)}, &cephv1.ObjectUserQuotaSpec{MaxObjects: &maxObjects})
+		assert.True(t, *result.Enabled)
+		assert.Equal(t, []string{"enabled from false to true"}, clamped)
+	})
+}
+
+func TestSuppressUserKeys(t *testing.T) {
+	var createArgs, keyArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				createArgs = args
+			}
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			if args[0] == "key" {
+				keyArgs = args
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.SuppressUserKeys = true
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "create", createArgs[1])
+	assert.Equal(t, []string{"key", "rm", "--uid", name, "--key-type", "s3", "--access-key", "EOE7FYCNOBZJ5VFV909G"}, keyArgs[:8])
+
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	assert.NotContains(t, secret.StringData, "AccessKey")
+	assert.NotContains(t, secret.StringData, "SecretKey")
+	assert.NotContains(t, secret.Data, "AccessKey")
+}
+
+func TestDefaultPlacement(t *testing.T) {
+	var modifyArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" && args[1] == "modify" {
+				modifyArgs = args
+			}
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			if args[0] == "zonegroup" && args[1] == "get" {
+				return `{"placement_targets": [{"name": "default-placement", "storage_classes": ["STANDARD"]}, {"name": "cold", "storage_classes": ["STANDARD", "GLACIER"]}]}`, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	setPlacement := func(placement, storageClass string) {
+		err := r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		objectUser.Spec.DefaultPlacement = placement
+		objectUser.Spec.DefaultStorageClass = storageClass
+		err = r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+		modifyArgs = nil
+	}
+
+	t.Run("placement is applied", func(t *testing.T) {
+		setPlacement("cold", "GLACIER")
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"user", "modify", "--uid", name, "--placement-id", "cold", "--storage-class", "GLACIER"}, modifyArgs[:8])
+	})
+
+	t.Run("storage class of the default placement target", func(t *testing.T) {
+		setPlacement("", "STANDARD")
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"user", "modify", "--uid", name, "--placement-id", "", "--storage-class", "STANDARD"}, modifyArgs[:8])
+	})
+
+	t.Run("unknown placement target", func(t *testing.T) {
+		setPlacement("nearline", "")
+		_, err := r.reconcile(req)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `placement target "nearline" of ceph object user "my-user" does not exist`)
+		assert.Nil(t, modifyArgs)
+	})
+
+	t.Run("unknown storage class", func(t *testing.T) {
+		setPlacement("default-placement", "GLACIER")
+		_, err := r.reconcile(req)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `storage class "GLACIER" of ceph object user "my-user" does not exist in placement target "default-placement"`)
+		assert.Nil(t, modifyArgs)
+	})
+}
+
+func TestPlacementTags(t *testing.T) {
+	var modifyArgs []string
+	liveTags := `[]`
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" && args[1] == "modify" {
+				modifyArgs = args
+			}
+			if args[0] == "user" {
+				return strings.Replace(userCreateJSON, `"placement_tags": []`, `"placement_tags": `+liveTags, 1), nil
+			}
+			if args[0] == "zonegroup" && args[1] == "get" {
+				return `{"placement_targets": [{"name": "default-placement", "tags": []}, {"name": "ssd", "tags": ["ssd", "fast"]}]}`, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	setTags := func(tags ...string) {
+		err := r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		objectUser.Spec.PlacementTags = tags
+		err = r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+		modifyArgs = nil
+	}
+
+	t.Run("tags are applied", func(t *testing.T) {
+		setTags("ssd", "fast")
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"user", "modify", "--uid", name, "--tags", "fast,ssd"}, modifyArgs[:6])
+
+		updated := &cephv1.CephObjectStoreUser{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, updated)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"fast", "ssd"}, updated.Status.PlacementTags)
+	})
+
+	t.Run("matching tags are not modified", func(t *testing.T) {
+		liveTags = `["ssd", "fast"]`
+		defer func() { liveTags = `[]` }()
+		setTags("fast", "ssd")
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.Nil(t, modifyArgs)
+	})
+
+	t.Run("unknown tag", func(t *testing.T) {
+		setTags("ssd", "nvme")
+		_, err := r.reconcile(req)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `placement tag "nvme" of ceph object user "my-user" is not the tag of a placement target`)
+		assert.Nil(t, modifyArgs)
+	})
+
+	t.Run("invalid tag", func(t *testing.T) {
+		u := newObjectUser()
+		u.Spec.PlacementTags = []string{"ssd,fast"}
+		err := ValidateUser(u)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "must not be empty or contain a comma")
+	})
+}
+
+func TestAdminNotResponding(t *testing.T) {
+	responding := false
+	var created bool
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "zone" && args[1] == "get" && !responding {
+				return "", errors.New("connect: connection refused")
+			}
+			if args[0] == "user" && args[1] == "create" {
+				created = true
+				return userCreateJSON, nil
+			}
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the user waits for the admin commands without failing
+	res, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.True(t, res.Requeue)
+	assert.False(t, created)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconciling, objectUser.Status.Phase)
+	assert.Equal(t, reasonAdminNotResponding, findCondition(objectUser.Status.Conditions, cephv1.ConditionProgressing).Reason)
+
+	// the user is created once they respond
+	responding = true
+	res, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.False(t, res.Requeue)
+	assert.True(t, created)
+}
+
+func TestAdminCapsInsufficient(t *testing.T) {
+	// radosgw-admin exits with EACCES if the ceph credentials lack the caps for the command
+	_, exitErr := osexec.Command("sh", "-c", "echo 'could not create user: (13) Permission denied' >&2; exit 13").Output()
+	deniedErr := &exec.CommandError{ActionName: "radosgw-admin", Err: exitErr}
+	tests := []struct {
+		name    string
+		command string
+	}{
+		{"probe", "zone get"},
+		{"create", "user create"},
+		{"update step", "quota set"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			executor := &exectest.MockExecutor{
+				MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+					if strings.Join(args[:2], " ") == test.command {
+						return "", deniedErr
+					}
+					if args[0] == "user" {
+						return userCreateJSON, nil
+					}
+					return "", nil
+				},
+			}
+			objectUser := newObjectUser()
+			objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxObjects: func(i int64) *int64 { return &i }(100)}
+			r := newReadyReconciler(executor, objectUser)
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+			// the user is failed with the caps to grant and only retried slowly
+			result, err := r.Reconcile(req)
+			assert.NoError(t, err)
+			assert.Equal(t, adminCapsRequeueAfter, result.RequeueAfter)
+			err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+			assert.NoError(t, err)
+			assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+			condition := findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure)
+			assert.Equal(t, reasonAdminCapsInsufficient, condition.Reason)
+			assert.Contains(t, condition.Message, "mon 'allow rw' osd 'allow rwx'")
+			assert.Empty(t, r.failures)
+		})
+	}
+}
+
+func TestObjectStoreReadOnly(t *testing.T) {
+	// radosgw-admin exits with EROFS if the object store does not accept changes
+	_, exitErr := osexec.Command("sh", "-c", "echo 'could not modify user: (30) Read-only file system' >&2; exit 30").Output()
+	readOnlyErr := &exec.CommandError{ActionName: "radosgw-admin", Err: exitErr}
+	readOnly := true
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if readOnly && strings.Join(args[:2], " ") == "quota set" {
+				return "", readOnlyErr
+			}
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxObjects: func(i int64) *int64 { return &i }(100)}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the user is retried without an error while the store is read-only
+	result, err := r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, readOnlyRequeueAfter, result.RequeueAfter)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconciling, objectUser.Status.Phase)
+	condition := findCondition(objectUser.Status.Conditions, cephv1.ConditionObjectStoreReadOnly)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+	assert.Equal(t, reasonObjectStoreReadOnly, condition.Reason)
+	assert.Nil(t, findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure))
+	assert.Empty(t, r.failures)
+
+	t.Run("writable again", func(t *testing.T) {
+		readOnly = false
+		_, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		objectUser := &cephv1.CephObjectStoreUser{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+		assert.Equal(t, corev1.ConditionFalse, findCondition(objectUser.Status.Conditions, cephv1.ConditionObjectStoreReadOnly).Status)
+	})
+}
+
+func TestEffectiveConfig(t *testing.T) {
+	userJSON := strings.Replace(userCreateJSON, `"caps": []`, `"caps": [{"type": "usage", "perm": "read"}]`, 1)
+	userJSON = strings.Replace(userJSON, `"enabled": false,
+		"check_on_raw": false,
+		"max_size": -1,
+		"max_size_kb": 0,
+		"max_objects": -1
+	},
+	"temp_url_keys"`, `"enabled": true,
+		"check_on_raw": false,
+		"max_size": 1048576,
+		"max_size_kb": 1024,
+		"max_objects": 100
+	},
+	"temp_url_keys"`, 1)
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" {
+				return userJSON, nil
+			}
+			return "", nil
+		},
+	}
+	// neither quotas nor capabilities are set in the spec
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	updated := &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, &cephv1.ObjectUserEffectiveQuotaStatus{
+		MaxBuckets: 1000,
+		User:       cephv1.ObjectUserQuotaLimits{Enabled: true, MaxSize: 1048576, MaxObjects: 100},
+		Bucket:     cephv1.ObjectUserQuotaLimits{Enabled: false, MaxSize: -1, MaxObjects: -1},
+	}, updated.Status.EffectiveQuota)
+	assert.Equal(t, map[string]string{"usage": "read"}, updated.Status.EffectiveCapabilities)
+	assert.Nil(t, updated.Status.Capabilities)
+}
+
+func TestUserWithoutKeys(t *testing.T) {
+	keylessJSON := `{"user_id": "my-user", "display_name": "my-user", "keys": []}`
+	var keyCreated bool
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "key" && args[1] == "create" {
+				keyCreated = true
+				return userCreateJSON, nil
+			}
+			if args[0] == "user" {
+				if keyCreated {
+					return userCreateJSON, nil
+				}
+				return keylessJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	secretName := types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}
+
+	t.Run("the user fails without writing a secret", func(t *testing.T) {
+		_, err := r.reconcile(req)
+		assert.Error(t, err)
+		_, ok := errors.Cause(err).(*NoKeysError)
+		assert.True(t, ok)
+		assert.False(t, keyCreated)
+		err = r.client.Get(context.TODO(), secretName, &corev1.Secret{})
+		assert.True(t, kerrors.IsNotFound(err))
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+		assert.Equal(t, reasonUserHasNoKeys, findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure).Reason)
+	})
+
+	t.Run("a key is generated", func(t *testing.T) {
+		objectUser.Spec.GenerateMissingKey = true
+		err := r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+		_, err = r.reconcile(req)
+		assert.NoError(t, err)
+		assert.True(t, keyCreated)
+		secret := &corev1.Secret{}
+		err = r.client.Get(context.TODO(), secretName, secret)
+		assert.NoError(t, err)
+		assert.Equal(t, "EOE7FYCNOBZJ5VFV909G", secret.StringData["AccessKey"])
+		assert.Equal(t, "qmIqpWm8HxCzmynCrD6U6vKWi4hnDBndOnmxXNsV", secret.StringData["SecretKey"])
+	})
+}
+
+func TestReconciledStore(t *testing.T) {
+	var userCalls int
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				userCalls++
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the user of another store is ignored without a requeue
+	r.store = "other-store"
+	res, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, res)
+	assert.Equal(t, 0, userCalls)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Nil(t, objectUser.Status)
+
+	// the user of the store is reconciled
+	r.store = store
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.NotEqual(t, 0, userCalls)
+
+	p := storePredicate(store)
+	other := newObjectUser()
+	other.Spec.Store = "other-store"
+	assert.True(t, p.Create(event.CreateEvent{Object: newObjectUser()}))
+	assert.False(t, p.Create(event.CreateEvent{Object: other}))
+	assert.False(t, p.Delete(event.DeleteEvent{Object: other}))
+	assert.True(t, p.Update(event.UpdateEvent{ObjectOld: newObjectUser(), ObjectNew: newObjectUser()}))
+	assert.False(t, p.Update(event.UpdateEvent{ObjectOld: other, ObjectNew: other}))
+}
+
+func TestGenerateKeyDisabled(t *testing.T) {
+	generateKey := false
+	providedJSON := `{"user_id": "my-user", "display_name": "my-user", "keys": [{"user": "my-user", "access_key": "REF", "secret_key": "secret-REF"}]}`
+	newExecutor := func(commands *[]string, createArgs *[]string) *exectest.MockExecutor {
+		exists := false
+		return &exectest.MockExecutor{
+			MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+				*commands = append(*commands, strings.Join(args[:2], " "))
+				if args[0] == "user" && args[1] == "create" {
+					*createArgs = args
+					exists = true
+					return providedJSON, nil
+				}
+				if args[0] == "user" && !exists {
+					return "", nil
+				}
+				if args[0] == "user" {
+					return providedJSON, nil
+				}
+				return "", nil
+			},
+		}
+	}
+
+	t.Run("false with no key", func(t *testing.T) {
+		var commands, createArgs []string
+		objectUser := newObjectUser()
+		objectUser.Spec.GenerateKey = &generateKey
+		r := newReadyReconciler(newExecutor(&commands, &createArgs), objectUser)
+		req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+		_, err := r.reconcile(req)
+		assert.Error(t, err)
+		_, ok := errors.Cause(err).(*KeyNotProvidedError)
+		assert.True(t, ok)
+		assert.Nil(t, createArgs)
+		assert.NotContains(t, commands, "key create")
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+		assert.Equal(t, reasonKeyNotProvided, findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure).Reason)
+	})
+
+	t.Run("false with provided key", func(t *testing.T) {
+		var commands, createArgs []string
+		keySecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-key", Namespace: namespace},
+			Data:       map[string][]byte{"AccessKey": []byte("REF"), "SecretKey": []byte("secret-REF")},
+		}
+		objectUser := newObjectUser()
+		objectUser.Spec.GenerateKey = &generateKey
+		objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "blue", SecretName: "my-key"}}
+		r := newReadyReconciler(newExecutor(&commands, &createArgs), objectUser, keySecret)
+		req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		i := indexOf(createArgs, "--access-key")
+		assert.True(t, i > 0)
+		assert.Equal(t, []string{"--access-key", "REF", "--secret-key", "secret-REF"}, createArgs[i:i+4])
+		assert.NotContains(t, commands, "key create")
+		assert.NotContains(t, commands, "key rm")
+		secret := &corev1.Secret{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+		assert.NoError(t, err)
+		assert.Equal(t, "REF", secret.StringData["AccessKey"])
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		objectUser := newObjectUser()
+		objectUser.Spec.GenerateKey = &generateKey
+		objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "blue"}}
+		err := ValidateUser(objectUser)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `key "blue" must be read from a secret`)
+		objectUser.Spec.Keys = nil
+		objectUser.Spec.SuppressUserKeys = true
+		assert.Error(t, ValidateUser(objectUser))
+	})
+}
+
+func TestFailureNamesAdminTarget(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return "", errors.New("failed to connect to the cluster")
+			}
+			return "", nil
+		},
+	}
+	monEndpoints := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mon-endpoints", Namespace: namespace},
+		Data:       map[string]string{"data": "a=10.0.0.1:6789"},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser, monEndpoints)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.Error(t, err)
+	target := `radosgw-admin ran against realm "my-store" of the ceph cluster in namespace "rook-ceph" with mons a=10.0.0.1:6789`
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Contains(t, findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure).Message, target)
+	event := <-r.recorder.(*record.FakeRecorder).Events
+	assert.Contains(t, event, "Warning CephUserFailed")
+	assert.Contains(t, event, target)
+}
+
+func TestSecretRecreated(t *testing.T) {
+	userExists := false
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" && userExists {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	secretName := types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}
+
+	_, err := r.Reconcile(req)
+	assert.NoError(t, err)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), secretName, secret)
+	assert.NoError(t, err)
+
+	// the secret is deleted while the user is ready
+	userExists = true
+	err = r.client.Delete(context.TODO(), secret)
+	assert.NoError(t, err)
+
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	secret = &corev1.Secret{}
+	err = r.client.Get(context.TODO(), secretName, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "EOE7FYCNOBZJ5VFV909G", secret.StringData["AccessKey"])
+	assert.Equal(t, "qmIqpWm8HxCzmynCrD6U6vKWi4hnDBndOnmxXNsV", secret.StringData["SecretKey"])
+}
+
+func TestSecretHash(t *testing.T) {
+	secretKey := "qmIqpWm8HxCzmynCrD6U6vKWi4hnDBndOnmxXNsV"
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" {
+				return strings.Replace(userCreateJSON, "qmIqpWm8HxCzmynCrD6U6vKWi4hnDBndOnmxXNsV", secretKey, 1), nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	secretName := types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}
+
+	// the secret and the status carry the hash of the data of the secret
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), secretName, secret)
+	assert.NoError(t, err)
+	hash := secret.Annotations[secretHashAnnotation]
+	assert.Equal(t, secretDataHash(secret.StringData), hash)
+	assert.Len(t, hash, 64)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, objectUser.Status.SecretHash)
+
+	// the hash is stable while the keys do not change
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	secret = &corev1.Secret{}
+	err = r.client.Get(context.TODO(), secretName, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, secret.Annotations[secretHashAnnotation])
+
+	// a rotated key changes the hash
+	secretKey = "rotated"
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	secret = &corev1.Secret{}
+	err = r.client.Get(context.TODO(), secretName, secret)
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, secret.Annotations[secretHashAnnotation])
+	assert.Equal(t, secretDataHash(secret.StringData), secret.Annotations[secretHashAnnotation])
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, secret.Annotations[secretHashAnnotation], objectUser.Status.SecretHash)
+}
+
+func TestDisableDriftCorrection(t *testing.T) {
+	var modifyArgs []string
+	liveDisplayName := "my-user"
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" && args[1] == "modify" {
+				modifyArgs = args
+			}
+			if args[0] == "user" {
+				return strings.Replace(userCreateJSON, `"display_name": "my-user"`, fmt.Sprintf(`"display_name": %q`, liveDisplayName), 1), nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Annotations = map[string]string{disableDriftCorrectionAnnotation: "true"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Nil(t, modifyArgs)
+
+	// the display name is changed by hand and not corrected on resync
+	liveDisplayName = "changed-by-hand"
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Nil(t, modifyArgs)
+
+	// a spec change corrects the drift
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	objectUser.Spec.Email = "my-user@example.com"
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user", "modify", "--uid", name, "--display-name", name, "--email", "my-user@example.com"}, modifyArgs[:8])
+}
+
+func TestSpecHashStoreDefaults(t *testing.T) {
+	var modifyArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" && args[1] == "modify" {
+				modifyArgs = args
+			}
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	// the drift of the user is not corrected while its spec is unchanged
+	objectUser := newObjectUser()
+	objectUser.Annotations = map[string]string{disableDriftCorrectionAnnotation: "true"}
+	objectUser.Status = &cephv1.ObjectStoreUserStatus{ObservedSpecHash: hashUserSpec(objectUser)}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Nil(t, modifyArgs)
+
+	// a new default of the object store changes the spec of its users
+	objectStore := &cephv1.CephObjectStore{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: store, Namespace: namespace}, objectStore)
+	assert.NoError(t, err)
+	objectStore.Spec.DefaultUserQuotas = &cephv1.ObjectUserQuotaSpec{MaxBuckets: &[]int{5}[0]}
+	err = r.client.Update(context.TODO(), objectStore)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Contains(t, strings.Join(modifyArgs, " "), "--max-buckets 5")
+}
+
+func TestForceResync(t *testing.T) {
+	var modifyArgs []string
+	liveDisplayName := "changed-by-hand"
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" && args[1] == "modify" {
+				modifyArgs = args
+			}
+			if args[0] == "user" {
+				return strings.Replace(userCreateJSON, `"display_name": "my-user"`, fmt.Sprintf(`"display_name": %q`, liveDisplayName), 1), nil
+			}
+			return "", nil
+		},
+	}
+	// the drift of the user is only corrected when a resync is forced
+	objectUser := newObjectUser()
+	objectUser.Annotations = map[string]string{disableDriftCorrectionAnnotation: "true"}
+	objectUser.Status = &cephv1.ObjectStoreUserStatus{ObservedSpecHash: hashUserSpec(objectUser)}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Nil(t, modifyArgs)
+
+	forceResync := func(value string) {
+		err := r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		objectUser.Annotations[forceResyncAnnotation] = value
+		err = r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+	}
+	forceResync("2020-05-04T10:00:00Z")
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user", "modify", "--uid", name, "--display-name", name}, modifyArgs[:6])
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, "2020-05-04T10:00:00Z", objectUser.Status.ObservedForceResync)
+
+	// the same value only triggers once
+	modifyArgs = nil
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Nil(t, modifyArgs)
+
+	// a new value triggers again
+	forceResync("2020-05-04T11:00:00Z")
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.NotNil(t, modifyArgs)
+}
+
+func TestMCSecretFormat(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.SecretFormat = "mc"
+	r := newReadyReconciler(executor, objectUser)
+	objectStore := &cephv1.CephObjectStore{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: store, Namespace: namespace}, objectStore)
+	assert.NoError(t, err)
+	objectStore.Spec.Gateway.Port = 80
+	err = r.client.Update(context.TODO(), objectStore)
+	assert.NoError(t, err)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+
+	config := mcConfig{}
+	err = json.Unmarshal([]byte(secret.StringData["config.json"]), &config)
+	assert.NoError(t, err)
+	assert.Equal(t, "10", config.Version)
+	assert.Equal(t, mcAliasConfig{
+		URL:       "http://rook-ceph-rgw-my-store.rook-ceph:80",
+		AccessKey: "EOE7FYCNOBZJ5VFV909G",
+		SecretKey: "qmIqpWm8HxCzmynCrD6U6vKWi4hnDBndOnmxXNsV",
+		API:       "s3v4",
+		Path:      "auto",
+	}, config.Aliases[store])
+	assert.Equal(t, "EOE7FYCNOBZJ5VFV909G", secret.StringData["AccessKey"])
+
+	objectUser.Spec.SecretFormat = "aws"
+	assert.Error(t, ValidateUser(objectUser))
+	objectUser.Spec.SecretFormat = "mc"
+	objectUser.Spec.SuppressUserKeys = true
+	assert.Error(t, ValidateUser(objectUser))
+}
+
+func TestObjectStoreEndpoint(t *testing.T) {
+	objectStore := &cephv1.CephObjectStore{ObjectMeta: metav1.ObjectMeta{Name: store, Namespace: namespace}}
+	objectStore.Spec.Gateway.SecurePort = 443
+	assert.Equal(t, "https://rook-ceph-rgw-my-store.rook-ceph:443", objectStoreEndpoint(objectStore))
+	objectStore.Spec.Gateway.Port = 8080
+	assert.Equal(t, "http://rook-ceph-rgw-my-store.rook-ceph:8080", objectStoreEndpoint(objectStore))
+	objectStore.Spec.Gateway.ExternalEndpoint = "https://rgw.example.com"
+	assert.Equal(t, "https://rgw.example.com", objectStoreEndpoint(objectStore))
+}
+
+func TestNamedKeys(t *testing.T) {
+	liveKeys := []string{"AK1"}
+	var removed []string
+	userJSON := func() string {
+		keys := []string{}
+		for _, key := range liveKeys {
+			keys = append(keys, fmt.Sprintf(`{"user": "my-user", "access_key": %q, "secret_key": "secret-%s"}`, key, key))
+		}
+		return fmt.Sprintf(`{"user_id": "my-user", "display_name": "my-user", "keys": [%s]}`, strings.Join(keys, ","))
+	}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "key" && args[1] == "create" {
+				liveKeys = append(liveKeys, fmt.Sprintf("AK%d", len(liveKeys)+1))
+			}
+			if args[0] == "key" && args[1] == "rm" {
+				removed = append(removed, args[7])
+				return "", nil
+			}
+			return userJSON(), nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "blue"}, {Name: "green"}}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	secretName := types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}
+
+	// the existing key is adopted and a missing key is generated
+	_, err := r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AK1", "AK2"}, liveKeys)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []cephv1.ObjectUserKeyStatus{{Name: "blue", AccessKey: "AK1"}, {Name: "green", AccessKey: "AK2"}}, objectUser.Status.Keys)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), secretName, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "AK1", secret.StringData["AccessKey"])
+	assert.Equal(t, "green", secret.StringData["KeyName_1"])
+	assert.Equal(t, "AK2", secret.StringData["AccessKey_1"])
+	assert.Equal(t, "secret-AK2", secret.StringData["SecretKey_1"])
+
+	// the key that is no longer desired is removed
+	objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "green"}}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AK1"}, removed)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []cephv1.ObjectUserKeyStatus{{Name: "green", AccessKey: "AK2"}}, objectUser.Status.Keys)
+
+	objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "green"}, {Name: "green"}}
+	assert.Error(t, ValidateUser(objectUser))
+}
+
+func TestLastKeyGuard(t *testing.T) {
+	keySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-key", Namespace: namespace},
+		Data:       map[string][]byte{"AccessKey": []byte("REF"), "SecretKey": []byte("secret-REF")},
+	}
+	tests := []struct {
+		name     string
+		taken    bool
+		removed  []string
+		expected []string
+	}{
+		{"replacement in rgw", true, []string{"AK1"}, []string{"REF"}},
+		{"replacement not in rgw", false, nil, []string{"AK1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			liveKeys := []string{"AK1"}
+			var removed []string
+			userJSON := func() string {
+				keys := []string{}
+				for _, key := range liveKeys {
+					keys = append(keys, fmt.Sprintf(`{"user": "my-user", "access_key": %q, "secret_key": "secret-%s"}`, key, key))
+				}
+				return fmt.Sprintf(`{"user_id": "my-user", "display_name": "my-user", "keys": [%s]}`, strings.Join(keys, ","))
+			}
+			executor := &exectest.MockExecutor{
+				MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+					if args[0] == "user" && args[1] == "create" {
+						return userExistsOutput, nil
+					}
+					if args[0] == "key" && args[1] == "create" && test.taken {
+						liveKeys = append(liveKeys, args[7])
+					}
+					if args[0] == "key" && args[1] == "rm" {
+						removed = append(removed, args[7])
+						liveKeys = liveKeys[1:]
+						return "", nil
+					}
+					return userJSON(), nil
+				},
+			}
+			objectUser := newObjectUser()
+			objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "blue", SecretName: "my-key"}}
+			r := newReadyReconciler(executor, objectUser, keySecret)
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+			// the only key of the user is replaced by the key of the secret, but only once RGW has it
+			_, err := r.reconcile(req)
+			if test.taken {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "refusing to remove the last key")
+			}
+			assert.Equal(t, test.removed, removed)
+			assert.Equal(t, test.expected, liveKeys)
+		})
+	}
+}
+
+func TestAdopt(t *testing.T) {
+	var keyCommands, modified [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "key" {
+				keyCommands = append(keyCommands, args)
+			}
+			if args[0] == "user" && args[1] == "modify" {
+				modified = append(modified, args)
+			}
+			return `{"user_id": "my-user", "display_name": "legacy", "keys": [
+				{"user": "my-user", "access_key": "AK1", "secret_key": "secret-AK1"},
+				{"user": "my-user", "access_key": "AK2", "secret_key": "secret-AK2"}]}`, nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Adopt = true
+	objectUser.Spec.DisplayName = "adopted"
+	objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "blue"}, {Name: "green"}, {Name: "red"}}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	// the keys are neither generated nor removed, the display name is still updated
+	assert.Nil(t, keyCommands)
+	assert.Len(t, modified, 1)
+	assert.Contains(t, modified[0], "adopted")
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "AK1", secret.StringData["AccessKey"])
+	assert.Equal(t, "secret-AK1", secret.StringData["SecretKey"])
+	assert.Equal(t, "AK2", secret.StringData["AccessKey_1"])
+	assert.NotContains(t, secret.StringData, "AccessKey_2")
+
+	objectUser.Spec.SuppressUserKeys = true
+	objectUser.Spec.Keys = nil
+	assert.Error(t, ValidateUser(objectUser))
+}
+
+func TestAdoptKeepsUnsetFields(t *testing.T) {
+	var modified [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "subuser" || (args[0] == "user" && args[1] == "modify") {
+				modified = append(modified, args)
+			}
+			return `{"user_id": "my-user", "display_name": "legacy", "email": "legacy@example.com",
+				"subusers": [{"id": "my-user:swift", "permissions": "read"}],
+				"keys": [{"user": "my-user", "access_key": "AK1", "secret_key": "secret-AK1"}]}`, nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Adopt = true
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the display name, email and subuser the spec does not set are kept
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, modified)
+
+	// those it sets are applied
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	objectUser.Spec.Email = "new@example.com"
+	objectUser.Spec.Subusers = []cephv1.SubuserSpec{{Name: "s3", Access: cephv1.AccessSpecRead}}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	commands := []string{}
+	for _, args := range modified {
+		commands = append(commands, strings.Join(args, " "))
+	}
+	assert.Contains(t, strings.Join(commands, "\n"), "--email new@example.com")
+	assert.NotContains(t, strings.Join(commands, "\n"), "--display-name")
+	assert.Contains(t, strings.Join(commands, "\n"), "subuser create --uid my-user --subuser my-user:s3")
+	assert.Contains(t, strings.Join(commands, "\n"), "subuser rm --uid my-user --subuser my-user:swift")
+}
+
+func TestDryRun(t *testing.T) {
+	var mutations [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			// the user does not exist yet
+			if args[0] == "user" && args[1] == "info" {
+				return "", nil
+			}
+			if args[0] == "zone" && args[1] == "get" {
+				return "", nil
+			}
+			mutations = append(mutations, args)
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Annotations = map[string]string{dryRunAnnotation: "true"}
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxBuckets: &[]int{5}[0]}
+	objectUser.Spec.Subusers = []cephv1.SubuserSpec{{Name: "swift", Access: cephv1.AccessSpecRead}}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, mutations)
+
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseValidated, objectUser.Status.Phase)
+	assert.Equal(t, []string{"user=created", "displayName=my-user", "accessKey=<redacted>", "secretKey=<redacted>", "maxBuckets=5", "subuser=my-user:swift:read"}, objectUser.Status.PlannedChanges)
+
+	// the secret of the user is not created
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.True(t, kerrors.IsNotFound(err))
+
+	for _, created := range []bool{false, true} {
+		t.Run(fmt.Sprintf("deletion of a created user %t", created), func(t *testing.T) {
+			deletes := 0
+			executor := &exectest.MockExecutor{
+				MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+					if args[0] == "user" && args[1] == "rm" {
+						deletes++
+					}
+					return "", nil
+				},
+			}
+			objectUser := newObjectUser()
+			objectUser.Annotations = map[string]string{dryRunAnnotation: "true"}
+			objectUser.Finalizers = []string{"cephobjectstoreuser.ceph.rook.io"}
+			objectUser.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			if created {
+				// the user was reconciled before the dry run was annotated
+				objectUser.Status = &cephv1.ObjectStoreUserStatus{UID: name}
+			}
+			r := newReadyReconciler(executor, objectUser)
+
+			_, err := r.reconcile(req)
+			assert.NoError(t, err)
+			if created {
+				assert.Equal(t, 1, deletes)
+			} else {
+				assert.Equal(t, 0, deletes)
+			}
+			objectUser = &cephv1.CephObjectStoreUser{}
+			err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+			assert.NoError(t, err)
+			assert.Empty(t, objectUser.Finalizers)
+		})
+	}
+}
+
+func TestKeyRefs(t *testing.T) {
+	liveKeys := []string{"AK1"}
+	var mutations []string
+	userJSON := func() string {
+		keys := []string{}
+		for _, key := range liveKeys {
+			keys = append(keys, fmt.Sprintf(`{"user": "my-user", "access_key": %q, "secret_key": "secret-%s"}`, key, key))
+		}
+		return fmt.Sprintf(`{"user_id": "my-user", "display_name": "my-user", "keys": [%s]}`, strings.Join(keys, ","))
+	}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "info" {
+				return userJSON(), nil
+			}
+			if args[0] == "bucket" && args[1] == "list" {
+				return "[]", nil
+			}
+			if args[0] == "zone" && args[1] == "get" {
+				return "", nil
+			}
+			mutations = append(mutations, strings.Join(args[:2], " "))
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "key" && args[1] == "create" {
+				liveKeys = append(liveKeys, args[7])
+			}
+			if args[0] == "key" && args[1] == "rm" {
+				liveKeys = liveKeys[1:]
+				return "", nil
+			}
+			return userJSON(), nil
+		},
+	}
+	keySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-key", Namespace: namespace},
+		Data:       map[string][]byte{"AccessKey": []byte("REF"), "SecretKey": []byte("secret-REF")},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "blue", SecretName: "my-key", SHA256: "0000"}}
+	r := newReadyReconciler(executor, objectUser, keySecret)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// a mismatched hash fails before anything is changed in RGW
+	_, err := r.reconcile(req)
+	assert.Error(t, err)
+	_, ok := errors.Cause(err).(*KeyIntegrityError)
+	assert.True(t, ok)
+	assert.Empty(t, mutations)
+
+	// the verified key is set and the other key is removed
+	sum := sha256.Sum256([]byte("REF:secret-REF"))
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	objectUser.Spec.Keys[0].SHA256 = hex.EncodeToString(sum[:])
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user create", "key create", "key rm"}, mutations)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []cephv1.ObjectUserKeyStatus{{Name: "blue", AccessKey: "REF"}}, objectUser.Status.Keys)
+
+	// the key is tracked once RGW has it, and the resynced user is not changed
+	mutations = nil
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, mutations)
+}
+
+func TestPhaseConditions(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	cephCluster := &cephv1.CephCluster{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: namespace, Namespace: namespace}, cephCluster)
+	assert.NoError(t, err)
+
+	// the wait for the CephCluster is reported while reconciling
+	cephCluster.Status.Phase = ""
+	err = r.client.Update(context.TODO(), cephCluster)
+	assert.NoError(t, err)
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconciling, objectUser.Status.Phase)
+	progressing := findCondition(objectUser.Status.Conditions, cephv1.ConditionProgressing)
+	assert.Equal(t, corev1.ConditionTrue, progressing.Status)
+	assert.Equal(t, reasonCephClusterNotReady, progressing.Reason)
+
+	// the wait remains visible once the user is ready
+	cephCluster.Status.Phase = k8sutil.ReadyStatus
+	err = r.client.Update(context.TODO(), cephCluster)
+	assert.NoError(t, err)
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	assert.Equal(t, corev1.ConditionTrue, findCondition(objectUser.Status.Conditions, cephv1.ConditionReady).Status)
+	assert.Equal(t, corev1.ConditionFalse, findCondition(objectUser.Status.Conditions, cephv1.ConditionProgressing).Status)
+	assert.Equal(t, corev1.ConditionFalse, findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure).Status)
+
+	// a retry after a failure is reconciling, the phase follows the conditions
+	setPhase(objectUser, cephv1.ObjectStoreUserPhaseReconcileFailed, reasonCephUserFailed, "failed")
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+	setPhase(objectUser, cephv1.ObjectStoreUserPhaseReconciling, reasonReconciling, "retrying")
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconciling, objectUser.Status.Phase)
+	setPhase(objectUser, cephv1.ObjectStoreUserPhaseValidated, reasonValidated, "validated")
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseValidated, objectUser.Status.Phase)
+}
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		name     string
+		caps     cephv1.ObjectUserCapSpec
+		liveCaps map[string]string
+		expected [][]string
+	}{
+		{"user read,write", cephv1.ObjectUserCapSpec{User: "read,write"}, nil, [][]string{{"caps", "add", "--uid", name, "--caps", "users=*"}}},
+		{"user *", cephv1.ObjectUserCapSpec{User: "*"}, nil, [][]string{{"caps", "add", "--uid", name, "--caps", "users=*"}}},
+		{"bucket read, write", cephv1.ObjectUserCapSpec{Bucket: "read, write"}, nil, [][]string{{"caps", "add", "--uid", name, "--caps", "buckets=*"}}},
+		{"bucket *", cephv1.ObjectUserCapSpec{Bucket: "*"}, nil, [][]string{{"caps", "add", "--uid", name, "--caps", "buckets=*"}}},
+		{"roles for sts", cephv1.ObjectUserCapSpec{Roles: "*"}, nil, [][]string{{"caps", "add", "--uid", name, "--caps", "roles=*"}}},
+		{"read,write is up to date", cephv1.ObjectUserCapSpec{User: "read,write", Bucket: "read"}, map[string]string{"users": "*", "buckets": "read"}, nil},
+		{"changed perms are added before the surplus is removed", cephv1.ObjectUserCapSpec{User: "read", Bucket: "*"}, map[string]string{"users": "*", "buckets": "read", "zone": "read"}, [][]string{
+			{"caps", "add", "--uid", name, "--caps", "buckets=write"},
+			{"caps", "rm", "--uid", name, "--caps", "users=write"},
+		}},
+		{"read replaced by write", cephv1.ObjectUserCapSpec{Usage: "write"}, map[string]string{"usage": "read"}, [][]string{
+			{"caps", "add", "--uid", name, "--caps", "usage=write"},
+			{"caps", "rm", "--uid", name, "--caps", "usage=read"},
+		}},
+		{"mixed perms", cephv1.ObjectUserCapSpec{User: "*", Bucket: "read"}, nil, [][]string{{"caps", "add", "--uid", name, "--caps", "users=*;buckets=read"}}},
+		{"only the changed user cap is updated", cephv1.ObjectUserCapSpec{User: "write", Bucket: "read"}, map[string]string{"users": "read", "buckets": "read"}, [][]string{
+			{"caps", "add", "--uid", name, "--caps", "users=write"},
+			{"caps", "rm", "--uid", name, "--caps", "users=read"},
+		}},
+		{"only the changed user cap is updated with mixed perms", cephv1.ObjectUserCapSpec{User: "read", Bucket: "*"}, map[string]string{"users": "*", "buckets": "*"}, [][]string{
+			{"caps", "rm", "--uid", name, "--caps", "users=write"},
+		}},
+		{"unchanged cap is kept while a cap is added", cephv1.ObjectUserCapSpec{User: "read", Bucket: "read"}, map[string]string{"users": "read"}, [][]string{
+			{"caps", "add", "--uid", name, "--caps", "buckets=read"},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var capsArgs [][]string
+			executor := &exectest.MockExecutor{
+				MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+					if args[0] == "user" && args[1] == "create" {
+						return userExistsOutput, nil
+					}
+					if args[0] == "caps" {
+						capsArgs = append(capsArgs, args[:6])
+						test.liveCaps = applyCaps(test.liveCaps, args[1], args[5])
+						return "", nil
+					}
+					return capsUserJSON(test.liveCaps), nil
+				},
+			}
+			objectUser := newObjectUser()
+			objectUser.Spec.Capabilities = &test.caps
+			r := newReadyReconciler(executor, objectUser)
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+			_, err := r.reconcile(req)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, capsArgs)
+			for capType, perm := range specCaps(&test.caps) {
+				if perm != "" {
+					assert.Equal(t, normalizeCapPerm(perm), test.liveCaps[capType], capType)
+				}
+			}
+		})
+	}
+
+	objectUser := newObjectUser()
+	objectUser.Spec.Capabilities = &cephv1.ObjectUserCapSpec{User: "read,write"}
+	assert.NoError(t, ValidateUser(objectUser))
+	objectUser.Spec.Capabilities = &cephv1.ObjectUserCapSpec{Bucket: "read,delete"}
+	assert.Error(t, ValidateUser(objectUser))
+}
+
+func TestDiffCaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing map[string]string
+		desired  map[string]string
+		toAdd    []string
+		toRemove []string
+	}{
+		{"add only", map[string]string{"users": "read"}, map[string]string{"users": "*", "usage": "read"}, []string{"users=write", "usage=read"}, []string{}},
+		{"remove only", map[string]string{"users": "*", "buckets": "read, write"}, map[string]string{"users": "read", "buckets": "write"}, []string{}, []string{"users=write", "buckets=read"}},
+		{"mixed", map[string]string{"users": "read", "usage": "*"}, map[string]string{"users": "write", "usage": "read", "info": "read"}, []string{"users=write", "info=read"}, []string{"users=read", "usage=write"}},
+		{"unchanged", map[string]string{"users": "read, write"}, map[string]string{"users": "*"}, []string{}, []string{}},
+		{"undesired types are kept", map[string]string{"zone": "*"}, map[string]string{}, []string{}, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			toAdd, toRemove := diffCaps(test.existing, test.desired)
+			assert.Equal(t, test.toAdd, toAdd)
+			assert.Equal(t, test.toRemove, toRemove)
+		})
+	}
+}
+
+func TestCapabilityPresets(t *testing.T) {
+	tests := []struct {
+		name     string
+		caps     *cephv1.ObjectUserCapSpec
+		expected [][]string
+	}{
+		{"monitoring", nil, [][]string{{"caps", "add", "--uid", name, "--caps", "metadata=read;usage=read;info=read"}}},
+		{"overridden", &cephv1.ObjectUserCapSpec{Usage: "*", Bucket: "read"}, [][]string{{"caps", "add", "--uid", name, "--caps", "buckets=read;metadata=read;usage=*;info=read"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			liveCaps := map[string]string{}
+			var capsArgs [][]string
+			executor := &exectest.MockExecutor{
+				MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+					if args[0] == "user" && args[1] == "create" {
+						return userExistsOutput, nil
+					}
+					if args[0] == "caps" {
+						capsArgs = append(capsArgs, args[:6])
+						liveCaps = applyCaps(liveCaps, args[1], args[5])
+						return "", nil
+					}
+					return capsUserJSON(liveCaps), nil
+				},
+			}
+			objectUser := newObjectUser()
+			objectUser.Spec.Preset = cephv1.ObjectUserPresetMonitoring
+			objectUser.Spec.Capabilities = test.caps
+			r := newReadyReconciler(executor, objectUser)
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+			_, err := r.reconcile(req)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, capsArgs)
+		})
+	}
+
+	objectUser := newObjectUser()
+	objectUser.Spec.Preset = "admin"
+	assert.Error(t, ValidateUser(objectUser))
+}
+
+func TestCapabilitiesCleared(t *testing.T) {
+	liveCaps := map[string]string{}
+	var capsArgs [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "caps" {
+				capsArgs = append(capsArgs, args[:6])
+				liveCaps = applyCaps(liveCaps, args[1], args[5])
+				return "", nil
+			}
+			return capsUserJSON(liveCaps), nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Capabilities = &cephv1.ObjectUserCapSpec{User: "*", Bucket: "read"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"caps", "add", "--uid", name, "--caps", "users=*;buckets=read"}}, capsArgs)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"users", "buckets"}, objectUser.Status.Capabilities)
+
+	// clearing the whole block removes the caps that were set
+	capsArgs = nil
+	objectUser.Spec.Capabilities = nil
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"caps", "rm", "--uid", name, "--caps", "buckets=read;users=*"}}, capsArgs)
+	assert.Empty(t, liveCaps)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Nil(t, objectUser.Status.Capabilities)
+
+	// the caps of a user without the block are left untouched
+	capsArgs = nil
+	liveCaps = map[string]string{"usage": "read"}
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Nil(t, capsArgs)
+}
+
+func TestMaxBuckets(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	tests := []struct {
+		name       string
+		maxBuckets int
+		expected   string
+	}{
+		{"unlimited", -1, "0"},
+		{"disabled", 0, "-1"},
+		{"limited", 20, "20"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var modifyArgs []string
+			liveMaxBuckets := "1000"
+			executor := &exectest.MockExecutor{
+				MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+					if args[0] == "user" && args[1] == "modify" {
+						modifyArgs = args
+						liveMaxBuckets = args[5]
+					}
+					if args[0] == "user" {
+						return strings.Replace(userCreateJSON, `"max_buckets": 1000`, `"max_buckets": `+liveMaxBuckets, 1), nil
+					}
+					return "", nil
+				},
+			}
+			objectUser := newObjectUser()
+			objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(test.maxBuckets)}
+			r := newReadyReconciler(executor, objectUser)
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+			_, err := r.reconcile(req)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"user", "modify", "--uid", name, "--max-buckets", test.expected}, modifyArgs[:6])
+
+			// the status reports the max buckets of RGW as in the spec
+			updated := &cephv1.CephObjectStoreUser{}
+			err = r.client.Get(context.TODO(), req.NamespacedName, updated)
+			assert.NoError(t, err)
+			assert.Equal(t, test.maxBuckets, updated.Status.EffectiveQuota.MaxBuckets)
+
+			// the matching live value is not modified again
+			modifyArgs = nil
+			_, err = r.reconcile(req)
+			assert.NoError(t, err)
+			assert.Nil(t, modifyArgs)
+		})
+	}
+
+	// without a max buckets the RGW default applies
+	var userArgs [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				userArgs = append(userArgs, args)
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	r := newReadyReconciler(executor, newObjectUser())
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, userArgs)
+	for _, args := range userArgs {
+		assert.NotContains(t, args, "--max-buckets")
+	}
+	updated := &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, updated.Status.EffectiveQuota.MaxBuckets)
+
+	// an unlimited user is limited to the maximum of the store
+	result, clamped := clampQuotas(&cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(-1)}, &cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(10)})
+	assert.Equal(t, 10, *result.MaxBuckets)
+	assert.Equal(t, []string{"maxBuckets from -1 to 10"}, clamped)
+	result, clamped = clampQuotas(&cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(0)}, &cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(10)})
+	assert.Equal(t, 0, *result.MaxBuckets)
+	assert.Empty(t, clamped)
+	result, clamped = clampQuotas(&cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(50)}, &cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(-1)})
+	assert.Equal(t, 50, *result.MaxBuckets)
+	assert.Empty(t, clamped)
+
+	objectUser := newObjectUser()
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxBuckets: intPtr(-2)}
+	assert.Error(t, ValidateUser(objectUser))
+}
+
+func TestSubuserDiff(t *testing.T) {
+	var subuserArgs [][]string
+	liveSubusers := map[string]string{"my-user:old": "read"}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "subuser" {
+				subuserArgs = append(subuserArgs, args)
+				switch args[1] {
+				case "create", "modify":
+					liveSubusers[args[5]] = map[string]string{"read": "read", "write": "write"}[args[7]]
+				case "rm":
+					delete(liveSubusers, args[5])
+				}
+				return "", nil
+			}
+			subusers := []string{}
+			for id, permissions := range liveSubusers {
+				subusers = append(subusers, fmt.Sprintf(`{"id": %q, "permissions": %q}`, id, permissions))
+			}
+			return strings.Replace(userCreateJSON, `"subusers": []`, fmt.Sprintf(`"subusers": [%s]`, strings.Join(subusers, ",")), 1), nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Subusers = []cephv1.SubuserSpec{{Name: "swift", Access: cephv1.AccessSpecRead}}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the missing subuser is created and the one that is not in the spec is removed
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"subuser", "create", "--uid", name, "--subuser", "my-user:swift", "--access", "read"},
+		{"subuser", "rm", "--uid", name, "--subuser", "my-user:old", "--purge-keys"},
+	}, trimSubuserArgs(subuserArgs))
+
+	// reconciling the same subuser again makes no calls
+	subuserArgs = nil
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Nil(t, subuserArgs)
+
+	// changing the access is a single modify
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	objectUser.Spec.Subusers[0].Access = cephv1.AccessSpecWrite
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"subuser", "modify", "--uid", name, "--subuser", "my-user:swift", "--access", "write"}}, trimSubuserArgs(subuserArgs))
+}
+
+// trimSubuserArgs drops the realm and zone group flags appended to the radosgw-admin commands
+func trimSubuserArgs(commands [][]string) [][]string {
+	trimmed := [][]string{}
+	for _, args := range commands {
+		for i, arg := range args {
+			if strings.HasPrefix(arg, "--rgw-realm") {
+				args = args[:i]
+				break
+			}
+		}
+		trimmed = append(trimmed, args)
+	}
+	return trimmed
+}
+
+func TestSwiftKeys(t *testing.T) {
+	swiftKeys := map[string]string{}
+	var keyArgs [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "key" && args[1] == "create" {
+				keyArgs = append(keyArgs, args[:9])
+				secretKey := "generated"
+				if args[8] == "--secret-key" {
+					secretKey = args[9]
+				}
+				swiftKeys[args[5]] = secretKey
+			}
+			keys := []string{}
+			for id, secretKey := range swiftKeys {
+				keys = append(keys, fmt.Sprintf(`{"user": %q, "secret_key": %q}`, id, secretKey))
+			}
+			return fmt.Sprintf(`{"user_id": "my-user", "display_name": "my-user", "keys": [{"user": "my-user", "access_key": "AK", "secret_key": "SK"}], "subusers": [{"id": "my-user:gen", "permissions": "read"}, {"id": "my-user:ref", "permissions": "read"}], "swift_keys": [%s]}`, strings.Join(keys, ",")), nil
+		},
+	}
+	keySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-swift-key", Namespace: namespace},
+		Data:       map[string][]byte{"SecretKey": []byte("provided")},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Subusers = []cephv1.SubuserSpec{
+		{Name: "gen", Access: cephv1.AccessSpecRead, GenerateKey: true},
+		{Name: "ref", Access: cephv1.AccessSpecRead, SecretName: "my-swift-key"},
+	}
+	r := newReadyReconciler(executor, objectUser, keySecret)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"key", "create", "--uid", name, "--subuser", "my-user:gen", "--key-type", "swift", "--gen-secret"},
+		{"key", "create", "--uid", name, "--subuser", "my-user:ref", "--key-type", "swift", "--secret-key"},
+	}, keyArgs)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-user:gen", secret.StringData["SwiftUser_0"])
+	assert.Equal(t, "generated", secret.StringData["SwiftKey_0"])
+	assert.Equal(t, "my-user:ref", secret.StringData["SwiftUser_1"])
+	assert.Equal(t, "provided", secret.StringData["SwiftKey_1"])
+
+	// the keys are not set again
+	keyArgs = nil
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Nil(t, keyArgs)
+
+	objectUser.Spec.Subusers[0].SecretName = "my-swift-key"
+	assert.Error(t, ValidateUser(objectUser))
+}
+
+func TestSubuserKeyType(t *testing.T) {
+	newExecutor := func(keyArgs *[][]string) *exectest.MockExecutor {
+		swiftKeys := map[string]string{}
+		s3Keys := map[string][2]string{}
+		return &exectest.MockExecutor{
+			MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+				if args[0] == "user" && args[1] == "create" {
+					return userExistsOutput, nil
+				}
+				if args[0] == "key" && args[1] == "create" {
+					*keyArgs = append(*keyArgs, args[:indexOf(args, "--rgw-realm=my-store")])
+					switch {
+					case args[7] == "swift":
+						swiftKeys[args[5]] = "generated"
+					case args[8] == "--access-key":
+						s3Keys[args[5]] = [2]string{args[9], args[11]}
+					default:
+						s3Keys[args[5]] = [2]string{"SUBAK", "SUBSK"}
+					}
+				}
+				keys := []string{`{"user": "my-user", "access_key": "AK", "secret_key": "SK"}`}
+				for id, key := range s3Keys {
+					keys = append(keys, fmt.Sprintf(`{"user": %q, "access_key": %q, "secret_key": %q}`, id, key[0], key[1]))
+				}
+				swift := []string{}
+				for id, secretKey := range swiftKeys {
+					swift = append(swift, fmt.Sprintf(`{"user": %q, "secret_key": %q}`, id, secretKey))
+				}
+				return fmt.Sprintf(`{"user_id": "my-user", "display_name": "my-user", "keys": [%s], "subusers": [{"id": "my-user:sub", "permissions": "read"}], "swift_keys": [%s]}`, strings.Join(keys, ","), strings.Join(swift, ",")), nil
+			},
+		}
+	}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	getSecret := func(r *ReconcileObjectStoreUser) *corev1.Secret {
+		secret := &corev1.Secret{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+		assert.NoError(t, err)
+		return secret
+	}
+
+	t.Run("s3 subuser", func(t *testing.T) {
+		var keyArgs [][]string
+		objectUser := newObjectUser()
+		objectUser.Spec.Subusers = []cephv1.SubuserSpec{{Name: "sub", Access: cephv1.AccessSpecRead, KeyType: cephv1.UserKeyTypeS3, GenerateKey: true}}
+		r := newReadyReconciler(newExecutor(&keyArgs), objectUser)
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"key", "create", "--uid", name, "--subuser", "my-user:sub", "--key-type", "s3", "--gen-access-key", "--gen-secret"}}, keyArgs)
+		secret := getSecret(r)
+		assert.Equal(t, "my-user:sub", secret.StringData["Subuser_0"])
+		assert.Equal(t, "SUBAK", secret.StringData["SubuserAccessKey_0"])
+		assert.Equal(t, "SUBSK", secret.StringData["SubuserSecretKey_0"])
+		assert.NotContains(t, secret.StringData, "SwiftUser_0")
+		// the key of the subuser is not taken for a top-level key
+		assert.Equal(t, "AK", secret.StringData["AccessKey"])
+
+		// the key is not created again
+		keyArgs = nil
+		_, err = r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Nil(t, keyArgs)
+	})
+
+	t.Run("s3 subuser from secret", func(t *testing.T) {
+		var keyArgs [][]string
+		keySecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-subuser-key", Namespace: namespace},
+			Data:       map[string][]byte{"AccessKey": []byte("PROVIDEDAK"), "SecretKey": []byte("PROVIDEDSK")},
+		}
+		objectUser := newObjectUser()
+		objectUser.Spec.Subusers = []cephv1.SubuserSpec{{Name: "sub", Access: cephv1.AccessSpecRead, KeyType: cephv1.UserKeyTypeS3, SecretName: "my-subuser-key"}}
+		r := newReadyReconciler(newExecutor(&keyArgs), objectUser, keySecret)
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"key", "create", "--uid", name, "--subuser", "my-user:sub", "--key-type", "s3", "--access-key", "PROVIDEDAK", "--secret-key", "PROVIDEDSK"}}, keyArgs)
+		secret := getSecret(r)
+		assert.Equal(t, "PROVIDEDAK", secret.StringData["SubuserAccessKey_0"])
+		assert.Equal(t, "PROVIDEDSK", secret.StringData["SubuserSecretKey_0"])
+	})
+
+	t.Run("swift subuser", func(t *testing.T) {
+		var keyArgs [][]string
+		objectUser := newObjectUser()
+		objectUser.Spec.Subusers = []cephv1.SubuserSpec{{Name: "sub", Access: cephv1.AccessSpecRead, KeyType: cephv1.UserKeyTypeSwift, GenerateKey: true}}
+		r := newReadyReconciler(newExecutor(&keyArgs), objectUser)
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"key", "create", "--uid", name, "--subuser", "my-user:sub", "--key-type", "swift", "--gen-secret"}}, keyArgs)
+		secret := getSecret(r)
+		assert.Equal(t, "my-user:sub", secret.StringData["SwiftUser_0"])
+		assert.Equal(t, "generated", secret.StringData["SwiftKey_0"])
+		assert.NotContains(t, secret.StringData, "SubuserAccessKey_0")
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		objectUser := newObjectUser()
+		objectUser.Spec.Subusers = []cephv1.SubuserSpec{{Name: "sub", KeyType: "ldap", GenerateKey: true}}
+		assert.Error(t, ValidateUser(objectUser))
+		objectUser.Spec.Subusers[0].KeyType = cephv1.UserKeyTypeS3
+		assert.NoError(t, ValidateUser(objectUser))
+		// a swift key cannot be set on a subuser holding an s3 key
+		objectUser.Spec.Subusers[0].GenerateKey = false
+		objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "sub", Type: cephv1.UserKeyTypeSwift}}
+		assert.Error(t, ValidateUser(objectUser))
+	})
+}
+
+func TestSubuserKeyRotation(t *testing.T) {
+	userKeys := []string{"AK"}
+	swiftKeys := map[string]string{}
+	s3Keys := map[string][]string{}
+	generated := 0
+	var removed []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "key" && args[1] == "create" {
+				generated++
+				switch {
+				case args[4] != "--subuser":
+					userKeys = append(userKeys, fmt.Sprintf("AK%d", generated))
+				case args[7] == "swift":
+					swiftKeys[args[5]] = fmt.Sprintf("swift%d", generated)
+				default:
+					s3Keys[args[5]] = append(s3Keys[args[5]], fmt.Sprintf("SUBAK%d", generated))
+				}
+			}
+			if args[0] == "key" && args[1] == "rm" && args[4] == "--subuser" {
+				removed = append(removed, args[9])
+				s3Keys[args[5]] = []string{}
+			}
+			keys := []string{}
+			for _, key := range userKeys {
+				keys = append(keys, fmt.Sprintf(`{"user": "my-user", "access_key": %q, "secret_key": "secret-%s"}`, key, key))
+			}
+			for id, subuserKeys := range s3Keys {
+				for _, key := range subuserKeys {
+					keys = append(keys, fmt.Sprintf(`{"user": %q, "access_key": %q, "secret_key": "secret-%s"}`, id, key, key))
+				}
+			}
+			swift := []string{}
+			for id, secretKey := range swiftKeys {
+				swift = append(swift, fmt.Sprintf(`{"user": %q, "secret_key": %q}`, id, secretKey))
+			}
+			return fmt.Sprintf(`{"user_id": "my-user", "display_name": "my-user", "keys": [%s], "subusers": [{"id": "my-user:swift", "permissions": "read"}, {"id": "my-user:s3", "permissions": "read"}, {"id": "my-user:fixed", "permissions": "read"}], "swift_keys": [%s]}`, strings.Join(keys, ","), strings.Join(swift, ",")), nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Subusers = []cephv1.SubuserSpec{
+		{Name: "swift", Access: cephv1.AccessSpecRead, GenerateKey: true, RotateWithUser: true},
+		{Name: "s3", Access: cephv1.AccessSpecRead, KeyType: cephv1.UserKeyTypeS3, GenerateKey: true, RotateWithUser: true},
+		{Name: "fixed", Access: cephv1.AccessSpecRead, GenerateKey: true},
+	}
+	objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "current"}}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	getSecret := func() *corev1.Secret {
+		secret := &corev1.Secret{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+		assert.NoError(t, err)
+		return secret
+	}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	secret := getSecret()
+	swiftKey, fixedKey, s3Key := secret.StringData["SwiftKey_0"], secret.StringData["SwiftKey_1"], secret.StringData["SubuserAccessKey_0"]
+	assert.NotEmpty(t, swiftKey)
+	assert.NotEmpty(t, s3Key)
+
+	// the subuser keys do not rotate without a rotation of the user keys
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	secret = getSecret()
+	assert.Equal(t, swiftKey, secret.StringData["SwiftKey_0"])
+	assert.Equal(t, s3Key, secret.StringData["SubuserAccessKey_0"])
+
+	t.Run("user keys rotate", func(t *testing.T) {
+		err := r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "next"}, {Name: "current"}}
+		err = r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+
+		_, err = r.reconcile(req)
+		assert.NoError(t, err)
+		secret := getSecret()
+		// the new user key and the rotated subuser keys are in the secret at once
+		assert.Equal(t, "next", secret.StringData["KeyName_0"])
+		assert.NotEqual(t, swiftKey, secret.StringData["SwiftKey_0"])
+		assert.NotEqual(t, s3Key, secret.StringData["SubuserAccessKey_0"])
+		assert.Equal(t, "secret-"+secret.StringData["SubuserAccessKey_0"], secret.StringData["SubuserSecretKey_0"])
+		assert.Equal(t, []string{s3Key}, removed)
+		// a subuser that does not rotate with the user keeps its key
+		assert.Equal(t, fixedKey, secret.StringData["SwiftKey_1"])
+	})
+
+	t.Run("rotate all subusers", func(t *testing.T) {
+		objectUser.Spec.Subusers[2].RotateWithUser = false
+		objectUser.Spec.Subusers[2].GenerateKey = false
+		assert.NoError(t, ValidateUser(objectUser))
+		objectUser.Spec.Subusers[2].RotateWithUser = true
+		assert.Error(t, ValidateUser(objectUser))
+
+		objectUser.Spec.Subusers[2].GenerateKey = true
+		objectUser.Spec.Subusers[2].RotateWithUser = false
+		objectUser.Spec.RotateSubuserKeys = true
+		assert.True(t, rotatesWithUser(objectUser.Spec, objectUser.Spec.Subusers[2]))
+	})
+}
+
+func TestKeyTypes(t *testing.T) {
+	newExecutor := func(liveKeys *[]string, swiftKeys map[string]string, removed *[]string) *exectest.MockExecutor {
+		return &exectest.MockExecutor{
+			MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+				if args[0] == "user" && args[1] == "create" {
+					return userExistsOutput, nil
+				}
+				if args[0] == "key" && args[1] == "create" && args[4] == "--subuser" {
+					swiftKeys[args[5]] = "generated"
+				} else if args[0] == "key" && args[1] == "create" {
+					*liveKeys = append(*liveKeys, fmt.Sprintf("AK%d", len(*liveKeys)+1))
+				}
+				if args[0] == "key" && args[1] == "rm" {
+					*removed = append(*removed, args[7])
+					keys := []string{}
+					for _, key := range *liveKeys {
+						if key != args[7] {
+							keys = append(keys, key)
+						}
+					}
+					*liveKeys = keys
+					return "", nil
+				}
+				keys := []string{}
+				for _, key := range *liveKeys {
+					keys = append(keys, fmt.Sprintf(`{"user": "my-user", "access_key": %q, "secret_key": "secret-%s"}`, key, key))
+				}
+				swift := []string{}
+				for id, secretKey := range swiftKeys {
+					swift = append(swift, fmt.Sprintf(`{"user": %q, "secret_key": %q}`, id, secretKey))
+				}
+				return fmt.Sprintf(`{"user_id": "my-user", "display_name": "my-user", "keys": [%s], "subusers": [{"id": "my-user:swift", "permissions": "full-control"}], "swift_keys": [%s]}`, strings.Join(keys, ","), strings.Join(swift, ",")), nil
+			},
+		}
+	}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	secretName := types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}
+
+	t.Run("swift only", func(t *testing.T) {
+		liveKeys := []string{"AK1"}
+		swiftKeys := map[string]string{}
+		var removed []string
+		objectUser := newObjectUser()
+		objectUser.Spec.Subusers = []cephv1.SubuserSpec{{Name: "swift", Access: cephv1.AccessSpecFull}}
+		objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "swift", Type: cephv1.UserKeyTypeSwift}}
+		r := newReadyReconciler(newExecutor(&liveKeys, swiftKeys, &removed), objectUser)
+
+		// the S3 key RGW generated is removed and the subuser gets a Swift key
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"AK1"}, removed)
+		assert.Equal(t, map[string]string{"my-user:swift": "generated"}, swiftKeys)
+		secret := &corev1.Secret{}
+		err = r.client.Get(context.TODO(), secretName, secret)
+		assert.NoError(t, err)
+		assert.NotContains(t, secret.StringData, "AccessKey")
+		assert.NotContains(t, secret.StringData, "SecretKey")
+		assert.Equal(t, "my-user:swift", secret.StringData["SwiftUser_0"])
+		assert.Equal(t, "generated", secret.StringData["SwiftKey_0"])
+	})
+	t.Run("mixed", func(t *testing.T) {
+		liveKeys := []string{"AK1"}
+		swiftKeys := map[string]string{}
+		var removed []string
+		objectUser := newObjectUser()
+		objectUser.Spec.Subusers = []cephv1.SubuserSpec{{Name: "swift", Access: cephv1.AccessSpecFull}}
+		objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "swift", Type: cephv1.UserKeyTypeSwift}, {Name: "s3", Type: cephv1.UserKeyTypeS3}}
+		r := newReadyReconciler(newExecutor(&liveKeys, swiftKeys, &removed), objectUser)
+
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Nil(t, removed)
+		assert.Equal(t, []string{"AK1"}, liveKeys)
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Equal(t, []cephv1.ObjectUserKeyStatus{{Name: "s3", AccessKey: "AK1"}}, objectUser.Status.Keys)
+		secret := &corev1.Secret{}
+		err = r.client.Get(context.TODO(), secretName, secret)
+		assert.NoError(t, err)
+		assert.Equal(t, "AK1", secret.StringData["AccessKey"])
+		assert.Equal(t, "s3", secret.StringData["KeyName_0"])
+		assert.Equal(t, "my-user:swift", secret.StringData["SwiftUser_0"])
+		assert.Equal(t, "generated", secret.StringData["SwiftKey_0"])
+	})
+	t.Run("validation", func(t *testing.T) {
+		objectUser := newObjectUser()
+		objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "swift", Type: cephv1.UserKeyTypeSwift}}
+		// RGW only holds Swift keys for subusers
+		assert.Error(t, ValidateUser(objectUser))
+		objectUser.Spec.Subusers = []cephv1.SubuserSpec{{Name: "swift", Access: cephv1.AccessSpecFull}}
+		assert.NoError(t, ValidateUser(objectUser))
+		// a swift-only user may suppress the user keys
+		objectUser.Spec.SuppressUserKeys = true
+		assert.NoError(t, ValidateUser(objectUser))
+		objectUser.Spec.SuppressUserKeys = false
+		objectUser.Spec.Subusers[0].GenerateKey = true
+		assert.Error(t, ValidateUser(objectUser))
+		objectUser.Spec.Subusers[0].GenerateKey = false
+		objectUser.Spec.Keys[0].Type = "ssh"
+		assert.Error(t, ValidateUser(objectUser))
+	})
+}
+
+func TestPreservePolicy(t *testing.T) {
+	tests := []struct {
+		policy  cephv1.PreservePolicy
+		deleted bool
+	}{
+		{"", true},
+		{cephv1.PreservePolicyDelete, true},
+		{cephv1.PreservePolicyRetain, false},
+	}
+	for _, test := range tests {
+		t.Run(string(test.policy), func(t *testing.T) {
+			deleted := false
+			executor := &exectest.MockExecutor{
+				MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+					if args[0] == "user" && args[1] == "rm" {
+						deleted = true
+					}
+					return "", nil
+				},
+			}
+			objectUser := newObjectUser()
+			objectUser.Spec.PreservePolicy = test.policy
+			objectUser.Finalizers = []string{"cephobjectstoreuser.ceph.rook.io"}
+			objectUser.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			r := newReadyReconciler(executor, objectUser)
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+			_, err := r.reconcile(req)
+			assert.NoError(t, err)
+			assert.Equal(t, test.deleted, deleted)
+		})
+	}
+
+	objectUser := newObjectUser()
+	objectUser.Spec.PreservePolicy = "orphan"
+	assert.Error(t, ValidateUser(objectUser))
+}
+
+func TestDeletionBlockedByObjectBuckets(t *testing.T) {
+	var deleteArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "rm" {
+				deleteArgs = args[:4]
+				if len(args) > 4 && args[4] == "--purge-data" {
+					deleteArgs = args[:5]
+				}
+			}
+			return "", nil
+		},
+	}
+	storageClass := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: "rook-ceph-bucket"},
+		Provisioner: "ceph.rook.io/bucket",
+		Parameters:  map[string]string{"objectStoreName": store, "objectStoreNamespace": namespace},
+	}
+	newObjectBucket := func(name, user string) *bktv1alpha1.ObjectBucket {
+		return &bktv1alpha1.ObjectBucket{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: bktv1alpha1.ObjectBucketSpec{
+				StorageClassName: storageClass.Name,
+				Connection:       &bktv1alpha1.Connection{AdditionalState: map[string]string{"cephUser": user}},
+			},
+		}
+	}
+	objectUser := newObjectUser()
+	objectUser.Finalizers = []string{"cephobjectstoreuser.ceph.rook.io"}
+	objectUser.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	ob := newObjectBucket("obc-default-my-bucket", name)
+	r := newReadyReconciler(executor, objectUser, storageClass, ob, newObjectBucket("obc-default-other", "other-user"))
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the user is kept while the object bucket of its claim exists
+	result, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, objectBucketsRequeueAfter, result.RequeueAfter)
+	assert.Nil(t, deleteArgs)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cephobjectstoreuser.ceph.rook.io"}, objectUser.Finalizers)
+	condition := findCondition(objectUser.Status.Conditions, cephv1.ConditionProgressing)
+	assert.Equal(t, reasonObjectBucketsExist, condition.Reason)
+	assert.Contains(t, condition.Message, "obc-default-my-bucket")
+	assert.NotContains(t, condition.Message, "obc-default-other")
+
+	t.Run("purge data", func(t *testing.T) {
+		objectUser.Spec.PurgeDataOnDeletion = true
+		err := r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+		_, err = r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"user", "rm", "--uid", name, "--purge-data"}, deleteArgs)
+	})
+	t.Run("object bucket removed", func(t *testing.T) {
+		deleteArgs = nil
+		objectUser := newObjectUser()
+		objectUser.Finalizers = []string{"cephobjectstoreuser.ceph.rook.io"}
+		objectUser.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		r := newReadyReconciler(executor, objectUser, storageClass)
+		_, err = r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"user", "rm", "--uid", name}, deleteArgs)
+	})
+}
+
+func TestResyncPeriod(t *testing.T) {
+	var modifies int
+	drifted := false
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				if args[1] == "modify" {
+					modifies++
+				}
+				if drifted && args[1] == "info" {
+					return strings.Replace(userCreateJSON, `"display_name": "my-user"`, `"display_name": "changed by hand"`, 1), nil
+				}
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// a reconciled user is not requeued by default
+	result, err := r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, result)
+
+	// with a resync period the user is reconciled again after it, which repairs the drift of the RGW user
+	r.resyncPeriod = 30 * time.Minute
+	drifted = true
+	modifies = 0
+	result, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, result.RequeueAfter)
+	assert.Equal(t, 1, modifies)
+
+	t.Run("failure backoff", func(t *testing.T) {
+		objectUser := newObjectUser()
+		objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxObjects: func(i int64) *int64 { return &i }(-2)}
+		r := newReadyReconciler(executor, objectUser)
+		r.resyncPeriod = 30 * time.Minute
+		result, err := r.Reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, initialFailureBackoff, result.RequeueAfter)
+	})
+}
+
+func TestDeletionRetries(t *testing.T) {
+	newDeletedUser := func() *cephv1.CephObjectStoreUser {
+		objectUser := newObjectUser()
+		objectUser.Finalizers = []string{"cephobjectstoreuser.ceph.rook.io"}
+		objectUser.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		return objectUser
+	}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	t.Run("transient failure", func(t *testing.T) {
+		// radosgw-admin exits with ETIMEDOUT while the cluster does not answer
+		_, exitErr := osexec.Command("sh", "-c", "echo 'could not remove user: connection timed out' >&2; exit 110").Output()
+		deleteErr := &exec.CommandError{ActionName: "radosgw-admin", Err: exitErr}
+		deletes := 0
+		executor := &exectest.MockExecutor{
+			MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+				if args[0] == "user" && args[1] == "rm" {
+					deletes++
+					if deletes == 1 {
+						return "", deleteErr
+					}
+				}
+				return "", nil
+			},
+		}
+		objectUser := newDeletedUser()
+		r := newReadyReconciler(executor, objectUser)
+
+		// the finalizer is kept and the error is returned for the controller to retry the deletion
+		_, err := r.Reconcile(req)
+		assert.Error(t, err)
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"cephobjectstoreuser.ceph.rook.io"}, objectUser.Finalizers)
+		assert.Equal(t, cephv1.ObjectStoreUserPhaseReconciling, objectUser.Status.Phase)
+		condition := findCondition(objectUser.Status.Conditions, cephv1.ConditionProgressing)
+		assert.Equal(t, reasonDeletionRetrying, condition.Reason)
+
+		// the finalizer is removed once the user is deleted
+		_, err = r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, deletes)
+		objectUser = &cephv1.CephObjectStoreUser{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Empty(t, objectUser.Finalizers)
+	})
+	t.Run("persistent failure", func(t *testing.T) {
+		_, exitErr := osexec.Command("sh", "-c", "echo 'could not remove user: permission denied' >&2; exit 13").Output()
+		deleteErr := &exec.CommandError{ActionName: "radosgw-admin", Err: exitErr}
+		executor := &exectest.MockExecutor{
+			MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+				if args[0] == "user" && args[1] == "rm" {
+					return "", deleteErr
+				}
+				return "", nil
+			},
+		}
+		objectUser := newDeletedUser()
+		r := newReadyReconciler(executor, objectUser)
+		_, err := r.reconcile(req)
+		assert.Error(t, err)
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"cephobjectstoreuser.ceph.rook.io"}, objectUser.Finalizers)
+		assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+		condition := findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure)
+		assert.Equal(t, reasonDeletionFailed, condition.Reason)
+	})
+	t.Run("already deleted", func(t *testing.T) {
+		// radosgw-admin exits with ENOENT if the user does not exist
+		_, exitErr := osexec.Command("sh", "-c", "echo 'could not remove user: unable to remove user, user does not exist' >&2; exit 2").Output()
+		deleteErr := &exec.CommandError{ActionName: "radosgw-admin", Err: exitErr}
+		executor := &exectest.MockExecutor{
+			MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+				if args[0] == "user" && args[1] == "rm" {
+					return "", deleteErr
+				}
+				return "", nil
+			},
+		}
+		objectUser := newDeletedUser()
+		r := newReadyReconciler(executor, objectUser)
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		objectUser = &cephv1.CephObjectStoreUser{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Empty(t, objectUser.Finalizers)
+	})
+}
+
+func TestExternalObjectStore(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the rgw does not run in the cluster
+	rgwPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-rgw-my-store-a-5fd6fb4489-xv65v", Namespace: namespace}}
+	err := r.client.Delete(context.TODO(), rgwPod)
+	assert.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	objectStore := &cephv1.CephObjectStore{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: store, Namespace: namespace}, objectStore)
+	assert.NoError(t, err)
+	objectStore.Spec.Gateway.ExternalEndpoint = fmt.Sprintf("http://%s", listener.Addr().String())
+	err = r.client.Update(context.TODO(), objectStore)
+	assert.NoError(t, err)
+
+	// a reachable endpoint is reconciled without rgw pods
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+
+	// an unreachable endpoint is reported in the status
+	listener.Close()
+	_, err = r.Reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+	failure := findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure)
+	assert.Equal(t, reasonObjectStoreNotReady, failure.Reason)
+	assert.Contains(t, failure.Message, "is unreachable")
+}
+
+func TestObjectStoreErrors(t *testing.T) {
+	objectUser := newObjectUser()
+	r := newReadyReconciler(&exectest.MockExecutor{}, objectUser)
+
+	objectStore, err := r.objectStoreInitialized(objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, store, objectStore.Name)
+
+	// a store without rgw pods
+	rgwPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-rgw-my-store-a-5fd6fb4489-xv65v", Namespace: namespace}}
+	err = r.client.Delete(context.TODO(), rgwPod)
+	assert.NoError(t, err)
+	_, err = r.objectStoreInitialized(objectUser)
+	assert.Equal(t, object.ErrRGWNotRunning, errors.Cause(err))
+
+	// an external store whose endpoint is unreachable
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	listener.Close()
+	objectStore = &cephv1.CephObjectStore{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: store, Namespace: namespace}, objectStore)
+	assert.NoError(t, err)
+	objectStore.Spec.Gateway.ExternalEndpoint = fmt.Sprintf("http://%s", listener.Addr().String())
+	err = r.client.Update(context.TODO(), objectStore)
+	assert.NoError(t, err)
+	_, err = r.objectStoreInitialized(objectUser)
+	assert.Equal(t, object.ErrObjectStoreNotReady, errors.Cause(err))
+	assert.Contains(t, err.Error(), "is unreachable")
+
+	// a store that does not exist
+	objectUser.Spec.Store = "other-store"
+	_, err = r.objectStoreInitialized(objectUser)
+	assert.Equal(t, object.ErrObjectStoreNotFound, errors.Cause(err))
+	assert.Contains(t, err.Error(), `CephObjectStore "other-store" could not be found`)
+}
+
+func TestClusterNamespace(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Namespace = "my-app"
+	objectUser.Spec.ClusterNamespace = namespace
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "my-app"}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+
+	// the secret is created next to the user
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: "my-app"}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, namespace, secret.Labels["rook_cluster"])
+}
+
+func TestCrossNamespaceStore(t *testing.T) {
+	var userArgs [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				userArgs = append(userArgs, args)
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Namespace = "my-app"
+	objectUser.Spec.Store = namespace + "/" + store
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "my-app"}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, userArgs)
+	for _, args := range userArgs {
+		assert.Contains(t, args, "--rgw-realm=my-store")
+	}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+
+	// the secret is named after the store and created next to the user
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: "my-app"}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, namespace, secret.Labels["rook_cluster"])
+	assert.Equal(t, store, secret.Labels["rook_object_store"])
+
+	// the namespace of the store must match the cluster namespace
+	objectUser.Spec.ClusterNamespace = "other"
+	err = ValidateUser(objectUser)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match cluster namespace")
+	objectUser.Spec.ClusterNamespace = ""
+	objectUser.Spec.Store = namespace + "/"
+	assert.Error(t, ValidateUser(objectUser))
+}
+
+func TestSecretOwnerReference(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.UID = "c5a1b2d4-0e7f-4b8a-9d6c-3f2e1a0b9c8d"
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+
+	// the secret is garbage collected with the user
+	assert.Len(t, secret.OwnerReferences, 1)
+	owner := secret.OwnerReferences[0]
+	assert.Equal(t, objectUser.UID, owner.UID)
+	assert.Equal(t, "CephObjectStoreUser", owner.Kind)
+	assert.Equal(t, name, owner.Name)
+	assert.True(t, *owner.Controller)
+	assert.Equal(t, map[string]string{
+		"app":                "rook-ceph-rgw",
+		"user":               name,
+		"rook_cluster":       namespace,
+		"rook_object_store":  store,
+		"rook.io/managed-by": "object-user-controller",
+	}, secret.Labels)
+}
+
+func TestDisableSecretGeneration(t *testing.T) {
+	var createArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				if args[1] == "create" {
+					createArgs = args
+				}
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.DisableSecretGeneration = true
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the RGW user is managed and the user is ready without a secret
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.NotNil(t, createArgs)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.True(t, kerrors.IsNotFound(err))
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	assert.Empty(t, objectUser.Status.SecretHash)
+
+	// the deletion does not fail on the missing secret
+	objectUser.Finalizers = []string{"cephobjectstoreuser.ceph.rook.io"}
+	objectUser.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+
+	t.Run("secret namespaces", func(t *testing.T) {
+		objectUser := newObjectUser()
+		objectUser.Spec.DisableSecretGeneration = true
+		objectUser.Spec.SecretNamespaces = []string{"apps"}
+		assert.Error(t, ValidateUser(objectUser))
+	})
+}
+
+func TestBuildUserSecret(t *testing.T) {
+	accessKey, secretKey := "AK", "SK"
+	internalStore := &cephv1.CephObjectStore{
+		ObjectMeta: metav1.ObjectMeta{Name: store, Namespace: namespace},
+		Spec:       cephv1.ObjectStoreSpec{Gateway: cephv1.GatewaySpec{Port: 80}},
+	}
+	externalStore := &cephv1.CephObjectStore{
+		ObjectMeta: metav1.ObjectMeta{Name: store, Namespace: namespace},
+		Spec:       cephv1.ObjectStoreSpec{Gateway: cephv1.GatewaySpec{ExternalEndpoint: "https://s3.example.com"}},
+	}
+	tests := []struct {
+		name     string
+		spec     cephv1.ObjectStoreUserSpec
+		keys     userSecretKeys
+		store    *cephv1.CephObjectStore
+		expected map[string]string
+		endpoint string
+	}{
+		{
+			name:     "user keys",
+			keys:     userSecretKeys{accessKey: &accessKey, secretKey: &secretKey},
+			store:    internalStore,
+			expected: map[string]string{"AccessKey": "AK", "SecretKey": "SK"},
+		},
+		{
+			name:     "suppressed keys",
+			store:    internalStore,
+			expected: map[string]string{},
+		},
+		{
+			name:  "named keys",
+			keys:  userSecretKeys{accessKey: &accessKey, secretKey: &secretKey, keys: []namedKey{{"blue", "AK1", "SK1"}, {"green", "AK2", "SK2"}}},
+			store: internalStore,
+			expected: map[string]string{"AccessKey": "AK", "SecretKey": "SK",
+				"KeyName_0": "blue", "AccessKey_0": "AK1", "SecretKey_0": "SK1",
+				"KeyName_1": "green", "AccessKey_1": "AK2", "SecretKey_1": "SK2"},
+		},
+		{
+			name:     "aws key names",
+			spec:     cephv1.ObjectStoreUserSpec{AWSKeyNames: true},
+			keys:     userSecretKeys{accessKey: &accessKey, secretKey: &secretKey},
+			store:    internalStore,
+			expected: map[string]string{"AccessKey": "AK", "SecretKey": "SK", "AWS_ACCESS_KEY_ID": "AK", "AWS_SECRET_ACCESS_KEY": "SK"},
+		},
+		{
+			name:     "swift and temp url keys without user keys",
+			keys:     userSecretKeys{swiftKeys: []namedKey{{"swift", "my-user:swift", "SWK"}}, tempURLKeys: map[int]string{1: "TUK"}},
+			store:    internalStore,
+			expected: map[string]string{"SwiftUser_0": "my-user:swift", "SwiftKey_0": "SWK", "TempURLKey_1": "TUK"},
+		},
+		{
+			name:     "mc config of an internal store",
+			spec:     cephv1.ObjectStoreUserSpec{SecretFormat: secretFormatMC},
+			keys:     userSecretKeys{accessKey: &accessKey, secretKey: &secretKey},
+			store:    internalStore,
+			endpoint: "http://rook-ceph-rgw-my-store.rook-ceph:80",
+		},
+		{
+			name:     "mc config of an external store",
+			spec:     cephv1.ObjectStoreUserSpec{SecretFormat: secretFormatMC},
+			keys:     userSecretKeys{accessKey: &accessKey, secretKey: &secretKey},
+			store:    externalStore,
+			endpoint: "https://s3.example.com",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objectUser := newObjectUser()
+			objectUser.UID = "c5a1b2d4-0e7f-4b8a-9d6c-3f2e1a0b9c8d"
+			test.spec.Store = store
+			objectUser.Spec = test.spec
+			secret, err := buildUserSecret(objectUser, test.keys, test.store)
+			assert.NoError(t, err)
+			assert.Equal(t, "rook-ceph-object-user-my-store-my-user", secret.Name)
+			assert.Equal(t, namespace, secret.Namespace)
+			assert.Equal(t, map[string]string{
+				"app":                "rook-ceph-rgw",
+				"user":               name,
+				"rook_cluster":       namespace,
+				"rook_object_store":  store,
+				"rook.io/managed-by": "object-user-controller",
+			}, secret.Labels)
+			assert.Equal(t, secretDataHash(secret.StringData), secret.Annotations[secretHashAnnotation])
+			assert.Len(t, secret.OwnerReferences, 1)
+			assert.Equal(t, objectUser.UID, secret.OwnerReferences[0].UID)
+			assert.Equal(t, "CephObjectStoreUser", secret.OwnerReferences[0].Kind)
+			assert.True(t, *secret.OwnerReferences[0].Controller)
+
+			if test.endpoint == "" {
+				assert.Equal(t, test.expected, secret.StringData)
+				return
+			}
+			var config mcConfig
+			err = json.Unmarshal([]byte(secret.StringData[mcConfigKey]), &config)
+			assert.NoError(t, err)
+			assert.Equal(t, mcAliasConfig{URL: test.endpoint, AccessKey: "AK", SecretKey: "SK", API: "s3v4", Path: "auto"}, config.Aliases[store])
+		})
+	}
+}
+
+func TestSecretFormats(t *testing.T) {
+	accessKey, secretKey := "AK", "SK"
+	keys := userSecretKeys{
+		accessKey:   &accessKey,
+		secretKey:   &secretKey,
+		keys:        []namedKey{{"blue", "AK1", "SK1"}},
+		swiftKeys:   []namedKey{{"swift", "my-user:swift", "SWK"}},
+		tempURLKeys: map[int]string{0: "TUK"},
+	}
+	objectStore := &cephv1.CephObjectStore{
+		ObjectMeta: metav1.ObjectMeta{Name: store, Namespace: namespace},
+		Spec:       cephv1.ObjectStoreSpec{Gateway: cephv1.GatewaySpec{Port: 80}},
+	}
+	tests := []struct {
+		format   string
+		schema   string
+		expected map[string]string
+	}{
+		{"", "rook/v1", map[string]string{
+			"AccessKey": "AK", "SecretKey": "SK",
+			"KeyName_0": "blue", "AccessKey_0": "AK1", "SecretKey_0": "SK1",
+			"SwiftUser_0": "my-user:swift", "SwiftKey_0": "SWK", "TempURLKey_0": "TUK",
+		}},
+		{"rook", "rook/v1", map[string]string{
+			"AccessKey": "AK", "SecretKey": "SK",
+			"KeyName_0": "blue", "AccessKey_0": "AK1", "SecretKey_0": "SK1",
+			"SwiftUser_0": "my-user:swift", "SwiftKey_0": "SWK", "TempURLKey_0": "TUK",
+		}},
+		{"standard", "standard/v1", map[string]string{
+			"accessKeyID": "AK", "secretAccessKey": "SK", "endpoint": "http://rook-ceph-rgw-my-store.rook-ceph:80",
+			"keyName_0": "blue", "accessKeyID_0": "AK1", "secretAccessKey_0": "SK1",
+			"swiftUser_0": "my-user:swift", "swiftKey_0": "SWK", "tempURLKey_0": "TUK",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.schema, func(t *testing.T) {
+			objectUser := newObjectUser()
+			objectUser.Spec.SecretFormat = test.format
+			assert.NoError(t, ValidateUser(objectUser))
+			secret, err := buildUserSecret(objectUser, keys, objectStore)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, secret.StringData)
+			assert.Equal(t, test.schema, secret.Annotations[secretSchemaAnnotation])
+		})
+	}
+
+	t.Run("mc", func(t *testing.T) {
+		objectUser := newObjectUser()
+		objectUser.Spec.SecretFormat = "mc"
+		secret, err := buildUserSecret(objectUser, userSecretKeys{accessKey: &accessKey, secretKey: &secretKey}, objectStore)
+		assert.NoError(t, err)
+		assert.Len(t, secret.StringData, 3)
+		assert.Equal(t, "AK", secret.StringData["AccessKey"])
+		assert.Equal(t, "SK", secret.StringData["SecretKey"])
+		assert.Contains(t, secret.StringData, "config.json")
+		assert.Equal(t, "mc/v1", secret.Annotations[secretSchemaAnnotation])
+	})
+	t.Run("invalid", func(t *testing.T) {
+		objectUser := newObjectUser()
+		objectUser.Spec.SecretFormat = "vault"
+		assert.Error(t, ValidateUser(objectUser))
+	})
+}
+
+func TestSecretType(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the secret has the rook type by default
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSecretName(objectUser), Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.SecretType(k8sutil.RookType), secret.Type)
+
+	// the secret is recreated with a new type, which cannot be updated
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	objectUser.Spec.SecretType = corev1.SecretTypeOpaque
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	secret = &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: userSecretName(objectUser), Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.SecretTypeOpaque, secret.Type)
+	assert.NotEmpty(t, secret.StringData["AccessKey"])
+
+	t.Run("validation", func(t *testing.T) {
+		u := newObjectUser()
+		for _, secretType := range []corev1.SecretType{"Opaque", "example.com/s3-credentials"} {
+			u.Spec.SecretType = secretType
+			assert.NoError(t, ValidateUser(u), secretType)
+		}
+		for _, secretType := range []corev1.SecretType{"kubernetes.io/tls", "kubernetes.io/basic-auth", "my type", "a/b/c"} {
+			u.Spec.SecretType = secretType
+			assert.Error(t, ValidateUser(u), secretType)
+		}
+	})
+}
+
+func TestCollisionSafeSecretNameLength(t *testing.T) {
+	u := newObjectUser()
+	u.Name = strings.Repeat("a", 220) + "." + strings.Repeat("b", 32)
+	name := collisionSafeSecretName(u)
+	assert.Len(t, name, validation.DNS1123SubdomainMaxLength)
+	assert.Empty(t, validation.IsDNS1123Subdomain(name))
+	// the hash still tells the users apart
+	other := u.DeepCopy()
+	other.Name = u.Name[:len(u.Name)-1] + "c"
+	assert.NotEqual(t, name, collisionSafeSecretName(other))
+
+	// a name cut after a dot is still valid
+	u.Name = strings.Repeat("a", 212) + "." + strings.Repeat("b", 40)
+	name = collisionSafeSecretName(u)
+	assert.Len(t, name, validation.DNS1123SubdomainMaxLength-1)
+	assert.Empty(t, validation.IsDNS1123Subdomain(name))
+
+	// a short name is not cut
+	assert.Regexp(t, "^rook-ceph-object-user-my-store-my-user-[0-9a-f]{8}$", collisionSafeSecretName(newObjectUser()))
+}
+
+func TestSecretNameCollision(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	// user "store-my-user" of store "my" has the secret name of user "my-user" of store "my-store"
+	objectUser := newObjectUser()
+	otherUser := newObjectUser()
+	otherUser.Name = "store-my-user"
+	otherUser.Spec.Store = "my"
+	otherStore := &cephv1.CephObjectStore{ObjectMeta: metav1.ObjectMeta{Name: "my", Namespace: namespace}}
+	otherPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "rook-ceph-rgw-my-a-5fd6fb4489-xv65v",
+		Namespace: namespace,
+		Labels:    map[string]string{k8sutil.AppAttr: appName, "rgw": "my"}}}
+	r := newReadyReconciler(executor, objectUser, otherUser, otherStore, otherPod)
+
+	for _, u := range []*cephv1.CephObjectStoreUser{objectUser, otherUser} {
+		_, err := r.reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: u.Name, Namespace: namespace}})
+		assert.NoError(t, err)
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: u.Name, Namespace: namespace}, u)
+		assert.NoError(t, err)
+		assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, u.Status.Phase)
+	}
+
+	assert.Equal(t, "rook-ceph-object-user-my-store-my-user", objectUser.Status.SecretName)
+	assert.Nil(t, findCondition(objectUser.Status.Conditions, cephv1.ConditionSecretNameCollision))
+	assert.NotEqual(t, objectUser.Status.SecretName, otherUser.Status.SecretName)
+	assert.Regexp(t, "^rook-ceph-object-user-my-store-my-user-[0-9a-f]{8}$", otherUser.Status.SecretName)
+	collision := findCondition(otherUser.Status.Conditions, cephv1.ConditionSecretNameCollision)
+	assert.Equal(t, corev1.ConditionTrue, collision.Status)
+
+	for _, u := range []*cephv1.CephObjectStoreUser{objectUser, otherUser} {
+		secret := &corev1.Secret{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: u.Status.SecretName, Namespace: namespace}, secret)
+		assert.NoError(t, err)
+		assert.Equal(t, u.Name, secret.Labels["user"])
+		assert.Equal(t, u.Spec.Store, secret.Labels["rook_object_store"])
+	}
+
+	// the secret name is kept by the next reconciles
+	_, err := r.reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: otherUser.Name, Namespace: namespace}})
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: otherUser.Name, Namespace: namespace}, otherUser)
+	assert.NoError(t, err)
+	assert.Regexp(t, "^rook-ceph-object-user-my-store-my-user-[0-9a-f]{8}$", otherUser.Status.SecretName)
+}
+
+func TestAWSKeyNames(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.AWSKeyNames = true
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	// both sets of names are set
+	assert.Equal(t, "EOE7FYCNOBZJ5VFV909G", secret.StringData["AccessKey"])
+	assert.Equal(t, "EOE7FYCNOBZJ5VFV909G", secret.StringData["AWS_ACCESS_KEY_ID"])
+	assert.Equal(t, "qmIqpWm8HxCzmynCrD6U6vKWi4hnDBndOnmxXNsV", secret.StringData["SecretKey"])
+	assert.Equal(t, "qmIqpWm8HxCzmynCrD6U6vKWi4hnDBndOnmxXNsV", secret.StringData["AWS_SECRET_ACCESS_KEY"])
+
+	objectUser.Spec.SuppressUserKeys = true
+	assert.Error(t, ValidateUser(objectUser))
+}
+
+func TestConcurrentReconciles(t *testing.T) {
+	var mutex sync.Mutex
+	inFlight, maxInFlight, keysCreated := 0, 0, 0
+	liveKeys := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			mutex.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mutex.Unlock()
+			// give the other reconcile a chance to interleave
+			time.Sleep(time.Millisecond)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			inFlight--
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "key" && args[1] == "create" {
+				keysCreated++
+				liveKeys = append(liveKeys, fmt.Sprintf(`{"user": "my-user", "access_key": "AK%d", "secret_key": "secret"}`, keysCreated))
+			}
+			return fmt.Sprintf(`{"user_id": "my-user", "display_name": "my-user", "keys": [%s]}`, strings.Join(liveKeys, ",")), nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Keys = []cephv1.UserKeySpec{{Name: "blue"}}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.Reconcile(req)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// the radosgw-admin commands of the two reconciles did not interleave
+	assert.Equal(t, 1, maxInFlight)
+	assert.Equal(t, 1, keysCreated)
+	err := r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []cephv1.ObjectUserKeyStatus{{Name: "blue", AccessKey: "AK1"}}, objectUser.Status.Keys)
+	// the lock of the user is dropped once no reconcile holds it
+	assert.Empty(t, r.locks.locks)
+}
+
+func TestSettings(t *testing.T) {
+	var buf bytes.Buffer
+	capnslog.SetFormatter(capnslog.NewStringFormatter(&buf))
+	defer capnslog.SetFormatter(capnslog.NewDefaultFormatter(os.Stderr))
+	name := "ROOK_OBJECT_USER_TEST_SETTING"
+	defer os.Unsetenv(name)
+
+	// a setting that is not set is not warned about
+	_, ok := durationSetting(name, true)
+	assert.False(t, ok)
+	assert.Empty(t, buf.String())
+
+	os.Setenv(name, "5m")
+	duration, ok := durationSetting(name, true)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Minute, duration)
+	os.Setenv(name, "0")
+	_, ok = durationSetting(name, false)
+	assert.True(t, ok)
+	os.Setenv(name, "3")
+	i, ok := intSetting(name, true)
+	assert.True(t, ok)
+	assert.Equal(t, 3, i)
+
+	// every invalid setting is ignored with the same warning
+	for _, value := range []string{"0", "-1", "often"} {
+		t.Run(value, func(t *testing.T) {
+			os.Setenv(name, value)
+			buf.Reset()
+			_, ok := durationSetting(name, true)
+			assert.False(t, ok)
+			assert.Contains(t, buf.String(), fmt.Sprintf("ignoring invalid ROOK_OBJECT_USER_TEST_SETTING %q, it must be a positive duration. using the default", value))
+			buf.Reset()
+			_, ok = intSetting(name, true)
+			assert.False(t, ok)
+			assert.Contains(t, buf.String(), fmt.Sprintf("ignoring invalid ROOK_OBJECT_USER_TEST_SETTING %q, it must be a positive integer. using the default", value))
+		})
+	}
+	os.Setenv(name, "-1")
+	buf.Reset()
+	_, ok = intSetting(name, false)
+	assert.False(t, ok)
+	assert.Contains(t, buf.String(), "it must be a non-negative integer")
+}
+
+func TestReconcileStateReset(t *testing.T) {
+	r := newReadyReconciler(&exectest.MockExecutor{})
+	// the state a previous reconcile left behind
+	r.changes = []string{"displayName=stale"}
+	r.dryRun = true
+	r.failureReason = failureReasonOther
+
+	_, err := r.reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: "missing", Namespace: namespace}})
+	assert.NoError(t, err)
+	assert.Nil(t, r.changes)
+	assert.False(t, r.dryRun)
+	assert.Empty(t, r.failureReason)
+	assert.NotNil(t, r.log)
+}
+
+func TestUserLocks(t *testing.T) {
+	locks := &userLocks{}
+	unlock := locks.lock("rook-ceph/my-store/my-user")
+	locked := make(chan bool)
+	go func() {
+		unlockOther := locks.lock("rook-ceph/my-store/my-user")
+		locked <- true
+		unlockOther()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("the lock of the user was taken twice")
+	case <-time.After(10 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+	<-locked
+
+	// the locks of the users that come and go are not kept
+	for i := 0; i < 10; i++ {
+		locks.lock(fmt.Sprintf("rook-ceph/my-store/user-%d", i))()
+	}
+	locks.mutex.Lock()
+	defer locks.mutex.Unlock()
+	assert.Empty(t, locks.locks)
+}
+
+func TestQuotaBytes(t *testing.T) {
+	tests := []struct {
+		size     string
+		expected int64
+	}{
+		{"10G", 10000000000},
+		{"10Gi", 10737418240},
+		{"512M", 512000000},
+		{"512Mi", 536870912},
+		{"100k", 100000},
+		{"100Ki", 102400},
+		{"-1", -1},
+		{"10Ei", math.MaxInt64},
+	}
+	for _, test := range tests {
+		t.Run(test.size, func(t *testing.T) {
+			size := resource.MustParse(test.size)
+			objectUser := newObjectUser()
+			objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxSize: &size}
+			userConfig, _, err := generateUserConfig(objectUser, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, userConfig.UserQuota.MaxSize)
+		})
+	}
+
+	// a size that overflows is rejected
+	size := resource.MustParse("10Ei")
+	objectUser := newObjectUser()
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxSize: &size}
+	assert.Error(t, ValidateUser(objectUser))
+	size = resource.MustParse("7Ei")
+	assert.NoError(t, ValidateUser(objectUser))
+
+	t.Run("negative", func(t *testing.T) {
+		size := resource.MustParse("-10G")
+		objectUser.Spec.Quotas.MaxSize = &size
+		assert.Error(t, ValidateUser(objectUser))
+		size = resource.MustParse("-1")
+		assert.NoError(t, ValidateUser(objectUser))
+	})
+	t.Run("fraction of a byte", func(t *testing.T) {
+		size := resource.MustParse("100m")
+		objectUser.Spec.Quotas.MaxSize = &size
+		assert.Error(t, ValidateUser(objectUser))
+	})
+	t.Run("malformed", func(t *testing.T) {
+		// a malformed size is rejected when the CR is decoded, before it reaches the operator
+		var quotas cephv1.ObjectUserQuotaSpec
+		assert.Error(t, json.Unmarshal([]byte(`{"maxSize": "10GB"}`), &quotas))
+		assert.Error(t, json.Unmarshal([]byte(`{"maxSize": "ten"}`), &quotas))
+	})
+}
+
+func TestMaxObjects(t *testing.T) {
+	int64Ptr := func(i int64) *int64 { return &i }
+	objectUser := newObjectUser()
+
+	t.Run("unlimited", func(t *testing.T) {
+		objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxObjects: int64Ptr(-1)}
+		assert.NoError(t, ValidateUser(objectUser))
+		userConfig, _, err := generateUserConfig(objectUser, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(-1), userConfig.UserQuota.MaxObjects)
+	})
+	t.Run("zero", func(t *testing.T) {
+		objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxObjects: int64Ptr(0)}
+		assert.NoError(t, ValidateUser(objectUser))
+		userConfig, _, err := generateUserConfig(objectUser, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), userConfig.UserQuota.MaxObjects)
+	})
+	t.Run("out of range", func(t *testing.T) {
+		objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxObjects: int64Ptr(-2)}
+		err := ValidateUser(objectUser)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid max objects -2")
+		objectUser.Spec.Quotas.MaxObjects = int64Ptr(math.MaxInt64)
+		assert.NoError(t, ValidateUser(objectUser))
+
+		// a number that overflows the int64 of RGW is rejected when the CR is decoded
+		var quotas cephv1.ObjectUserQuotaSpec
+		assert.Error(t, json.Unmarshal([]byte(`{"maxObjects": 9223372036854775808}`), &quotas))
+	})
+	t.Run("status", func(t *testing.T) {
+		executor := &exectest.MockExecutor{
+			MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+				if args[0] == "user" {
+					return userCreateJSON, nil
+				}
+				return "", nil
+			},
+		}
+		objectUser := newObjectUser()
+		objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxObjects: int64Ptr(-10)}
+		r := newReadyReconciler(executor, objectUser)
+		req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+		_, err := r.reconcile(req)
+		assert.Error(t, err)
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		condition := findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure)
+		assert.Equal(t, reasonInvalidSpec, condition.Reason)
+		assert.Contains(t, condition.Message, "invalid max objects -10")
+	})
+}
+
+func TestMaxSizeKB(t *testing.T) {
+	int64Ptr := func(i int64) *int64 { return &i }
+	var quotaArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			if args[0] == "quota" && args[1] == "set" {
+				quotaArgs = args
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxSizeKB: int64Ptr(1000)}
+	r := newReadyReconciler(executor, objectUser)
+
+	// the size is sent in KB as is
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"quota", "set", "--uid", name, "--quota-scope", "user", "--max-size-kb", "1000", "--max-objects", "-1"}, quotaArgs[:10])
+
+	t.Run("compared in bytes", func(t *testing.T) {
+		userConfig, _, err := generateUserConfig(objectUser, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1024000), userConfig.UserQuota.MaxSize)
+		assert.True(t, quotaMatches(object.ObjectUserQuota{Enabled: true, MaxSize: 1024000, MaxObjects: -1}, *userConfig.UserQuota))
+
+		objectUser.Spec.Quotas.MaxSizeKB = int64Ptr(-1)
+		userConfig, _, err = generateUserConfig(objectUser, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(-1), userConfig.UserQuota.MaxSize)
+	})
+	t.Run("validation", func(t *testing.T) {
+		objectUser := newObjectUser()
+		maxSize := resource.MustParse("1G")
+		objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxSize: &maxSize, MaxSizeKB: int64Ptr(1000)}
+		err := ValidateUser(objectUser)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "mutually exclusive")
+
+		objectUser.Spec.Quotas.MaxSize = nil
+		assert.NoError(t, ValidateUser(objectUser))
+		objectUser.Spec.Quotas.MaxSizeKB = int64Ptr(-2)
+		assert.Error(t, ValidateUser(objectUser))
+		objectUser.Spec.Quotas.MaxSizeKB = int64Ptr(math.MaxInt64 / 1000)
+		assert.Error(t, ValidateUser(objectUser))
+	})
+	t.Run("clamped", func(t *testing.T) {
+		maxSize := resource.MustParse("1M")
+		maxQuotas := &cephv1.ObjectUserQuotaSpec{MaxSize: &maxSize}
+		result, clamped := clampQuotas(&cephv1.ObjectUserQuotaSpec{MaxSizeKB: int64Ptr(900)}, maxQuotas)
+		assert.Equal(t, int64(900), *result.MaxSizeKB)
+		assert.Empty(t, clamped)
+
+		result, clamped = clampQuotas(&cephv1.ObjectUserQuotaSpec{MaxSizeKB: int64Ptr(1000)}, maxQuotas)
+		assert.Nil(t, result.MaxSizeKB)
+		assert.Equal(t, int64(1000000), result.MaxSize.Value())
+		assert.Equal(t, []string{"maxSizeKB from 1000 to 1M"}, clamped)
+
+		// a store maximum in KB
+		result, clamped = clampQuotas(&cephv1.ObjectUserQuotaSpec{MaxSize: &maxSize}, &cephv1.ObjectUserQuotaSpec{MaxSizeKB: int64Ptr(500)})
+		assert.Nil(t, result.MaxSize)
+		assert.Equal(t, int64(500), *result.MaxSizeKB)
+		assert.Equal(t, []string{"maxSize from 1M to 500KB"}, clamped)
+	})
+}
+
+func TestMaxSizePercent(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	var quotaArgs []string
+	// the data pool stores 1TB and has 9TB available
+	dataPoolStats := `{"pools":[{"name":"my-store.rgw.buckets.data","stats":{"bytes_used":1000000000000,"max_avail":9000000000000}}]}`
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			if args[0] == "quota" && args[1] == "set" {
+				quotaArgs = args
+			}
+			return "", nil
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outfile string, args ...string) (string, error) {
+			if args[0] == "status" {
+				return `{"pgmap":{"num_pgs":100,"pgs_by_state":[{"state_name":"active+clean","count":100}]}}`, nil
+			}
+			if args[0] == "df" {
+				return dataPoolStats, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxSizePercent: intPtr(5)}
+	r := newReadyReconciler(executor, objectUser)
+
+	// 5% of the capacity of 10TB
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"quota", "set", "--uid", name, "--quota-scope", "user", "--max-size", "500000000000", "--max-objects", "-1"}, quotaArgs[:10])
+
+	t.Run("resized store", func(t *testing.T) {
+		dataPoolStats = `{"pools":[{"name":"my-store.rgw.buckets.data","stats":{"bytes_used":1000000000000,"max_avail":19000000000000}}]}`
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"--max-size", "1000000000000"}, quotaArgs[6:8])
+	})
+	t.Run("no data pool", func(t *testing.T) {
+		dataPoolStats = `{"pools":[{"name":"other-store.rgw.buckets.data","stats":{"bytes_used":1,"max_avail":1}}]}`
+		_, err := r.reconcile(req)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `data pool "my-store.rgw.buckets.data" of object store "my-store" not found`)
+
+		u := &cephv1.CephObjectStoreUser{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, u)
+		assert.NoError(t, err)
+		assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, u.Status.Phase)
+		assert.Equal(t, reasonQuotaCapacityUnavailable, findCondition(u.Status.Conditions, cephv1.ConditionFailure).Reason)
+	})
+	t.Run("validation", func(t *testing.T) {
+		objectUser := newObjectUser()
+		objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxSizePercent: intPtr(100)}
+		assert.NoError(t, ValidateUser(objectUser))
+		objectUser.Spec.Quotas.MaxSizePercent = intPtr(0)
+		assert.Error(t, ValidateUser(objectUser))
+		objectUser.Spec.Quotas.MaxSizePercent = intPtr(101)
+		assert.Error(t, ValidateUser(objectUser))
+
+		maxSize := resource.MustParse("1G")
+		objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxSize: &maxSize, MaxSizePercent: intPtr(5)}
+		err := ValidateUser(objectUser)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "mutually exclusive")
+	})
+}
+
+func TestDefaultBucketQuota(t *testing.T) {
+	int64Ptr := func(i int64) *int64 { return &i }
+	userJSON := userCreateJSON
+	var quotaCommands [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userJSON, nil
+			}
+			if args[0] == "quota" {
+				quotaCommands = append(quotaCommands, args[:indexOf(args, "--rgw-realm=my-store")])
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	maxSize := resource.MustParse("1G")
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxObjects: int64Ptr(1000), DefaultBucketMaxSize: &maxSize, DefaultBucketMaxObjects: int64Ptr(100)}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the bucket scope quota is set apart from the user scope quota
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"quota", "set", "--uid", name, "--quota-scope", "user", "--max-size", "-1", "--max-objects", "1000"},
+		{"quota", "enable", "--quota-scope", "user", "--uid", name},
+		{"quota", "set", "--uid", name, "--quota-scope", "bucket", "--max-size", "1000000000", "--max-objects", "100"},
+		{"quota", "enable", "--quota-scope", "bucket", "--uid", name},
+	}, quotaCommands)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.True(t, objectUser.Status.Quota.BucketEnforced)
+
+	t.Run("unchanged", func(t *testing.T) {
+		quotaCommands = nil
+		userJSON = strings.Replace(userCreateJSON, `"bucket_quota": {
+		"enabled": false,
+		"check_on_raw": false,
+		"max_size": -1,
+		"max_size_kb": 0,
+		"max_objects": -1`, `"bucket_quota": {
+		"enabled": true,
+		"check_on_raw": false,
+		"max_size": 1000000000,
+		"max_size_kb": 976563,
+		"max_objects": 100`, 1)
+		userJSON = strings.Replace(userJSON, `"user_quota": {
+		"enabled": false,
+		"check_on_raw": false,
+		"max_size": -1,
+		"max_size_kb": 0,
+		"max_objects": -1`, `"user_quota": {
+		"enabled": true,
+		"check_on_raw": false,
+		"max_size": -1,
+		"max_size_kb": 0,
+		"max_objects": 1000`, 1)
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Empty(t, quotaCommands)
+	})
+	t.Run("only objects", func(t *testing.T) {
+		objectUser := newObjectUser()
+		objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{DefaultBucketMaxObjects: int64Ptr(100)}
+		userConfig, _, err := generateUserConfig(objectUser, nil)
+		assert.NoError(t, err)
+		assert.Nil(t, userConfig.UserQuota)
+		assert.Equal(t, &object.ObjectUserQuota{Enabled: true, MaxSize: -1, MaxObjects: 100}, userConfig.BucketQuota)
+	})
+	t.Run("validation", func(t *testing.T) {
+		objectUser := newObjectUser()
+		objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{DefaultBucketMaxObjects: int64Ptr(-2)}
+		err := ValidateUser(objectUser)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid default bucket max objects")
+
+		maxSize := resource.MustParse("-2")
+		objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{DefaultBucketMaxSize: &maxSize}
+		assert.Error(t, ValidateUser(objectUser))
+	})
+}
+
+func TestCapsVerification(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			// the caps are not applied
+			if args[0] == "caps" {
+				return "", nil
+			}
+			return capsUserJSON(map[string]string{"users": "read"}), nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Capabilities = &cephv1.ObjectUserCapSpec{User: "*"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `users capability of ceph object user "my-user" is "read" after it was set to "*"`)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+}
+
+func TestRawCaps(t *testing.T) {
+	liveCaps := map[string]string{}
+	var capsArgs [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "caps" {
+				capsArgs = append(capsArgs, args[:indexOf(args, "--rgw-realm=my-store")])
+				liveCaps = applyCaps(liveCaps, args[1], args[indexOf(args, "--caps")+1])
+				return capsUserJSON(liveCaps), nil
+			}
+			return capsUserJSON(liveCaps), nil
+		},
+	}
+	objectUser := newObjectUser()
+	// a capability type the spec has no field for is passed on
+	objectUser.Spec.Capabilities = &cephv1.ObjectUserCapSpec{Raw: "users=read;oidc-provider=*"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"caps", "add", "--uid", name, "--caps", "users=read;oidc-provider=*"}}, capsArgs)
+	assert.Equal(t, map[string]string{"users": "read", "oidc-provider": "*"}, liveCaps)
+
+	t.Run("unchanged", func(t *testing.T) {
+		capsArgs = nil
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Empty(t, capsArgs)
+	})
+	t.Run("validation", func(t *testing.T) {
+		u := newObjectUser()
+		for _, raw := range []string{"users", "users=all", "=read", "Users=read", "users=read;users=write", ";"} {
+			u.Spec.Capabilities = &cephv1.ObjectUserCapSpec{Raw: raw}
+			assert.Error(t, ValidateUser(u), raw)
+		}
+		u.Spec.Capabilities = &cephv1.ObjectUserCapSpec{Raw: "users=read, write; buckets=*;"}
+		assert.NoError(t, ValidateUser(u))
+		u.Spec.Capabilities.Bucket = "read"
+		err := ValidateUser(u)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "mutually exclusive")
+		u.Spec.Capabilities.Bucket = ""
+		u.Spec.Preset = cephv1.ObjectUserPresetMonitoring
+		assert.Error(t, ValidateUser(u))
+	})
+}
+
+func TestUnchangedUserNoMutations(t *testing.T) {
+	created := false
+	var commands [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			commands = append(commands, args[:indexOf(args, "--rgw-realm=my-store")])
+			if args[0] == "user" && args[1] == "create" {
+				if created {
+					return userExistsOutput, nil
+				}
+				created = true
+			}
+			if args[0] == "user" || args[0] == "caps" || args[0] == "quota" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user", "create"}, commands[1][:2])
+
+	// the user that was reconciled before is only read
+	commands = nil
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, commands)
+	for _, command := range commands {
+		assert.Contains(t, []string{"get", "info", "list", "stats"}, command[1], command)
+	}
+}
+
+// capsUserJSON returns the user info of a user with the given caps
+func capsUserJSON(caps map[string]string) string {
+	list := []string{}
+	for capType, perm := range caps {
+		list = append(list, fmt.Sprintf(`{"type": %q, "perm": %q}`, capType, perm))
+	}
+	return fmt.Sprintf(`{"user_id": "my-user", "display_name": "my-user", "keys": [{"user": "my-user", "access_key": "AK", "secret_key": "SK"}], "caps": [%s]}`, strings.Join(list, ","))
+}
+
+// applyCaps returns the caps after running the given radosgw-admin caps command
+func applyCaps(caps map[string]string, command, arg string) map[string]string {
+	result := map[string]string{}
+	for capType, perm := range caps {
+		result[capType] = perm
+	}
+	// RGW adds the permission to the existing one and removes it from the existing one
+	for _, c := range strings.Split(arg, ";") {
+		parts := strings.SplitN(c, "=", 2)
+		liveRead, liveWrite := capPermBits(result[parts[0]])
+		read, write := capPermBits(parts[1])
+		perm := capPermOf(liveRead || read, liveWrite || write)
+		if command == "rm" {
+			perm = capPermOf(liveRead && !read, liveWrite && !write)
+		}
+		result[parts[0]] = perm
+		if perm == "" {
+			delete(result, parts[0])
+		}
+	}
+	return result
+}
+
+func TestTemplate(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			t.Fatalf("unexpected radosgw-admin call %v for a template", args)
+			return "", nil
+		},
+	}
+	template := newObjectUser()
+	template.Spec.DisplayName = "tenant user"
+	template.Spec.Users = []string{"a", "b"}
+	r := newReadyReconciler(executor, template)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, template)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, template.Status.Phase)
+	assert.Equal(t, []string{"my-user-a", "my-user-b"}, template.Status.Users)
+
+	generatedUser := &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "my-user-a", Namespace: namespace}, generatedUser)
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant user", generatedUser.Spec.DisplayName)
+	assert.Empty(t, generatedUser.Spec.Users)
+	assert.Equal(t, name, generatedUser.Labels[templateLabel])
+	assert.Equal(t, name, metav1.GetControllerOf(generatedUser).Name)
+
+	// a user is removed, another one is added and the spec of the remaining one is updated
+	template.Spec.Users = []string{"b", "c"}
+	template.Spec.DisplayName = "renamed"
+	err = r.client.Update(context.TODO(), template)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "my-user-a", Namespace: namespace}, generatedUser)
+	assert.True(t, kerrors.IsNotFound(err))
+	for _, user := range []string{"my-user-b", "my-user-c"} {
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: user, Namespace: namespace}, generatedUser)
+		assert.NoError(t, err)
+		assert.Equal(t, "renamed", generatedUser.Spec.DisplayName)
+	}
+
+	// the users of a template must be unique and valid names
+	template.Spec.Users = []string{"b", "b"}
+	assert.Error(t, ValidateUser(template))
+	template.Spec.Users = []string{"B_"}
+	assert.Error(t, ValidateUser(template))
+}
+
+func TestObservedGeneration(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Generation = 3
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	assert.Equal(t, int64(3), objectUser.Status.ObservedGeneration)
+
+	// a failed reconcile keeps the generation last reconciled
+	objectUser.Generation = 4
+	objectUser.Spec.OpMask = "invalid"
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.Error(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+	assert.Equal(t, int64(3), objectUser.Status.ObservedGeneration)
+}
+
+func TestListManagedSecrets(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.SecretNamespaces = []string{"app-a"}
+	otherSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: namespace}}
+	r := newReadyReconciler(executor, objectUser, otherSecret)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "object-user-controller", secret.Labels["rook.io/managed-by"])
+
+	// the secret and its copy are listed in all namespaces, other secrets are not
+	secrets, err := ListManagedSecrets(r.client, "")
+	assert.NoError(t, err)
+	listed := []string{}
+	for _, s := range secrets.Items {
+		listed = append(listed, fmt.Sprintf("%s/%s", s.Namespace, s.Name))
+	}
+	assert.ElementsMatch(t, []string{"rook-ceph/rook-ceph-object-user-my-store-my-user", "app-a/rook-ceph-object-user-my-store-my-user"}, listed)
+
+	secrets, err = ListManagedSecrets(r.client, "app-a")
+	assert.NoError(t, err)
+	assert.Len(t, secrets.Items, 1)
+}
+
+func TestSecretNamespaces(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.SecretNamespaces = []string{"app-a", "app-b", namespace}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	secretName := "rook-ceph-object-user-my-store-my-user"
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app-a", "app-b"}, objectUser.Status.SecretNamespaces)
+	for _, ns := range []string{"app-a", "app-b"} {
+		replica := &corev1.Secret{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: secretName, Namespace: ns}, replica)
+		assert.NoError(t, err)
+		assert.Equal(t, "EOE7FYCNOBZJ5VFV909G", replica.StringData["AccessKey"])
+		assert.Empty(t, replica.OwnerReferences)
+	}
+
+	// the copy in a namespace removed from the spec is deleted
+	objectUser.Spec.SecretNamespaces = []string{"app-b"}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: secretName, Namespace: "app-a"}, &corev1.Secret{})
+	assert.True(t, kerrors.IsNotFound(err))
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app-b"}, objectUser.Status.SecretNamespaces)
+
+	// the copies are deleted with the user
+	objectUser.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: secretName, Namespace: "app-b"}, &corev1.Secret{})
+	assert.True(t, kerrors.IsNotFound(err))
+
+	objectUser = newObjectUser()
+	objectUser.Spec.SecretNamespaces = []string{"App_A"}
+	assert.Error(t, ValidateUser(objectUser))
+}
+
+func TestObjectStoreNotFound(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	objectUser := newObjectUser()
+	objectUser.Spec.Store = "my-stroe"
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	res, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, objectStoreNotFoundRequeueAfter, res.RequeueAfter)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+	failure := findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure)
+	assert.Equal(t, reasonObjectStoreNotFound, failure.Reason)
+	assert.Equal(t, `CephObjectStore "my-stroe" does not exist in namespace "rook-ceph"`, failure.Message)
+	assert.Equal(t, `Warning ObjectStoreNotFound CephObjectStore "my-stroe" does not exist in namespace "rook-ceph"`, <-r.recorder.(*record.FakeRecorder).Events)
+}
+
+func TestBucketStatus(t *testing.T) {
+	buckets := []string{}
+	for i := 0; i < maxStatusBuckets+5; i++ {
+		buckets = append(buckets, fmt.Sprintf(`"bucket-%03d"`, maxStatusBuckets+4-i))
+	}
+	listed := fmt.Sprintf("[%s]", strings.Join(buckets[:2], ","))
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			if args[0] == "bucket" && args[1] == "list" {
+				assert.Equal(t, []string{"bucket", "list", "--uid", name}, args[:4])
+				return listed, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bucket-103", "bucket-104"}, objectUser.Status.Buckets)
+	assert.Equal(t, 2, objectUser.Status.BucketCount)
+
+	// the list is capped for users with many buckets
+	listed = fmt.Sprintf("[%s]", strings.Join(buckets, ","))
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Len(t, objectUser.Status.Buckets, maxStatusBuckets)
+	assert.Equal(t, "bucket-000", objectUser.Status.Buckets[0])
+	assert.Equal(t, maxStatusBuckets+5, objectUser.Status.BucketCount)
+
+	// a failed listing keeps the previous buckets
+	listed = "not json"
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, maxStatusBuckets+5, objectUser.Status.BucketCount)
+}
+
+func TestLinkBuckets(t *testing.T) {
+	owners := map[string]string{"free": "old-user", "taken": "other-user", "mine": name}
+	userBuckets := map[string][]string{"other-user": {"taken"}, "old-user": {}}
+	var linkArgs [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			switch {
+			case args[0] == "user":
+				return userCreateJSON, nil
+			case args[0] == "bucket" && args[1] == "stats":
+				if _, ok := owners[args[3]]; !ok {
+					return "", errors.New("No such file or directory")
+				}
+				return fmt.Sprintf(`{"bucket": %q, "usage": {}}`, args[3]), nil
+			case args[0] == "metadata":
+				bucket := strings.TrimPrefix(args[2], "bucket:")
+				return fmt.Sprintf(`{"data": {"owner": %q, "creation_time": "2020-01-01 00:00:00.000000Z"}}`, owners[bucket]), nil
+			case args[0] == "bucket" && args[1] == "list":
+				buckets, _ := json.Marshal(userBuckets[args[3]])
+				return string(buckets), nil
+			case args[0] == "bucket":
+				linkArgs = append(linkArgs, args[:6])
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.LinkBuckets = []string{"free", "taken", "mine", "missing"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"bucket", "link", "--uid", name, "--bucket", "free"}}, linkArgs)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	assert.Equal(t, []string{"free", "mine"}, objectUser.Status.LinkedBuckets)
+	conflict := findCondition(objectUser.Status.Conditions, cephv1.ConditionBucketLinkConflict)
+	assert.Equal(t, corev1.ConditionTrue, conflict.Status)
+	assert.Equal(t, `bucket "taken" is linked to user "other-user", bucket "missing" does not exist`, conflict.Message)
+
+	// a bucket removed from the spec is unlinked
+	owners["free"] = name
+	linkArgs = nil
+	objectUser.Spec.LinkBuckets = []string{"mine"}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"bucket", "unlink", "--uid", name, "--bucket", "free"}}, linkArgs)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mine"}, objectUser.Status.LinkedBuckets)
+	conflict = findCondition(objectUser.Status.Conditions, cephv1.ConditionBucketLinkConflict)
+	assert.Equal(t, corev1.ConditionFalse, conflict.Status)
+}
+
+func TestInitialBuckets(t *testing.T) {
+	owners := map[string]string{"mine": name, "taken": "other-user"}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			switch {
+			case args[0] == "user":
+				return userCreateJSON, nil
+			case args[0] == "bucket" && args[1] == "stats":
+				if _, ok := owners[args[3]]; !ok {
+					return "", errors.New("No such file or directory")
+				}
+				return fmt.Sprintf(`{"bucket": %q, "usage": {}}`, args[3]), nil
+			case args[0] == "metadata":
+				bucket := strings.TrimPrefix(args[2], "bucket:")
+				return fmt.Sprintf(`{"data": {"owner": %q, "creation_time": "2020-01-01 00:00:00.000000Z"}}`, owners[bucket]), nil
+			case args[0] == "bucket" && args[1] == "list":
+				return "[]", nil
+			}
+			return "", nil
+		},
+	}
+	var created []string
+	createBucketFails := false
+	defer func(create func(endpoint, accessKey, secretKey, name string) error) { createBucket = create }(createBucket)
+	createBucket = func(endpoint, accessKey, secretKey, bucket string) error {
+		if createBucketFails {
+			return errors.New("connection refused")
+		}
+		assert.Equal(t, "http://rook-ceph-rgw-my-store.rook-ceph:0", endpoint)
+		assert.NotEmpty(t, accessKey)
+		created = append(created, bucket)
+		owners[bucket] = name
+		return nil
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.InitialBuckets = []string{"new", "mine", "taken"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// only the missing bucket is created, the bucket of another user is a conflict
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new"}, created)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	assert.Equal(t, []string{"new", "mine"}, objectUser.Status.InitialBuckets)
+	conflict := findCondition(objectUser.Status.Conditions, cephv1.ConditionInitialBucketConflict)
+	assert.Equal(t, corev1.ConditionTrue, conflict.Status)
+	assert.Equal(t, `bucket "taken" is owned by user "other-user"`, conflict.Message)
+
+	// a created bucket is not created again, even once the user deleted it
+	created = nil
+	delete(owners, "new")
+	objectUser.Spec.InitialBuckets = []string{"new", "mine"}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, created)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new", "mine"}, objectUser.Status.InitialBuckets)
+	conflict = findCondition(objectUser.Status.Conditions, cephv1.ConditionInitialBucketConflict)
+	assert.Equal(t, corev1.ConditionFalse, conflict.Status)
+
+	// a bucket that cannot be created fails the user
+	createBucketFails = true
+	objectUser.Spec.InitialBuckets = []string{"new", "mine", "other"}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.Error(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+	failure := findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure)
+	assert.Equal(t, reasonInitialBucketsFailed, failure.Reason)
+	assert.Contains(t, failure.Message, `failed to create initial bucket "other"`)
+
+	t.Run("validation", func(t *testing.T) {
+		u := newObjectUser()
+		u.Spec.InitialBuckets = []string{"my-bucket", "my.bucket"}
+		assert.NoError(t, ValidateUser(u))
+		for _, bucket := range []string{"ab", "My-Bucket", "-bucket", "bucket-", "my..bucket", "my_bucket", strings.Repeat("a", 64)} {
+			u.Spec.InitialBuckets = []string{bucket}
+			assert.Error(t, ValidateUser(u), bucket)
+		}
+		u.Spec.InitialBuckets = []string{"my-bucket", "my-bucket"}
+		assert.Error(t, ValidateUser(u))
+		u.Spec.InitialBuckets = []string{"my-bucket"}
+		u.Spec.SuppressUserKeys = true
+		assert.Error(t, ValidateUser(u))
+		u.Spec.SuppressUserKeys = false
+		u.Spec.Users = []string{"a", "b"}
+		assert.Error(t, ValidateUser(u))
+	})
+}
+
+func TestInitialBucketVersioning(t *testing.T) {
+	owners := map[string]string{"mine": name}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			switch {
+			case args[0] == "user":
+				return userCreateJSON, nil
+			case args[0] == "bucket" && args[1] == "stats":
+				if _, ok := owners[args[3]]; !ok {
+					return "", errors.New("No such file or directory")
+				}
+				return fmt.Sprintf(`{"bucket": %q, "usage": {}}`, args[3]), nil
+			case args[0] == "metadata":
+				bucket := strings.TrimPrefix(args[2], "bucket:")
+				return fmt.Sprintf(`{"data": {"owner": %q, "creation_time": "2020-01-01 00:00:00.000000Z"}}`, owners[bucket]), nil
+			case args[0] == "bucket" && args[1] == "list":
+				return "[]", nil
+			}
+			return "", nil
+		},
+	}
+	defer func(create func(endpoint, accessKey, secretKey, name string) error) { createBucket = create }(createBucket)
+	createBucket = func(endpoint, accessKey, secretKey, bucket string) error {
+		owners[bucket] = name
+		return nil
+	}
+	var versioned []string
+	versioningFails := true
+	defer func(enable func(endpoint, accessKey, secretKey, name string) error) { enableBucketVersioning = enable }(enableBucketVersioning)
+	enableBucketVersioning = func(endpoint, accessKey, secretKey, bucket string) error {
+		if versioningFails {
+			return errors.New("connection refused")
+		}
+		assert.Equal(t, "http://rook-ceph-rgw-my-store.rook-ceph:0", endpoint)
+		assert.NotEmpty(t, accessKey)
+		versioned = append(versioned, bucket)
+		return nil
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.InitialBuckets = []string{"new", "mine"}
+	objectUser.Spec.DefaultBucketVersioning = true
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// a bucket whose versioning could not be enabled is not tracked
+	_, err := r.reconcile(req)
+	assert.Error(t, err)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, reasonInitialBucketsFailed, findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure).Reason)
+	assert.Empty(t, objectUser.Status.InitialBuckets)
+
+	// the versioning of the created and the owned buckets is enabled once they are tracked
+	versioningFails = false
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new", "mine"}, versioned)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new", "mine"}, objectUser.Status.InitialBuckets)
+
+	// the versioning of the tracked buckets is left to the user
+	versioned = nil
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, versioned)
+
+	t.Run("validation", func(t *testing.T) {
+		u := newObjectUser()
+		u.Spec.DefaultBucketVersioning = true
+		assert.Error(t, ValidateUser(u))
+		u.Spec.InitialBuckets = []string{"my-bucket"}
+		assert.NoError(t, ValidateUser(u))
+	})
+}
+
+func TestFailedStep(t *testing.T) {
+	quotaFails := true
+	liveCaps := map[string]string{}
+	var quotaCalls int
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			switch {
+			case args[0] == "user" && args[1] == "create":
+				return userExistsOutput, nil
+			case args[0] == "caps":
+				liveCaps = applyCaps(liveCaps, args[1], args[5])
+				return "", nil
+			case args[0] == "quota":
+				quotaCalls++
+				if quotaFails {
+					return "", errors.New("quota set failed")
+				}
+				return "", nil
+			}
+			return capsUserJSON(liveCaps), nil
+		},
+	}
+	objectUser := newObjectUser()
+	maxSize := resource.MustParse("10G")
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxSize: &maxSize}
+	objectUser.Spec.Capabilities = &cephv1.ObjectUserCapSpec{User: "read"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the quota fails after the caps were set
+	_, err := r.reconcile(req)
+	assert.Error(t, err)
+	stepErr, ok := errors.Cause(err).(*StepError)
+	assert.True(t, ok)
+	assert.Equal(t, "quota", stepErr.Step)
+	assert.Equal(t, []string{"caps"}, stepErr.Completed)
+	assert.Equal(t, map[string]string{"users": "read"}, liveCaps)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+	assert.Equal(t, "quota", objectUser.Status.FailedStep)
+	assert.Contains(t, findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure).Message, `step "quota" failed, completed steps: caps`)
+	// the steps after the quota were not run
+	assert.Equal(t, 2, len(objectUser.Status.ComponentStatus))
+	assert.Equal(t, "Applied", objectUser.Status.ComponentStatus["caps"])
+	assert.True(t, strings.HasPrefix(objectUser.Status.ComponentStatus["quota"], "Failed: failed to set quota"))
+	assert.True(t, strings.HasSuffix(objectUser.Status.ComponentStatus["quota"], "quota set failed"))
+
+	// the next reconcile resumes with the quota
+	quotaFails = false
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	// the failed quota set is followed by a set and an enable
+	assert.Equal(t, 3, quotaCalls)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	assert.Empty(t, objectUser.Status.FailedStep)
+	assert.Equal(t, "Applied", objectUser.Status.ComponentStatus["quota"])
+	assert.Equal(t, "Applied", objectUser.Status.ComponentStatus["subusers"])
+	for step, outcome := range objectUser.Status.ComponentStatus {
+		assert.Equal(t, "Applied", outcome, step)
+	}
+}
+
+func TestSyncStats(t *testing.T) {
+	for _, syncStats := range []bool{false, true} {
+		t.Run(fmt.Sprintf("%t", syncStats), func(t *testing.T) {
+			var commands []string
+			executor := &exectest.MockExecutor{
+				MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+					commands = append(commands, strings.Join(args[:2], " "))
+					// the usage of the user is also read after the quota is set
+					if args[0] == "user" && args[1] == "stats" && contains(args, "--sync-stats") {
+						assert.Equal(t, []string{"user", "stats", "--uid", name, "--sync-stats"}, args[:5])
+						commands[len(commands)-1] = "user sync-stats"
+					}
+					if args[0] == "user" {
+						return userCreateJSON, nil
+					}
+					return "", nil
+				},
+			}
+			objectUser := newObjectUser()
+			maxObjects := int64(500)
+			objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxObjects: &maxObjects, SyncStats: syncStats}
+			r := newReadyReconciler(executor, objectUser)
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+			_, err := r.reconcile(req)
+			assert.NoError(t, err)
+			assert.Equal(t, syncStats, contains(commands, "user sync-stats"))
+			if syncStats {
+				// the stats are synced after the quota is set
+				assert.Equal(t, []string{"quota set", "quota enable", "user sync-stats"}, commands[indexOf(commands, "quota set"):indexOf(commands, "user sync-stats")+1])
+			}
+		})
+	}
+}
+
+func TestTempURLKeys(t *testing.T) {
+	tempURLKeys := map[int]string{}
+	var keyArgs [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" && args[1] == "modify" && strings.HasPrefix(args[4], "--temp-url-key") {
+				keyArgs = append(keyArgs, args[:5])
+				keyID := 0
+				if args[4] == "--temp-url-key-2" {
+					keyID = 1
+				}
+				tempURLKeys[keyID] = args[5]
+			}
+			keys := []string{}
+			for keyID, key := range tempURLKeys {
+				keys = append(keys, fmt.Sprintf(`{"key": %d, "val": %q}`, keyID, key))
+			}
+			return fmt.Sprintf(`{"user_id": "my-user", "display_name": "my-user", "keys": [{"user": "my-user", "access_key": "AK", "secret_key": "SK"}], "temp_url_keys": [%s]}`, strings.Join(keys, ",")), nil
+		},
+	}
+	keySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-temp-url-key", Namespace: namespace},
+		Data:       map[string][]byte{"TempURLKey": []byte("provided")},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.TempURLKeys = []cephv1.TempURLKeySpec{
+		{ID: 1, SecretName: "my-temp-url-key"},
+		{ID: 0, Generate: true},
+	}
+	r := newReadyReconciler(executor, objectUser, keySecret)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"user", "modify", "--uid", name, "--temp-url-key-2"},
+		{"user", "modify", "--uid", name, "--temp-url-key"},
+	}, keyArgs)
+	assert.Equal(t, "provided", tempURLKeys[1])
+	assert.Len(t, tempURLKeys[0], 40)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, tempURLKeys[0], secret.StringData["TempURLKey_0"])
+	assert.Equal(t, "provided", secret.StringData["TempURLKey_1"])
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, objectUser.Status.TempURLKeys)
+
+	// the keys are not set again
+	keyArgs = nil
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Nil(t, keyArgs)
+
+	// a key removed from the spec is removed from the user
+	objectUser.Spec.TempURLKeys = objectUser.Spec.TempURLKeys[1:]
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"user", "modify", "--uid", name, "--temp-url-key-2"}}, keyArgs)
+	assert.Equal(t, "", tempURLKeys[1])
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, objectUser.Status.TempURLKeys)
+
+	objectUser.Spec.TempURLKeys = []cephv1.TempURLKeySpec{{ID: 2, Generate: true}}
+	assert.Error(t, ValidateUser(objectUser))
+	objectUser.Spec.TempURLKeys = []cephv1.TempURLKeySpec{{ID: 0}}
+	assert.Error(t, ValidateUser(objectUser))
+	objectUser.Spec.TempURLKeys = []cephv1.TempURLKeySpec{{ID: 0, Generate: true}, {ID: 0, SecretName: "my-temp-url-key"}}
+	assert.Error(t, ValidateUser(objectUser))
+}
+
+func TestCephClusterHealth(t *testing.T) {
+	tests := []struct {
+		health            string
+		blockOnHealthWarn bool
+		phase             cephv1.ObjectStoreUserPhase
+		message           string
+	}{
+		{"HEALTH_OK", false, cephv1.ObjectStoreUserPhaseReady, "the object store user is reconciled"},
+		{"HEALTH_WARN", false, cephv1.ObjectStoreUserPhaseReady, "the object store user is reconciled while the CephCluster is HEALTH_WARN"},
+		{"HEALTH_WARN", true, cephv1.ObjectStoreUserPhaseReconciling, `waiting for the CephCluster in namespace "rook-ceph" to recover from HEALTH_WARN`},
+		{"HEALTH_ERR", false, cephv1.ObjectStoreUserPhaseReconciling, `waiting for the CephCluster in namespace "rook-ceph" to recover from HEALTH_ERR`},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s-%t", test.health, test.blockOnHealthWarn), func(t *testing.T) {
+			created := false
+			executor := &exectest.MockExecutor{
+				MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+					if args[0] == "user" {
+						created = created || args[1] == "create"
+						return userCreateJSON, nil
+					}
+					return "", nil
+				},
+			}
+			objectUser := newObjectUser()
+			r := newReadyReconciler(executor, objectUser)
+			r.blockOnHealthWarn = test.blockOnHealthWarn
+			cephCluster := &cephv1.CephCluster{}
+			err := r.client.Get(context.TODO(), types.NamespacedName{Name: namespace, Namespace: namespace}, cephCluster)
+			assert.NoError(t, err)
+			cephCluster.Status.CephStatus = &cephv1.CephStatus{Health: test.health}
+			err = r.client.Update(context.TODO(), cephCluster)
+			assert.NoError(t, err)
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+			result, err := r.reconcile(req)
+			assert.NoError(t, err)
+			err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+			assert.NoError(t, err)
+			assert.Equal(t, test.phase, objectUser.Status.Phase)
+			assert.Equal(t, test.phase == cephv1.ObjectStoreUserPhaseReady, created)
+			if test.phase == cephv1.ObjectStoreUserPhaseReady {
+				assert.Equal(t, test.message, findCondition(objectUser.Status.Conditions, cephv1.ConditionReady).Message)
+			} else {
+				assert.True(t, result.Requeue)
+				progressing := findCondition(objectUser.Status.Conditions, cephv1.ConditionProgressing)
+				assert.Equal(t, reasonCephClusterUnhealthy, progressing.Reason)
+				assert.Equal(t, test.message, progressing.Message)
+			}
+		})
+	}
+}
+
+func TestAdminCommandStats(t *testing.T) {
+	calls := 0
+	createFails := true
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			calls++
+			if args[0] == "user" && args[1] == "create" && createFails {
+				return "", errors.New("connection refused")
+			}
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the failed reconcile counts its failed command
+	_, err := r.reconcile(req)
+	assert.Error(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	stats := objectUser.Status.AdminCommandStats
+	assert.Equal(t, []cephv1.ObjectUserAdminCommandCounts{{Calls: calls, Failures: 1}}, stats.Reconciles)
+	assert.Equal(t, "radosgw-admin user create: connection refused", stats.LastError)
+
+	// the counts of each reconcile are kept, up to the last reconciles
+	createFails = false
+	expected := stats.Reconciles
+	for i := 0; i < maxAdminStatsReconciles; i++ {
+		calls = 0
+		_, err = r.reconcile(req)
+		assert.NoError(t, err)
+		expected = append(expected, cephv1.ObjectUserAdminCommandCounts{Calls: calls})
+	}
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	stats = objectUser.Status.AdminCommandStats
+	assert.Len(t, stats.Reconciles, maxAdminStatsReconciles)
+	assert.Equal(t, expected[1:], stats.Reconciles)
+	assert.NotZero(t, stats.Reconciles[0].Calls)
+	assert.Equal(t, "radosgw-admin user create: connection refused", stats.LastError)
+}
+
+func TestCephClusterWithoutCephStatus(t *testing.T) {
+	created := false
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				created = created || args[1] == "create"
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	cephCluster := &cephv1.CephCluster{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: namespace, Namespace: namespace}, cephCluster)
+	assert.NoError(t, err)
+	cephCluster.Status.CephStatus = nil
+	err = r.client.Update(context.TODO(), cephCluster)
+	assert.NoError(t, err)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the user waits for the first ceph status of the cluster
+	result, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.True(t, result.Requeue)
+	assert.False(t, created)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconciling, objectUser.Status.Phase)
+	progressing := findCondition(objectUser.Status.Conditions, cephv1.ConditionProgressing)
+	assert.Equal(t, reasonCephClusterNotReady, progressing.Reason)
+	assert.Equal(t, `waiting for the CephCluster in namespace "rook-ceph" to report its health`, progressing.Message)
+
+	// the user is reconciled once the health is reported
+	cephCluster.Status.CephStatus = &cephv1.CephStatus{Health: "HEALTH_OK"}
+	err = r.client.Update(context.TODO(), cephCluster)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+}
+
+func indexOf(list []string, item string) int {
+	for i, value := range list {
+		if value == item {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRealm(t *testing.T) {
+	var commands int
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			assert.Contains(t, args, "--rgw-realm="+store)
+			if args[0] == "user" {
+				commands++
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Realm = "other-realm"
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// a user expected in another realm is not created in the realm of the store
+	_, err := r.reconcile(req)
+	assert.Error(t, err)
+	assert.Equal(t, 0, commands)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReconcileFailed, objectUser.Status.Phase)
+	assert.Equal(t, store, objectUser.Status.Realm)
+	assert.Contains(t, findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure).Message, `realm "other-realm" does not match realm "my-store"`)
+
+	objectUser.Spec.Realm = store
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.NotEqual(t, 0, commands)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	assert.Equal(t, store, objectUser.Status.Realm)
+}
+
+func TestExpiry(t *testing.T) {
+	suspended := false
+	var suspensions []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && (args[1] == "suspend" || args[1] == "enable") {
+				suspensions = append(suspensions, args[1])
+				suspended = args[1] == "suspend"
+			}
+			if args[0] == "user" {
+				if suspended {
+					return strings.Replace(userCreateJSON, `"suspended": 0`, `"suspended": 1`, 1), nil
+				}
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.ExpiresAt = &metav1.Time{Time: time.Now().Add(time.Hour)}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// a user that did not expire yet is requeued to be suspended once it expires
+	result, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, suspensions)
+	assert.True(t, result.RequeueAfter > 59*time.Minute && result.RequeueAfter <= time.Hour+time.Second, result.RequeueAfter)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	assert.Regexp(t, `^(59m|1h0m)\d+s$`, objectUser.Status.ExpiresIn)
+	assert.Nil(t, findCondition(objectUser.Status.Conditions, cephv1.ConditionExpired))
+
+	// an expired user is suspended but not deleted
+	objectUser.Spec.ExpiresAt = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	result, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"suspend"}, suspensions)
+	assert.Equal(t, time.Duration(0), result.RequeueAfter)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	assert.Empty(t, objectUser.Status.ExpiresIn)
+	assert.Equal(t, corev1.ConditionTrue, findCondition(objectUser.Status.Conditions, cephv1.ConditionExpired).Status)
+
+	// the suspended user is not suspended again
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"suspend"}, suspensions)
+
+	// moving the expiry to the future enables the user again
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	objectUser.Spec.ExpiresAt = &metav1.Time{Time: time.Now().Add(time.Hour)}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"suspend", "enable"}, suspensions)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, objectUser.Status.ExpiresIn)
+	assert.Equal(t, corev1.ConditionFalse, findCondition(objectUser.Status.Conditions, cephv1.ConditionExpired).Status)
+
+	t.Run("suspended by hand", func(t *testing.T) {
+		// a user suspended by other means is not enabled by its expiry
+		suspended = true
+		suspensions = nil
+		_, err = r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Empty(t, suspensions)
+	})
+}
+
+func TestMaxBucketsCleared(t *testing.T) {
+	liveMaxBuckets := 1000
+	var modifyArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "modify" {
+				modifyArgs = args
+				liveMaxBuckets, _ = strconv.Atoi(args[indexOf(args, "--max-buckets")+1])
+			}
+			if args[0] == "user" {
+				return strings.Replace(userCreateJSON, `"max_buckets": 1000`, fmt.Sprintf(`"max_buckets": %d`, liveMaxBuckets), 1), nil
+			}
+			return "", nil
+		},
+	}
+	maxBuckets := 5
+	objectUser := newObjectUser()
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxBuckets: &maxBuckets}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, liveMaxBuckets)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.True(t, objectUser.Status.MaxBucketsSet)
+
+	// clearing the quota block restores the default instead of keeping the old limit
+	objectUser.Spec.Quotas = nil
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, liveMaxBuckets)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.False(t, objectUser.Status.MaxBucketsSet)
+
+	// the max buckets is left untouched from then on
+	modifyArgs = nil
+	liveMaxBuckets = 20
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Nil(t, modifyArgs)
+	assert.Equal(t, 20, liveMaxBuckets)
+}
+
+func TestParallelReconciles(t *testing.T) {
+	const users = 8
+	var mutex sync.Mutex
+	creating := map[string]bool{}
+	allCreating := make(chan struct{})
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				// Every user waits for the others, which only succeeds if they are reconciled at the same time
+				mutex.Lock()
+				creating[args[3]] = true
+				if len(creating) == users {
+					close(allCreating)
+				}
+				mutex.Unlock()
+				select {
+				case <-allCreating:
+				case <-time.After(5 * time.Second):
+					return "", errors.New("timed out waiting for the other users")
+				}
+				return strings.Replace(userCreateJSON, `"user_id": "my-user"`, fmt.Sprintf(`"user_id": %q`, args[3]), 1), nil
+			}
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objects := []runtime.Object{}
+	for i := 0; i < users; i++ {
+		objectUser := newObjectUser()
+		objectUser.Name = fmt.Sprintf("user-%d", i)
+		objects = append(objects, objectUser)
+	}
+	r := newReadyReconciler(executor, objects...)
+	r.recorder = record.NewFakeRecorder(100)
+
+	var wg sync.WaitGroup
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: fmt.Sprintf("user-%d", i), Namespace: namespace}}
+			_, err := r.Reconcile(req)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < users; i++ {
+		objectUser := &cephv1.CephObjectStoreUser{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: fmt.Sprintf("user-%d", i), Namespace: namespace}, objectUser)
+		assert.NoError(t, err)
+		assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase, objectUser.Name)
+	}
+	assert.Empty(t, r.failures)
+}
+
+func TestSecretKeyDrift(t *testing.T) {
+	liveAccessKey, liveSecretKey := "EOE7FYCNOBZJ5VFV909G", "qmIqpWm8HxCzmynCrD6U6vKWi4hnDBndOnmxXNsV"
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "user" {
+				userJSON := strings.Replace(userCreateJSON, "EOE7FYCNOBZJ5VFV909G", liveAccessKey, 1)
+				return strings.Replace(userJSON, "qmIqpWm8HxCzmynCrD6U6vKWi4hnDBndOnmxXNsV", liveSecretKey, 1), nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	recorder := record.NewFakeRecorder(10)
+	r.recorder = recorder
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, recorder.Events)
+
+	// the key is rotated with radosgw-admin, outside of the operator
+	liveAccessKey, liveSecretKey = "ROTATEDACCESSKEY0000", "rotatedsecretkey0000000000000000000000000"
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "ROTATEDACCESSKEY0000", secret.StringData["AccessKey"])
+	assert.Equal(t, "rotatedsecretkey0000000000000000000000000", secret.StringData["SecretKey"])
+	assert.Contains(t, <-recorder.Events, reasonSecretKeysRepaired)
+
+	// a secret that matches the keys is left alone
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, recorder.Events)
+}
+
+func TestUID(t *testing.T) {
+	uid := "team-a$service"
+	var uids []string
+	var deleteArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "rm" {
+				deleteArgs = args[:4]
+			}
+			if i := indexOf(args, "--uid"); i >= 0 {
+				uids = append(uids, args[i+1])
+			}
+			if args[0] == "user" {
+				return strings.Replace(userCreateJSON, `"user_id": "my-user"`, `"user_id": "team-a$service"`, 1), nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Name = "foo"
+	objectUser.Spec.UID = uid
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "foo", Namespace: namespace}}
+
+	// every radosgw-admin command runs against the uid of the spec rather than the name of the CR
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, uids)
+	for _, id := range uids {
+		assert.Equal(t, uid, id)
+	}
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	assert.Equal(t, uid, objectUser.Status.UID)
+
+	// the secret is named after the CR and annotated with the uid
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-foo", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, uid, secret.Annotations[userIDAnnotation])
+	assert.Equal(t, "foo", secret.Labels["user"])
+
+	// the uid cannot be changed once the user is created
+	objectUser.Spec.UID = "team-b$service"
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	uids = nil
+	_, err = r.reconcile(req)
+	assert.Error(t, err)
+	assert.Empty(t, uids)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Contains(t, findCondition(objectUser.Status.Conditions, cephv1.ConditionFailure).Message, `uid cannot be changed from "team-a$service" to "team-b$service"`)
+
+	// the user that was created is deleted with the CR
+	objectUser.Finalizers = []string{"cephobjectstoreuser.ceph.rook.io"}
+	objectUser.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user", "rm", "--uid", uid}, deleteArgs)
+
+	t.Run("validation", func(t *testing.T) {
+		for _, test := range []struct {
+			uid   string
+			valid bool
+		}{
+			{"service", true},
+			{"team-a$service", true},
+			{"team-a$", false},
+			{"$service", false},
+			{"a$b$c", false},
+			{"team-a$service:swift", false},
+			{"team a", false},
+			{"team/a", false},
+		} {
+			u := newObjectUser()
+			u.Spec.UID = test.uid
+			err := ValidateUser(u)
+			assert.Equal(t, test.valid, err == nil, test.uid)
+		}
+
+		u := newObjectUser()
+		u.Spec.UID = "service"
+		u.Spec.Users = []string{"a", "b"}
+		assert.Error(t, ValidateUser(u))
+	})
+}
+
+func TestOverQuota(t *testing.T) {
+	objects := 20
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "stats" {
+				return fmt.Sprintf(`{"stats": {"size": 4096, "num_objects": %d}}`, objects), nil
+			}
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	maxObjects := int64(10)
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxObjects: &maxObjects}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// a quota below the usage is applied and reported, but does not fail the reconcile
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	condition := findCondition(objectUser.Status.Conditions, cephv1.ConditionOverQuota)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+	assert.Equal(t, "UsageExceedsQuota", condition.Reason)
+	assert.Contains(t, condition.Message, "20 objects exceeds the user quota of -1 bytes and 10 objects")
+
+	// the condition is cleared once the usage is within the quota
+	objects = 5
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.ConditionFalse, findCondition(objectUser.Status.Conditions, cephv1.ConditionOverQuota).Status)
+}
+
+func TestQuotaUtilization(t *testing.T) {
+	size := 8300
+	info := strings.Replace(userCreateJSON, `"user_quota": {
+		"enabled": false,
+		"check_on_raw": false,
+		"max_size": -1,`, `"user_quota": {
+		"enabled": true,
+		"check_on_raw": false,
+		"max_size": 10000,`, 1)
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "stats" {
+				return fmt.Sprintf(`{"stats": {"size": %d, "num_objects": 3}}`, size), nil
+			}
+			if args[0] == "user" {
+				return info, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	maxSize := resource.MustParse("10000")
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxSize: &maxSize}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, 83, *objectUser.Status.QuotaUtilizationPercent)
+
+	t.Run("updated each reconcile", func(t *testing.T) {
+		size = 12000
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		objectUser := &cephv1.CephObjectStoreUser{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Equal(t, 120, *objectUser.Status.QuotaUtilizationPercent)
+	})
+
+	t.Run("unlimited size quota", func(t *testing.T) {
+		info = userCreateJSON
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		objectUser := &cephv1.CephObjectStoreUser{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Nil(t, objectUser.Status.QuotaUtilizationPercent)
+	})
+}
+
+func TestSuspendOnBreach(t *testing.T) {
+	size := 12000
+	suspended := false
+	suspensions := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "stats" {
+				return fmt.Sprintf(`{"stats": {"size": %d, "num_objects": 3}}`, size), nil
+			}
+			if args[0] == "user" && (args[1] == "suspend" || args[1] == "enable") {
+				suspensions = append(suspensions, args[1])
+				suspended = args[1] == "suspend"
+			}
+			if args[0] == "user" {
+				info := strings.Replace(userCreateJSON, `"user_quota": {
+		"enabled": false,
+		"check_on_raw": false,
+		"max_size": -1,`, `"user_quota": {
+		"enabled": true,
+		"check_on_raw": false,
+		"max_size": 10000,`, 1)
+				if suspended {
+					info = strings.Replace(info, `"suspended": 0`, `"suspended": 1`, 1)
+				}
+				return info, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	maxSize := resource.MustParse("10000")
+	objectUser.Spec.Quotas = &cephv1.ObjectUserQuotaSpec{MaxSize: &maxSize, SuspendOnBreach: true}
+	r := newReadyReconciler(executor, objectUser)
+	recorder := record.NewFakeRecorder(10)
+	r.recorder = recorder
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// The usage exceeds the quota, the user is suspended
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"suspend"}, suspensions)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	condition := findCondition(objectUser.Status.Conditions, cephv1.ConditionSuspendedOnQuotaBreach)
+	assert.NotNil(t, condition)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+	assert.Contains(t, <-recorder.Events, "SuspendedOnQuotaBreach")
+
+	t.Run("still breached", func(t *testing.T) {
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"suspend"}, suspensions)
+	})
+
+	t.Run("usage within quota enables the user", func(t *testing.T) {
+		size = 8000
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"suspend", "enable"}, suspensions)
+		objectUser := &cephv1.CephObjectStoreUser{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		condition := findCondition(objectUser.Status.Conditions, cephv1.ConditionSuspendedOnQuotaBreach)
+		assert.Equal(t, corev1.ConditionFalse, condition.Status)
+		assert.Contains(t, <-recorder.Events, "EnabledWithinQuota")
+	})
+
+	t.Run("user suspended by other means is left suspended", func(t *testing.T) {
+		suspended = true
+		suspensions = nil
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Empty(t, suspensions)
+		assert.True(t, suspended)
+	})
+}
+
+func TestKeyRotation(t *testing.T) {
+	keys := []string{"EOE7FYCNOBZJ5VFV909G"}
+	userJSON := func() string {
+		entries := []string{}
+		for _, key := range keys {
+			entries = append(entries, fmt.Sprintf(`{"user": "my-user", "access_key": %q, "secret_key": "secret-%s"}`, key, key))
+		}
+		return strings.Replace(userCreateJSON, `"keys": [
+		{
+			"user": "my-user",
+			"access_key": "EOE7FYCNOBZJ5VFV909G",
+			"secret_key": "qmIqpWm8HxCzmynCrD6U6vKWi4hnDBndOnmxXNsV"
+		}
+	],`, fmt.Sprintf(`"keys": [%s],`, strings.Join(entries, ",")), 1)
+	}
+	var r *ReconcileObjectStoreUser
+	removedWithSecret := map[string]string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "key" && args[1] == "create" {
+				keys = append(keys, fmt.Sprintf("ROTATED%d", len(keys)))
+			}
+			if args[0] == "key" && args[1] == "rm" {
+				accessKey := args[indexOf(args, "--access-key")+1]
+				for i, key := range keys {
+					if key == accessKey {
+						keys = append(keys[:i], keys[i+1:]...)
+						break
+					}
+				}
+				// the secret must already hold the new key
+				secret := &corev1.Secret{}
+				err := r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+				assert.NoError(t, err)
+				removedWithSecret[accessKey] = secret.StringData["AccessKey"]
+			}
+			if args[0] == "user" || args[0] == "key" {
+				return userJSON(), nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.KeyRotation = &cephv1.KeyRotationSpec{IntervalDays: 30}
+	r = newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	defer func() { now = time.Now }()
+
+	// the first rotation is scheduled after the interval, and the user is requeued for it
+	result, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.True(t, result.RequeueAfter > 29*24*time.Hour && result.RequeueAfter <= 30*24*time.Hour+time.Second, result.RequeueAfter)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Nil(t, objectUser.Status.KeyRotation.LastRotation)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), objectUser.Status.KeyRotation.NextRotation.Time, 2*time.Second)
+	assert.Equal(t, "EOE7FYCNOBZJ5VFV909G", objectUser.Status.KeyRotation.AccessKey)
+	assert.Equal(t, []string{"EOE7FYCNOBZJ5VFV909G"}, keys)
+
+	t.Run("rotated once due", func(t *testing.T) {
+		rotatedAt := time.Now().Add(31 * 24 * time.Hour)
+		now = func() time.Time { return rotatedAt }
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		// the new key is in the secret before the previous key is removed
+		assert.Equal(t, []string{"ROTATED1"}, keys)
+		assert.Equal(t, map[string]string{"EOE7FYCNOBZJ5VFV909G": "ROTATED1"}, removedWithSecret)
+		objectUser := &cephv1.CephObjectStoreUser{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Equal(t, rotatedAt.Truncate(time.Second).Unix(), objectUser.Status.KeyRotation.LastRotation.Unix())
+		assert.Equal(t, rotatedAt.Truncate(time.Second).Add(30*24*time.Hour).Unix(), objectUser.Status.KeyRotation.NextRotation.Unix())
+		assert.Equal(t, "ROTATED1", objectUser.Status.KeyRotation.AccessKey)
+	})
+
+	t.Run("not rotated again before the next rotation", func(t *testing.T) {
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ROTATED1"}, keys)
+	})
+
+	t.Run("interval validated", func(t *testing.T) {
+		objectUser := newObjectUser()
+		objectUser.Spec.KeyRotation = &cephv1.KeyRotationSpec{}
+		assert.Error(t, ValidateUser(objectUser))
+		objectUser.Spec.KeyRotation.IntervalDays = 30
+		objectUser.Spec.Adopt = true
+		assert.Error(t, ValidateUser(objectUser))
+	})
+}
+
+func TestDeletionGracePeriod(t *testing.T) {
+	var deletions int
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "rm" {
+				deletions++
+			}
+			return "", nil
+		},
+	}
+	newDeletedUser := func(deletedAgo time.Duration) *cephv1.CephObjectStoreUser {
+		objectUser := newObjectUser()
+		objectUser.Finalizers = []string{"cephobjectstoreuser.ceph.rook.io"}
+		objectUser.DeletionTimestamp = &metav1.Time{Time: time.Now().Add(-deletedAgo)}
+		objectUser.Spec.DeletionGracePeriod = &metav1.Duration{Duration: time.Hour}
+		return objectUser
+	}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the user is kept until the grace period passed
+	r := newReadyReconciler(executor, newDeletedUser(30*time.Minute))
+	result, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, deletions)
+	assert.True(t, result.RequeueAfter > 29*time.Minute && result.RequeueAfter <= 30*time.Minute, result.RequeueAfter)
+	objectUser := &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cephobjectstoreuser.ceph.rook.io"}, objectUser.Finalizers)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), objectUser.Status.DeletionScheduledAt.Time, 2*time.Second)
+	assert.Equal(t, reasonDeletionScheduled, findCondition(objectUser.Status.Conditions, cephv1.ConditionProgressing).Reason)
+
+	// the annotation deletes the user right away
+	objectUser.Annotations = map[string]string{forceDeleteAnnotation: "true"}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deletions)
+
+	// the user is deleted once the grace period passed
+	r = newReadyReconciler(executor, newDeletedUser(2*time.Hour))
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deletions)
+
+	assert.Error(t, ValidateUser(&cephv1.CephObjectStoreUser{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       cephv1.ObjectStoreUserSpec{Store: store, DeletionGracePeriod: &metav1.Duration{Duration: -time.Hour}},
+	}))
+}
+
+func TestObjectStoreEndpointChange(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.SecretFormat = secretFormatStandard
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	objectStore := &cephv1.CephObjectStore{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: store, Namespace: namespace}, objectStore)
+	assert.NoError(t, err)
+	objectStore.Spec.Gateway.Port = 80
+	err = r.client.Update(context.TODO(), objectStore)
+	assert.NoError(t, err)
+	endpoint := func() string {
+		secret := &corev1.Secret{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+		assert.NoError(t, err)
+		return secret.StringData[endpointKey]
+	}
+
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://rook-ceph-rgw-my-store.rook-ceph:80", endpoint())
+
+	// the endpoint follows the store to TLS on the next reconcile, nothing is cached across reconciles
+	objectStore.Spec.Gateway.Port = 0
+	objectStore.Spec.Gateway.SecurePort = 443
+	err = r.client.Update(context.TODO(), objectStore)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://rook-ceph-rgw-my-store.rook-ceph:443", endpoint())
+}
+
+func TestTags(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.Tags = map[string]string{"cost-center": "4711", "team": "storage"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	secretName := types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}
+
+	// the tags are annotations of the secret and are reported in the status
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), secretName, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "4711", secret.Annotations["tags.ceph.rook.io/cost-center"])
+	assert.Equal(t, "storage", secret.Annotations["tags.ceph.rook.io/team"])
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"cost-center": "4711", "team": "storage"}, objectUser.Status.Tags)
+
+	// a tag removed from the spec is removed from the secret
+	objectUser.Spec.Tags = map[string]string{"cost-center": "4712"}
+	err = r.client.Update(context.TODO(), objectUser)
+	assert.NoError(t, err)
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	secret = &corev1.Secret{}
+	err = r.client.Get(context.TODO(), secretName, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "4712", secret.Annotations["tags.ceph.rook.io/cost-center"])
+	assert.NotContains(t, secret.Annotations, "tags.ceph.rook.io/team")
+
+	u := newObjectUser()
+	u.Spec.Tags = map[string]string{"cost center": "4711"}
+	assert.Error(t, ValidateUser(u))
+}
+
+func TestSecretLabels(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.SecretLabels = map[string]string{"network-policy": "s3-clients", "example.com/cost-center": "4711"}
+	objectUser.Spec.SecretAnnotations = map[string]string{"example.com/owner": "team-a"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the labels and annotations of the spec are set alongside those of the operator
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"app":                     appName,
+		"user":                    name,
+		"rook_cluster":            namespace,
+		"rook_object_store":       store,
+		managedByLabel:            managedByValue,
+		"network-policy":          "s3-clients",
+		"example.com/cost-center": "4711",
+	}, secret.Labels)
+	assert.Equal(t, "team-a", secret.Annotations["example.com/owner"])
+	assert.Equal(t, name, secret.Annotations[userIDAnnotation])
+
+	t.Run("invalid labels and annotations", func(t *testing.T) {
+		for _, spec := range []cephv1.ObjectStoreUserSpec{
+			{SecretLabels: map[string]string{"network policy": "s3-clients"}},
+			{SecretLabels: map[string]string{"network-policy": "s3 clients"}},
+			{SecretLabels: map[string]string{"app": "other"}},
+			{SecretAnnotations: map[string]string{"example.com/": "team-a"}},
+			{SecretAnnotations: map[string]string{userIDAnnotation: "other"}},
+			{SecretAnnotations: map[string]string{"tags.ceph.rook.io/team": "storage"}},
+		} {
+			u := newObjectUser()
+			u.Spec.SecretLabels, u.Spec.SecretAnnotations = spec.SecretLabels, spec.SecretAnnotations
+			assert.Error(t, ValidateUser(u), spec)
+		}
+	})
+}
+
+func TestObjectStoreProgressing(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	setStorePhase := func(phase string) {
+		objectStore := &cephv1.CephObjectStore{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: store, Namespace: namespace}, objectStore)
+		assert.NoError(t, err)
+		objectStore.Status = &cephv1.Status{Phase: phase}
+		err = r.client.Update(context.TODO(), objectStore)
+		assert.NoError(t, err)
+	}
+	// the store is updated while its rgw pod is ready
+	setStorePhase(k8sutil.ProcessingStatus)
+	rgwPod := &corev1.Pod{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-rgw-my-store-a-5fd6fb4489-xv65v", Namespace: namespace}, rgwPod)
+	assert.NoError(t, err)
+	rgwPod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	err = r.client.Update(context.TODO(), rgwPod)
+	assert.NoError(t, err)
+
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	condition := findCondition(objectUser.Status.Conditions, cephv1.ConditionObjectStoreProgressing)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+	assert.Contains(t, condition.Message, "Processing")
+
+	t.Run("store ready", func(t *testing.T) {
+		setStorePhase(k8sutil.ReadyStatus)
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		objectUser := &cephv1.CephObjectStoreUser{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		condition := findCondition(objectUser.Status.Conditions, cephv1.ConditionObjectStoreProgressing)
+		assert.Equal(t, corev1.ConditionFalse, condition.Status)
+	})
+}
+
+func TestSameStoreNameInOtherNamespace(t *testing.T) {
+	otherStore := &cephv1.CephObjectStore{ObjectMeta: metav1.ObjectMeta{Name: store, Namespace: "other-ns"}}
+	r := newReadyReconciler(&exectest.MockExecutor{}, otherStore)
+	objectUser := newObjectUser()
+	objectUser.Spec.Store = "other-ns/" + store
+
+	// the rgw pods of the store of the same name in the namespace of the user do not count
+	_, err := r.objectStoreInitialized(objectUser)
+	assert.Equal(t, object.ErrRGWNotRunning, errors.Cause(err))
+
+	rgwPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "rook-ceph-rgw-my-store-a-6c8b9d7f5-abcde",
+		Namespace: "other-ns",
+		Labels:    map[string]string{k8sutil.AppAttr: appName, "rgw": store}}}
+	err = r.client.Create(context.TODO(), rgwPod)
+	assert.NoError(t, err)
+	objectStore, err := r.objectStoreInitialized(objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, "other-ns", objectStore.Namespace)
+
+	// the store in the namespace of the user is still its own store
+	objectStore, err = r.objectStoreInitialized(newObjectUser())
+	assert.NoError(t, err)
+	assert.Equal(t, namespace, objectStore.Namespace)
+}
+
+func TestDebugReconcile(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Annotations = map[string]string{debugReconcileAnnotation: "1"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the annotation is ignored unless the operator allows debug reconciles
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, r.adminStats.Commands)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Empty(t, objectUser.Status.ObservedDebugReconcile)
+
+	// the commands of the debug reconcile are traced without their arguments
+	r.allowDebugReconcile = true
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Contains(t, r.adminStats.Commands, "radosgw-admin user info")
+	assert.Len(t, r.adminStats.Commands, r.adminStats.Calls)
+	for _, command := range r.adminStats.Commands {
+		assert.NotContains(t, command, "--")
+	}
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", objectUser.Status.ObservedDebugReconcile)
+
+	// a value is only traced once
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, r.adminStats.Commands)
+}
+
+func TestRestoreKeys(t *testing.T) {
+	restoredAccessKey, restoredSecretKey := "RESTOREDACCESSKEY000", "restoredSecretKey0000000000000000000000"
+	// the user was already created with the key RGW generated, e.g. by a reconcile before the restore
+	keys := map[string]string{"EOE7FYCNOBZJ5VFV909G": "qmIqpWm8HxCzmynCrD6U6vKWi4hnDBndOnmxXNsV"}
+	userJSON := func() string {
+		list := []string{}
+		for accessKey, secretKey := range keys {
+			list = append(list, fmt.Sprintf(`{"user": "my-user", "access_key": %q, "secret_key": %q}`, accessKey, secretKey))
+		}
+		return fmt.Sprintf(`{"user_id": "my-user", "display_name": "my-user", "keys": [%s]}`, strings.Join(list, ","))
+	}
+	var keyCommands [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" && args[1] == "create" {
+				return userExistsOutput, nil
+			}
+			if args[0] == "key" {
+				keyCommands = append(keyCommands, args[:indexOf(args, "--rgw-realm=my-store")])
+				accessKey := args[indexOf(args, "--access-key")+1]
+				if args[1] == "create" {
+					keys[accessKey] = args[indexOf(args, "--secret-key")+1]
+				} else {
+					delete(keys, accessKey)
+				}
+			}
+			if args[0] == "user" || args[0] == "key" {
+				return userJSON(), nil
+			}
+			return "", nil
+		},
+	}
+	backup := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-user-backup", Namespace: namespace},
+		Data:       map[string][]byte{"AccessKey": []byte(restoredAccessKey), "SecretKey": []byte(restoredSecretKey)},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.RestoreKeysFrom = "my-user-backup"
+	r := newReadyReconciler(executor, objectUser, backup)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// the provided key is set and the generated key is removed
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"key", "create", "--uid", name, "--key-type", "s3", "--access-key", restoredAccessKey, "--secret-key", restoredSecretKey},
+		{"key", "rm", "--uid", name, "--key-type", "s3", "--access-key", "EOE7FYCNOBZJ5VFV909G"},
+	}, keyCommands)
+	assert.Equal(t, map[string]string{restoredAccessKey: restoredSecretKey}, keys)
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, restoredAccessKey, secret.StringData["AccessKey"])
+	assert.Equal(t, restoredSecretKey, secret.StringData["SecretKey"])
+
+	t.Run("restored again", func(t *testing.T) {
+		keyCommands = nil
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Empty(t, keyCommands)
+	})
+	t.Run("validation", func(t *testing.T) {
+		u := newObjectUser()
+		u.Spec.RestoreKeysFrom = "my-user-backup"
+		assert.NoError(t, ValidateUser(u))
+		u.Spec.Adopt = true
+		err := ValidateUser(u)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "restoreKeysFrom is the only key of the user")
+		u.Spec.Adopt = false
+		u.Spec.Keys = []cephv1.UserKeySpec{{Name: "other"}}
+		assert.Error(t, ValidateUser(u))
+	})
+	t.Run("secret without keys", func(t *testing.T) {
+		backup.Data = map[string][]byte{"AccessKey": []byte(restoredAccessKey)}
+		err := r.client.Update(context.TODO(), backup)
+		assert.NoError(t, err)
+		_, err = r.reconcile(req)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `secret "my-user-backup" of the restored key must hold an AccessKey and a SecretKey`)
+	})
+}
+
+func TestReconcilePaused(t *testing.T) {
+	var commands [][]string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			commands = append(commands, args)
+			if args[0] == "user" {
+				return userCreateJSON, nil
+			}
+			return "", nil
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outfile string, args ...string) (string, error) {
+			commands = append(commands, args)
+			if args[0] == "status" {
+				return `{"pgmap":{"num_pgs":100,"pgs_by_state":[{"state_name":"active+clean","count":100}]}}`, nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	objectUser.Annotations = map[string]string{pausedAnnotation: "true"}
+	r := newReadyReconciler(executor, objectUser)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// nothing is run while the user is paused
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	assert.Empty(t, commands)
+	objectUser = &cephv1.CephObjectStoreUser{}
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	paused := findCondition(objectUser.Status.Conditions, cephv1.ConditionPaused)
+	assert.Equal(t, corev1.ConditionTrue, paused.Status)
+	assert.Equal(t, reasonReconcilePaused, paused.Reason)
+	assert.Empty(t, objectUser.Finalizers)
+
+	t.Run("deleted", func(t *testing.T) {
+		deleting := objectUser.DeepCopy()
+		deleting.Finalizers = []string{"cephobjectstoreuser.ceph.rook.io"}
+		deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		r := newReadyReconciler(executor, deleting)
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		assert.Empty(t, commands)
+		err = r.client.Get(context.TODO(), req.NamespacedName, deleting)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"cephobjectstoreuser.ceph.rook.io"}, deleting.Finalizers)
+	})
+	t.Run("resumed", func(t *testing.T) {
+		objectUser.Annotations = nil
+		err := r.client.Update(context.TODO(), objectUser)
+		assert.NoError(t, err)
+		_, err = r.reconcile(req)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, commands)
+		objectUser = &cephv1.CephObjectStoreUser{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+		assert.NoError(t, err)
+		assert.Equal(t, corev1.ConditionFalse, findCondition(objectUser.Status.Conditions, cephv1.ConditionPaused).Status)
+		assert.Equal(t, cephv1.ObjectStoreUserPhaseReady, objectUser.Status.Phase)
+	})
+}
+
+func TestCreationInfo(t *testing.T) {
+	rgwVersion := "ceph version 15.2.4 (7447c15c6ff58d7fce91843b705a268a1917325c) octopus (stable)"
+	info := userCreateJSON
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			if args[0] == "user" {
+				return info, nil
+			}
+			return "", nil
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outfile string, args ...string) (string, error) {
+			if args[0] == "status" {
+				return `{"pgmap":{"num_pgs":100,"pgs_by_state":[{"state_name":"active+clean","count":100}]}}`, nil
+			}
+			if args[0] == "versions" {
+				return fmt.Sprintf(`{"rgw":{%q:2,"ceph version 15.2.3 (d289bbdec69ed7c1f516e0a093594580a76b78d0) octopus (stable)":1}}`, rgwVersion), nil
+			}
+			return "", nil
+		},
+	}
+	objectUser := newObjectUser()
+	r := newReadyReconciler(executor, objectUser)
+
+	// An older RGW does not report the creation date, the time the operator created the user is reported
+	before := time.Now().Truncate(time.Second)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+	_, err := r.reconcile(req)
+	assert.NoError(t, err)
+	err = r.client.Get(context.TODO(), req.NamespacedName, objectUser)
+	assert.NoError(t, err)
+	assert.NotNil(t, objectUser.Status.CreatedAt)
+	assert.False(t, objectUser.Status.CreatedAt.Time.Before(before))
+	assert.Equal(t, rgwVersion, objectUser.Status.CreatedWithVersion)
+
+	t.Run("creation date of rgw", func(t *testing.T) {
+		info = strings.Replace(userCreateJSON, `"user_id": "my-user",`, `"user_id": "my-user", "create_date": "2020-06-25T08:30:15.123456Z",`, 1)
+		_, err := r.reconcile(req)
+		assert.NoError(t, err)
+		updated := &cephv1.CephObjectStoreUser{}
+		err = r.client.Get(context.TODO(), req.NamespacedName, updated)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2020, 6, 25, 8, 30, 15, 0, time.UTC), updated.Status.CreatedAt.Time.UTC())
+		// The version the user was created with is kept
+		assert.Equal(t, rgwVersion, updated.Status.CreatedWithVersion)
+	})
+}
+
+func TestGenerateUserConfigWithoutUID(t *testing.T) {
+	objectUser := newObjectUser()
+	objectUser.Name = ""
+	_, _, err := generateUserConfig(objectUser, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "missing uid")
+
+	objectUser.Spec.UID = "other-user"
+	userConfig, _, err := generateUserConfig(objectUser, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "other-user", userConfig.UserID)
+}
+
+func TestImportUsers(t *testing.T) {
+	t.Run("user info is imported with adopt mode", func(t *testing.T) {
+		users, err := ImportUsers(store, namespace, []byte(userCreateJSON))
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+		u := users[0]
+		assert.Equal(t, "CephObjectStoreUser", u.Kind)
+		assert.Equal(t, "ceph.rook.io/v1", u.APIVersion)
+		assert.Equal(t, name, u.Name)
+		assert.Equal(t, namespace, u.Namespace)
+		assert.Equal(t, store, u.Spec.Store)
+		assert.True(t, u.Spec.Adopt)
+		assert.Equal(t, "", u.Spec.UID)
+		assert.Equal(t, "my-user", u.Spec.DisplayName)
+		assert.Equal(t, "", u.Spec.OpMask)
+		// The user has the RGW defaults
+		assert.Nil(t, u.Spec.Quotas)
+		assert.Nil(t, u.Spec.Capabilities)
+	})
+
+	t.Run("quotas and caps are imported", func(t *testing.T) {
+		dump := strings.NewReplacer(
+			`"display_name": "my-user"`, `"display_name": "My User"`,
+			`"max_buckets": 1000`, `"max_buckets": 20`,
+			`"caps": []`, `"caps": [{"type": "buckets", "perm": "*"}, {"type": "users", "perm": "read"}]`,
+			`"user_quota": {
+		"enabled": false,
+		"check_on_raw": false,
+		"max_size": -1`, `"user_quota": {
+		"enabled": true,
+		"check_on_raw": false,
+		"max_size": 1073741824`,
+		).Replace(userCreateJSON)
+		users, err := ImportUsers(store, namespace, []byte(dump))
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+		u := users[0]
+		assert.Equal(t, "My User", u.Spec.DisplayName)
+		assert.Equal(t, 20, *u.Spec.Quotas.MaxBuckets)
+		assert.True(t, *u.Spec.Quotas.Enabled)
+		assert.Equal(t, "1Gi", u.Spec.Quotas.MaxSize.String())
+		assert.Nil(t, u.Spec.Quotas.MaxObjects)
+		assert.Nil(t, u.Spec.Quotas.DefaultBucketMaxSize)
+		assert.Equal(t, "*", u.Spec.Capabilities.Bucket)
+		assert.Equal(t, "read", u.Spec.Capabilities.User)
+		assert.Equal(t, "", u.Spec.Capabilities.Raw)
+	})
+
+	t.Run("unknown caps are imported as raw caps", func(t *testing.T) {
+		users, err := ImportUsers(store, namespace, []byte(capsUserJSON(map[string]string{"users": "read", "oidc-provider": "*"})))
+		assert.NoError(t, err)
+		assert.Equal(t, &cephv1.ObjectUserCapSpec{Raw: "users=read;oidc-provider=*"}, users[0].Spec.Capabilities)
+	})
+
+	t.Run("user list is imported", func(t *testing.T) {
+		users, err := ImportUsers(store, namespace, []byte(`["my-user", "tenant$Other_User"]`))
+		assert.NoError(t, err)
+		assert.Len(t, users, 2)
+		assert.Equal(t, name, users[0].Name)
+		assert.Equal(t, "", users[0].Spec.UID)
+		assert.Equal(t, "tenant-other-user", users[1].Name)
+		assert.Equal(t, "tenant$Other_User", users[1].Spec.UID)
+	})
+
+	t.Run("users with the same name are rejected", func(t *testing.T) {
+		_, err := ImportUsers(store, namespace, []byte(`["my-user", "My_User"]`))
+		assert.Error(t, err)
+	})
+
+	t.Run("yaml documents are written", func(t *testing.T) {
+		out, err := ImportUsersYAML(store, namespace, []byte(fmt.Sprintf("[%s, %s]", userCreateJSON, `{"user_id": "other-user", "caps": [{"type": "usage", "perm": "read"}]}`)))
+		assert.NoError(t, err)
+		documents := strings.Split(string(out), "---\n")
+		assert.Len(t, documents, 2)
+		assert.Contains(t, documents[0], "kind: CephObjectStoreUser")
+		assert.Contains(t, documents[0], "adopt: true")
+		assert.Contains(t, documents[1], "usage: read")
+	})
+}
+
+func TestDeterministicKey(t *testing.T) {
+	seed := []byte("0123456789abcdef0123456789abcdef")
+	accessKey, secretKey := deriveKey(seed, "my-user")
+	assert.Len(t, accessKey, 20)
+	assert.Len(t, secretKey, 40)
+
+	// the same seed and uid always yield the same key, another seed or uid another key
+	sameAccessKey, sameSecretKey := deriveKey(seed, "my-user")
+	assert.Equal(t, accessKey, sameAccessKey)
+	assert.Equal(t, secretKey, sameSecretKey)
+	otherAccessKey, otherSecretKey := deriveKey(seed, "my-other-user")
+	assert.NotEqual(t, accessKey, otherAccessKey)
+	assert.NotEqual(t, secretKey, otherSecretKey)
+	otherAccessKey, _ = deriveKey([]byte("fedcba9876543210fedcba9876543210"), "my-user")
+	assert.NotEqual(t, accessKey, otherAccessKey)
+
+	var createArgs []string
+	exists := false
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			userJSON := fmt.Sprintf(`{"user_id": "my-user", "display_name": "my-user", "keys": [{"user": "my-user", "access_key": %q, "secret_key": %q}]}`, accessKey, secretKey)
+			if args[0] == "user" && args[1] == "create" {
+				createArgs = args
+				exists = true
+				return userJSON, nil
+			}
+			if args[0] == "user" && exists {
+				return userJSON, nil
+			}
+			return "", nil
+		},
+	}
+	seedSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-seed", Namespace: namespace},
+		Data:       map[string][]byte{"Seed": seed},
+	}
+	objectUser := newObjectUser()
+	objectUser.Spec.DeterministicKey = &cephv1.DeterministicKeySpec{SeedRef: "my-seed"}
+	r := newReadyReconciler(executor, objectUser, seedSecret)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}
+
+	// deterministic keys must be allowed by the operator
+	_, err := r.reconcile(req)
+	assert.Error(t, err)
+	assert.Nil(t, createArgs)
+
+	// the user is created with the derived key, which is written to the secret
+	r.allowDeterministicKeys = true
+	_, err = r.reconcile(req)
+	assert.NoError(t, err)
+	i := indexOf(createArgs, "--access-key")
+	assert.True(t, i > 0)
+	assert.Equal(t, []string{"--access-key", accessKey, "--secret-key", secretKey}, createArgs[i:i+4])
+	secret := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: "rook-ceph-object-user-my-store-my-user", Namespace: namespace}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, accessKey, secret.StringData["AccessKey"])
+	assert.Equal(t, secretKey, secret.StringData["SecretKey"])
+
+	t.Run("validation", func(t *testing.T) {
+		u := newObjectUser()
+		u.Spec.DeterministicKey = &cephv1.DeterministicKeySpec{}
+		assert.Error(t, ValidateUser(u))
+		u.Spec.DeterministicKey.SeedRef = "my-seed"
+		assert.NoError(t, ValidateUser(u))
+		u.Spec.Keys = []cephv1.UserKeySpec{{Name: "blue"}}
+		assert.Error(t, ValidateUser(u))
+	})
+}
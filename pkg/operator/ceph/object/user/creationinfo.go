@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"sort"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	cephclient "github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/ceph/object"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// refreshCreationInfo reports when the RGW user was created and the rgw version it was created with in
+// the status. RGW only reports the creation date of a user in newer versions, for older ones the time
+// the operator created the user is reported instead. The version is only known for a user created by
+// the operator. Like the effective config this is only reported, a failure does not fail the reconcile.
+func (r *ReconcileObjectStoreUser) refreshCreationInfo(u *cephv1.CephObjectStoreUser, objectUser *object.ObjectUser) {
+	created := r.userCreated()
+	if objectUser.CreateDate != nil {
+		createdAt := metav1.NewTime(*objectUser.CreateDate)
+		u.Status.CreatedAt = &createdAt
+	} else if created {
+		createdAt := metav1.NewTime(time.Now().Truncate(time.Second))
+		u.Status.CreatedAt = &createdAt
+	}
+	if !created {
+		return
+	}
+
+	version, err := r.rgwVersion(u)
+	if err != nil {
+		r.log.Warningf("failed to get the rgw version ceph object user %q was created with. %v", r.userConfig.UserID, err)
+		return
+	}
+	u.Status.CreatedWithVersion = version
+}
+
+// userCreated returns whether this reconcile created the ceph user
+func (r *ReconcileObjectStoreUser) userCreated() bool {
+	for _, change := range r.changes {
+		if change == "user=created" {
+			return true
+		}
+	}
+	return false
+}
+
+// rgwVersion returns the version most of the rgw daemons of the cluster run, e.g. while they are
+// upgraded, as "ceph versions" reports it
+func (r *ReconcileObjectStoreUser) rgwVersion(u *cephv1.CephObjectStoreUser) (string, error) {
+	versions, err := cephclient.GetAllCephDaemonVersions(r.context, clusterNamespace(u))
+	if err != nil {
+		return "", err
+	}
+	names := []string{}
+	for name := range versions.Rgw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	version := ""
+	for _, name := range names {
+		if version == "" || versions.Rgw[name] > versions.Rgw[version] {
+			version = name
+		}
+	}
+	return version, nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// deterministicKeyName is the name the deterministic key of a user is tracked under
+	deterministicKeyName = "deterministic"
+	// minSeedLength is the minimum length of the seed of deterministic keys, anyone holding the seed
+	// can derive the keys of all users provisioned with it
+	minSeedLength = 32
+)
+
+// deriveKey derives the S3 key of the user with the given uid from the seed with HMAC-SHA256. The
+// access key has the 20 upper case characters and the secret key the 40 characters of the keys RGW
+// generates.
+func deriveKey(seed []byte, uid string) (string, string) {
+	mac := hmac.New(sha256.New, seed)
+	mac.Write([]byte("access-key:" + uid))
+	accessKey := base32.StdEncoding.EncodeToString(mac.Sum(nil))[:20]
+
+	mac.Reset()
+	mac.Write([]byte("secret-key:" + uid))
+	secretKey := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:40]
+	return accessKey, secretKey
+}
+
+// validateDeterministicKey validates that the deterministic key is the only source of the S3 keys
+// of the spec
+func validateDeterministicKey(spec cephv1.ObjectStoreUserSpec) error {
+	if spec.DeterministicKey.SeedRef == "" {
+		return errors.New("deterministicKey requires a seedRef")
+	}
+	if len(spec.Keys) > 0 || spec.Adopt || spec.SuppressUserKeys || spec.GenerateMissingKey || (spec.GenerateKey != nil && *spec.GenerateKey) {
+		return errors.New("deterministicKey is the only key of the user, it cannot be combined with keys, adopt, suppressUserKeys, generateMissingKey or generateKey")
+	}
+	return nil
+}
+
+// withDeterministicKey returns the spec with its deterministic key as its only key, which is then set
+// like a key read from a secret. The key is never generated randomly.
+func withDeterministicKey(spec cephv1.ObjectStoreUserSpec) cephv1.ObjectStoreUserSpec {
+	if spec.DeterministicKey == nil {
+		return spec
+	}
+	spec.Keys = []cephv1.UserKeySpec{{Name: deterministicKeyName, SecretName: spec.DeterministicKey.SeedRef}}
+	generateKey := false
+	spec.GenerateKey = &generateKey
+	return spec
+}
+
+// readDeterministicKey derives the deterministic key of the user from the seed of its secret
+func (r *ReconcileObjectStoreUser) readDeterministicKey(u *cephv1.CephObjectStoreUser) error {
+	if u.Spec.DeterministicKey == nil {
+		return nil
+	}
+	secretName := u.Spec.DeterministicKey.SeedRef
+	secret := &v1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: secretName, Namespace: u.Namespace}, secret)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get secret %q of the deterministic key", secretName)
+	}
+	seed := secretValue(secret, "Seed")
+	if len(seed) < minSeedLength {
+		return errors.Errorf("secret %q of the deterministic key must hold a Seed of at least %d characters", secretName, minSeedLength)
+	}
+
+	accessKey, secretKey := deriveKey([]byte(seed), r.userConfig.UserID)
+	if r.keyRefs == nil {
+		r.keyRefs = map[string]namedKey{}
+	}
+	r.keyRefs[deterministicKeyName] = namedKey{name: deterministicKeyName, accessKey: accessKey, secretKey: secretKey}
+	return nil
+}
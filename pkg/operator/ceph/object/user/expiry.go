@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/operator/ceph/object"
+	v1 "k8s.io/api/core/v1"
+)
+
+// expiresIn returns the time left until the user of the spec expires, zero or less once it expired.
+// It is only meaningful for a spec with an expiry.
+func expiresIn(spec cephv1.ObjectStoreUserSpec) time.Duration {
+	return time.Until(spec.ExpiresAt.Time)
+}
+
+// isExpired returns whether the user of the spec expired
+func isExpired(spec cephv1.ObjectStoreUserSpec) bool {
+	return spec.ExpiresAt != nil && expiresIn(spec) <= 0
+}
+
+// managesSuspension returns whether the suspension of the user is reconciled, which is only the case
+// for a user that expires or was suspended because it expired
+func (r *ReconcileObjectStoreUser) managesSuspension() bool {
+	return r.userSpec.ExpiresAt != nil || r.suspendedOnExpiry
+}
+
+// reconcileSuspension suspends the user once it expired. A user the operator suspended because it
+// expired is enabled again when the expiry is moved to the future or removed, while a user suspended by
+// other means is left suspended.
+func (r *ReconcileObjectStoreUser) reconcileSuspension(objectUser *object.ObjectUser) (bool, error) {
+	suspended := objectUser.Suspended != nil && *objectUser.Suspended
+	expired := isExpired(r.userSpec)
+	if suspended == expired || (!expired && !r.suspendedOnExpiry) {
+		return false, nil
+	}
+
+	if expired {
+		r.log.Infof("suspending ceph object user %q, it expired at %s", r.userConfig.UserID, r.userSpec.ExpiresAt.Format(time.RFC3339))
+	} else {
+		r.log.Infof("enabling ceph object user %q, it no longer expired", r.userConfig.UserID)
+	}
+	err := r.applyChange(func() error {
+		_, _, err := object.SuspendUser(r.objContext, r.userConfig.UserID, expired)
+		return err
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to change the suspension of ceph object user %q", r.userConfig.UserID)
+	}
+	r.recordChange("suspended", strconv.FormatBool(expired))
+	return true, nil
+}
+
+// refreshExpiry reports the time left until the user expires, or that it is suspended because it
+// expired
+func refreshExpiry(u *cephv1.CephObjectStoreUser) {
+	u.Status.ExpiresIn = ""
+	if isExpired(u.Spec) {
+		message := fmt.Sprintf("the user expired at %s and is suspended", u.Spec.ExpiresAt.Format(time.RFC3339))
+		setCondition(u, cephv1.ConditionExpired, v1.ConditionTrue, "UserExpired", message)
+		return
+	}
+	if u.Spec.ExpiresAt != nil {
+		u.Status.ExpiresIn = expiresIn(u.Spec).Truncate(time.Second).String()
+	}
+	if findCondition(u.Status.Conditions, cephv1.ConditionExpired) != nil {
+		setCondition(u, cephv1.ConditionExpired, v1.ConditionFalse, "UserNotExpired", "the user did not expire")
+	}
+}
+
+// expiryRequeueAfter returns the requeue interval of a reconciled user, which is reconciled again right
+// after it expires to be suspended
+func expiryRequeueAfter(spec cephv1.ObjectStoreUserSpec, resyncPeriod time.Duration) time.Duration {
+	if spec.ExpiresAt == nil || isExpired(spec) {
+		return resyncPeriod
+	}
+	// Requeue just past the expiry, so the user has expired by then
+	requeueAfter := expiresIn(spec) + time.Second
+	if resyncPeriod > 0 && resyncPeriod < requeueAfter {
+		return resyncPeriod
+	}
+	return requeueAfter
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/operator/ceph/object"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// rgwDefaultOpMask is the op mask RGW gives a new user, which the spec leaves untouched
+const rgwDefaultOpMask = "read, write, delete"
+
+// invalidNameCharsRegexp matches the characters of a uid that are not valid in the name of a CR
+var invalidNameCharsRegexp = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// ImportUsers returns the CephObjectStoreUsers that adopt the RGW users of a radosgw-admin dump, to
+// bring the users of an object store that were managed by hand under the management of the operator.
+// The dump is the JSON of "user info" of a user, a list of them, or the list of uids of "user list". The
+// users adopt the RGW users with their keys, and their spec holds the settings of the dump, so that
+// reconciling them changes nothing. A list of uids only gives the users their uid.
+func ImportUsers(storeName, namespace string, dump []byte) ([]cephv1.CephObjectStoreUser, error) {
+	dump = bytes.TrimSpace(dump)
+	entries := []json.RawMessage{dump}
+	if bytes.HasPrefix(dump, []byte("[")) {
+		entries = nil
+		if err := json.Unmarshal(dump, &entries); err != nil {
+			return nil, errors.Wrap(err, "failed to read the list of users")
+		}
+	}
+
+	users := []cephv1.CephObjectStoreUser{}
+	names := map[string]string{}
+	for _, entry := range entries {
+		objectUser := &object.ObjectUser{}
+		var uid string
+		if err := json.Unmarshal(entry, &uid); err == nil {
+			objectUser.UserID = uid
+		} else {
+			objectUser, err = object.ParseUserInfo(string(entry))
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read user")
+			}
+		}
+		u, err := importUser(storeName, namespace, objectUser)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := names[u.Name]; ok {
+			return nil, errors.Errorf("users %q and %q would both be imported as %q", other, objectUser.UserID, u.Name)
+		}
+		names[u.Name] = objectUser.UserID
+		users = append(users, *u)
+	}
+	return users, nil
+}
+
+// ImportUsersYAML returns the YAML of the CephObjectStoreUsers that adopt the RGW users of a
+// radosgw-admin dump, see ImportUsers, as a stream of documents to apply with kubectl
+func ImportUsersYAML(storeName, namespace string, dump []byte) ([]byte, error) {
+	users, err := ImportUsers(storeName, namespace, dump)
+	if err != nil {
+		return nil, err
+	}
+	documents := []string{}
+	for _, u := range users {
+		document, err := yaml.Marshal(u)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to write user %q", u.Name)
+		}
+		documents = append(documents, string(document))
+	}
+	return []byte(strings.Join(documents, "---\n")), nil
+}
+
+// importUser returns the CephObjectStoreUser that adopts the RGW user. A uid that is not a valid name
+// of a CR, e.g. "tenant$user", is set as the uid of a CR with a name derived from it.
+func importUser(storeName, namespace string, objectUser *object.ObjectUser) (*cephv1.CephObjectStoreUser, error) {
+	if objectUser.UserID == "" {
+		return nil, errors.New("failed to import a user without uid")
+	}
+	name := strings.Trim(invalidNameCharsRegexp.ReplaceAllString(strings.ToLower(objectUser.UserID), "-"), ".-")
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return nil, errors.Errorf("failed to derive the name of a CR from uid %q, %s", objectUser.UserID, strings.Join(errs, ", "))
+	}
+
+	u := &cephv1.CephObjectStoreUser{
+		TypeMeta:   metav1.TypeMeta{APIVersion: cephv1.SchemeGroupVersion.String(), Kind: "CephObjectStoreUser"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       cephv1.ObjectStoreUserSpec{Store: storeName, Adopt: true},
+	}
+	if name != objectUser.UserID {
+		u.Spec.UID = objectUser.UserID
+	}
+	if objectUser.DisplayName != nil {
+		u.Spec.DisplayName = *objectUser.DisplayName
+	}
+	if objectUser.Email != nil {
+		u.Spec.Email = *objectUser.Email
+	}
+	if objectUser.OpMask != nil && *objectUser.OpMask != rgwDefaultOpMask {
+		u.Spec.OpMask = *objectUser.OpMask
+	}
+	if objectUser.DefaultPlacement != nil {
+		u.Spec.DefaultPlacement = *objectUser.DefaultPlacement
+	}
+	if objectUser.DefaultStorageClass != nil {
+		u.Spec.DefaultStorageClass = *objectUser.DefaultStorageClass
+	}
+	if len(objectUser.PlacementTags) > 0 {
+		u.Spec.PlacementTags = objectUser.PlacementTags
+	}
+	u.Spec.Quotas = importQuotas(objectUser)
+	u.Spec.Capabilities = importCaps(objectUser.Caps)
+	for _, subuser := range objectUser.Subusers {
+		spec := cephv1.SubuserSpec{Name: strings.TrimPrefix(subuser.ID, objectUser.UserID+":")}
+		for access, rgw := range rgwSubuserAccess {
+			if rgw.permission == subuser.Permissions {
+				spec.Access = access
+			}
+		}
+		if len(subuser.Keys) > 0 && subuser.SwiftKey == "" {
+			spec.KeyType = cephv1.UserKeyTypeS3
+		}
+		u.Spec.Subusers = append(u.Spec.Subusers, spec)
+	}
+
+	if err := ValidateUser(u); err != nil {
+		return nil, errors.Wrapf(err, "failed to import user %q", objectUser.UserID)
+	}
+	return u, nil
+}
+
+// importQuotas returns the quotas of the spec that hold the limits of the RGW user, nil for a user
+// with the RGW defaults. A size is kept in bytes.
+func importQuotas(objectUser *object.ObjectUser) *cephv1.ObjectUserQuotaSpec {
+	quotas := &cephv1.ObjectUserQuotaSpec{}
+	set := false
+	if objectUser.MaxBuckets != nil && *objectUser.MaxBuckets != rgwDefaultMaxBuckets {
+		maxBuckets := specMaxBuckets(*objectUser.MaxBuckets)
+		quotas.MaxBuckets = &maxBuckets
+		set = true
+	}
+	if quota := objectUser.UserQuota; quota != nil && (quota.Enabled || quota.MaxSize >= 0 || quota.MaxObjects >= 0) {
+		if quota.MaxSize >= 0 {
+			quotas.MaxSize = resource.NewQuantity(quota.MaxSize, resource.BinarySI)
+		}
+		if quota.MaxObjects >= 0 {
+			maxObjects := quota.MaxObjects
+			quotas.MaxObjects = &maxObjects
+		}
+		enabled := quota.Enabled
+		quotas.Enabled = &enabled
+		set = true
+	}
+	// The bucket quota of the spec is always enforced
+	if quota := objectUser.BucketQuota; quota != nil && quota.Enabled {
+		if quota.MaxSize >= 0 {
+			quotas.DefaultBucketMaxSize = resource.NewQuantity(quota.MaxSize, resource.BinarySI)
+			set = true
+		}
+		if quota.MaxObjects >= 0 {
+			maxObjects := quota.MaxObjects
+			quotas.DefaultBucketMaxObjects = &maxObjects
+			set = true
+		}
+	}
+	if !set {
+		return nil
+	}
+	return quotas
+}
+
+// importCaps returns the admin capabilities of the spec that hold the capabilities of the RGW user, as
+// raw capabilities if the user has capability types without a field in the spec
+func importCaps(caps map[string]string) *cephv1.ObjectUserCapSpec {
+	if len(caps) == 0 {
+		return nil
+	}
+	spec := &cephv1.ObjectUserCapSpec{}
+	fields := map[string]*string{
+		"users":    &spec.User,
+		"buckets":  &spec.Bucket,
+		"metadata": &spec.Metadata,
+		"usage":    &spec.Usage,
+		"zone":     &spec.Zone,
+		"roles":    &spec.Roles,
+		"info":     &spec.Info,
+	}
+	raw := []string{}
+	for _, capType := range orderedCapTypes(caps) {
+		perm := normalizeCapPerm(caps[capType])
+		if perm == "" {
+			continue
+		}
+		raw = append(raw, fmt.Sprintf("%s=%s", capType, perm))
+		if field, ok := fields[capType]; ok {
+			*field = perm
+			continue
+		}
+		spec.Raw = "unknown"
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	if spec.Raw != "" {
+		return &cephv1.ObjectUserCapSpec{Raw: strings.Join(raw, ";")}
+	}
+	return spec
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	oerrors "github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api/errors"
+	"github.com/pkg/errors"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/operator/ceph/object"
+	"github.com/rook/rook/pkg/operator/ceph/object/bucket"
+	v1 "k8s.io/api/core/v1"
+)
+
+// reasonInitialBucketsFailed is the reason of a user whose initial buckets could not be created
+const reasonInitialBucketsFailed = "InitialBucketsFailed"
+
+// bucketNameRegexp matches the S3 bucket names RGW accepts by default
+var bucketNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// createBucket creates a bucket with the S3 API of the object store, as the user with the given keys.
+// radosgw-admin cannot create buckets, and a bucket created by the user is owned by it from the start.
+var createBucket = func(endpoint, accessKey, secretKey, name string) error {
+	s3svc, err := bucket.NewS3Agent(accessKey, secretKey, endpoint)
+	if err != nil {
+		return err
+	}
+	return s3svc.CreateBucket(name)
+}
+
+// enableBucketVersioning enables the versioning of a bucket with the S3 API of the object store, as the
+// user with the given keys. RGW has no setting for the versioning of the new buckets of a user.
+var enableBucketVersioning = func(endpoint, accessKey, secretKey, name string) error {
+	s3svc, err := bucket.NewS3Agent(accessKey, secretKey, endpoint)
+	if err != nil {
+		return err
+	}
+	return s3svc.EnableBucketVersioning(name)
+}
+
+// validateInitialBuckets validates the initial buckets of the spec, which are created with the S3 keys
+// of the user
+func validateInitialBuckets(spec cephv1.ObjectStoreUserSpec) error {
+	if len(spec.InitialBuckets) == 0 {
+		if spec.DefaultBucketVersioning {
+			return errors.New("defaultBucketVersioning only applies to initial buckets, none are set")
+		}
+		return nil
+	}
+	if spec.SuppressUserKeys {
+		return errors.New("initial buckets are created with the user keys, they must not be suppressed")
+	}
+	if len(spec.Users) > 0 {
+		// Bucket names are unique in the object store, the users of a template cannot all own them
+		return errors.New("initial buckets cannot be set on a template")
+	}
+	buckets := map[string]bool{}
+	for _, name := range spec.InitialBuckets {
+		if buckets[name] {
+			return errors.Errorf("duplicate initial bucket %q", name)
+		}
+		buckets[name] = true
+		if !bucketNameRegexp.MatchString(name) || strings.Contains(name, "..") {
+			return errors.Errorf("invalid initial bucket %q, must be 3 to 63 lowercase letters, numbers, dots or hyphens, starting and ending with a letter or number", name)
+		}
+	}
+	return nil
+}
+
+// reconcileInitialBuckets creates the initial buckets of the spec that were not created yet. A bucket
+// that already exists is not created again: it is tracked if the user owns it, and reported as a
+// conflict if another user does. The tracked buckets are never touched again, so a bucket the user
+// deleted stays deleted. The versioning of a bucket is enabled before it is tracked, so that it is
+// enabled again if that failed.
+func (r *ReconcileObjectStoreUser) reconcileInitialBuckets(u *cephv1.CephObjectStoreUser) error {
+	created := []string{}
+	conflicts := []string{}
+	for _, name := range u.Spec.InitialBuckets {
+		if u.Status != nil && contains(u.Status.InitialBuckets, name) {
+			created = append(created, name)
+			continue
+		}
+
+		owner, err := r.initialBucketOwner(name)
+		if err != nil {
+			return err
+		}
+		if owner == "" {
+			if r.userConfig.AccessKey == nil || r.userConfig.SecretKey == nil {
+				return errors.Errorf("failed to create initial bucket %q, ceph object user %q has no s3 keys", name, r.userConfig.UserID)
+			}
+			r.log.Infof("creating initial bucket %q of ceph object user %q", name, r.userConfig.UserID)
+			err = createBucket(objectStoreEndpoint(r.objectStore), *r.userConfig.AccessKey, *r.userConfig.SecretKey, name)
+			if err != nil && !oerrors.IsBucketExists(err) {
+				return errors.Wrapf(err, "failed to create initial bucket %q", name)
+			}
+			if err == nil {
+				r.recordChange("createBucket", name)
+				owner = r.userConfig.UserID
+			} else {
+				// The bucket was created since it was looked up, by this user or another one
+				owner, err = r.initialBucketOwner(name)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if owner != r.userConfig.UserID {
+			conflicts = append(conflicts, fmt.Sprintf("bucket %q is owned by user %q", name, owner))
+			continue
+		}
+		if u.Spec.DefaultBucketVersioning {
+			if r.userConfig.AccessKey == nil || r.userConfig.SecretKey == nil {
+				return errors.Errorf("failed to enable versioning of initial bucket %q, ceph object user %q has no s3 keys", name, r.userConfig.UserID)
+			}
+			err = enableBucketVersioning(objectStoreEndpoint(r.objectStore), *r.userConfig.AccessKey, *r.userConfig.SecretKey, name)
+			if err != nil {
+				return errors.Wrapf(err, "failed to enable versioning of initial bucket %q", name)
+			}
+			r.recordChange("bucketVersioning", name)
+		}
+		created = append(created, name)
+	}
+
+	u.Status.InitialBuckets = nil
+	if len(created) > 0 {
+		u.Status.InitialBuckets = created
+	}
+	if len(conflicts) > 0 {
+		message := strings.Join(conflicts, ", ")
+		r.log.Warningf("ceph object user %q cannot own all initial buckets, %s", r.userConfig.UserID, message)
+		setCondition(u, cephv1.ConditionInitialBucketConflict, v1.ConditionTrue, "BucketsOwnedByOtherUsers", message)
+	} else if findCondition(u.Status.Conditions, cephv1.ConditionInitialBucketConflict) != nil {
+		setCondition(u, cephv1.ConditionInitialBucketConflict, v1.ConditionFalse, "BucketsOwned", "the initial buckets of the spec are owned by the user")
+	}
+	return nil
+}
+
+// initialBucketOwner returns the owner of the bucket, empty if the bucket does not exist
+func (r *ReconcileObjectStoreUser) initialBucketOwner(name string) (string, error) {
+	objectBucket, rgwerr, err := object.GetBucket(r.objContext, name)
+	if err != nil {
+		if rgwerr == object.RGWErrorNotFound {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "failed to get initial bucket %q", name)
+	}
+	return objectBucket.Owner, nil
+}
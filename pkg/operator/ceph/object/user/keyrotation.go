@@ -0,0 +1,169 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/operator/ceph/object"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// reasonKeyRotationFailed is the reason of a user whose previous key could not be removed after a
+// scheduled rotation
+const reasonKeyRotationFailed = "KeyRotationFailed"
+
+// now returns the current time the key rotations are scheduled with
+var now = time.Now
+
+// validateKeyRotation validates that the rotated key is the key the operator generates for the user
+func validateKeyRotation(spec cephv1.ObjectStoreUserSpec) error {
+	if spec.KeyRotation.IntervalDays < 1 {
+		return errors.Errorf("invalid keyRotation intervalDays %d, must be at least 1", spec.KeyRotation.IntervalDays)
+	}
+	if len(spec.Keys) > 0 || spec.Adopt || spec.SuppressUserKeys || !generatesKeys(spec) || spec.DeterministicKey != nil || spec.RestoreKeysFrom != "" {
+		return errors.New("keyRotation rotates the generated key of the user, it cannot be combined with keys, adopt, suppressUserKeys, generateKey false, deterministicKey or restoreKeysFrom")
+	}
+	return nil
+}
+
+// nextKeyRotation returns the time the key of the user is rotated next, the interval after its last
+// rotation. Before the first rotation, it is the interval after the user was created, or after the
+// rotation was scheduled for a user whose creation time is not known.
+func nextKeyRotation(u *cephv1.CephObjectStoreUser) time.Time {
+	interval := time.Duration(u.Spec.KeyRotation.IntervalDays) * 24 * time.Hour
+	status := u.Status.KeyRotation
+	if status != nil && status.LastRotation != nil {
+		return status.LastRotation.Add(interval)
+	}
+	if u.Status.CreatedAt != nil {
+		return u.Status.CreatedAt.Add(interval)
+	}
+	if status != nil && status.NextRotation != nil {
+		return status.NextRotation.Time
+	}
+	return now().Add(interval).Truncate(time.Second)
+}
+
+// reconcileKeyRotation generates a new S3 key for the user once its rotation is due. The new key is
+// written to the secret instead of the current one, which is only removed once the secret holds the new
+// key, so that the clients can switch to it. The key of the last rotation is recorded in the status
+// right away, so that a reconcile that fails before the previous key is removed resumes with it.
+func (r *ReconcileObjectStoreUser) reconcileKeyRotation(u *cephv1.CephObjectStoreUser, objectUser *object.ObjectUser) error {
+	if r.userSpec.KeyRotation == nil || len(objectUser.Keys) == 0 {
+		return nil
+	}
+	if u.Status.KeyRotation == nil {
+		u.Status.KeyRotation = &cephv1.ObjectUserKeyRotationStatus{}
+	}
+	status := u.Status.KeyRotation
+
+	current := 0
+	for i, key := range objectUser.Keys {
+		if key.AccessKey == status.AccessKey {
+			current = i
+		}
+	}
+	if !now().Before(nextKeyRotation(u)) {
+		r.log.Infof("rotating the s3 key of ceph object user %q on its schedule of %d days", r.userConfig.UserID, r.userSpec.KeyRotation.IntervalDays)
+		user, _, err := object.CreateKey(r.objContext, r.userConfig.UserID)
+		if err != nil {
+			return errors.Wrapf(err, "failed to rotate the key of ceph object user %q", r.userConfig.UserID)
+		}
+		previous := map[string]bool{}
+		for _, key := range objectUser.Keys {
+			previous[key.AccessKey] = true
+		}
+		current = -1
+		for i, key := range user.Keys {
+			if !previous[key.AccessKey] {
+				current = i
+			}
+		}
+		if current < 0 {
+			return errors.Errorf("failed to find the rotated key of ceph object user %q", r.userConfig.UserID)
+		}
+		objectUser.Keys = user.Keys
+		r.recordChange("key", "scheduled:created")
+		rotatedAt := metav1.NewTime(now().Truncate(time.Second))
+		status.LastRotation = &rotatedAt
+	}
+
+	key := objectUser.Keys[current]
+	status.AccessKey = key.AccessKey
+	r.userConfig.AccessKey = &key.AccessKey
+	r.userConfig.SecretKey = &key.SecretKey
+	for _, previous := range objectUser.Keys {
+		if previous.AccessKey != key.AccessKey {
+			r.rotatedKeys = append(r.rotatedKeys, previous.AccessKey)
+		}
+	}
+	next := metav1.NewTime(nextKeyRotation(u))
+	status.NextRotation = &next
+	return nil
+}
+
+// removeRotatedKeys removes the keys replaced by a rotation of the key of the user, once the secret of the
+// user holds the new key
+func (r *ReconcileObjectStoreUser) removeRotatedKeys() error {
+	for _, accessKey := range r.rotatedKeys {
+		r.log.Infof("removing the rotated key %q of ceph object user %q", accessKey, r.userConfig.UserID)
+		_, _, err := object.RemoveKey(r.objContext, r.userConfig.UserID, accessKey)
+		if err != nil {
+			return errors.Wrapf(err, "failed to remove the rotated key of ceph object user %q", r.userConfig.UserID)
+		}
+		r.recordChange("key", "removed")
+	}
+	r.rotatedKeys = nil
+	return nil
+}
+
+// refreshKeyRotation reports when the key of the user is rotated next, which also schedules the first
+// rotation of a user that was just created
+func refreshKeyRotation(u *cephv1.CephObjectStoreUser, accessKey *string) {
+	if u.Spec.KeyRotation == nil {
+		u.Status.KeyRotation = nil
+		return
+	}
+	if u.Status.KeyRotation == nil {
+		u.Status.KeyRotation = &cephv1.ObjectUserKeyRotationStatus{}
+	}
+	if accessKey != nil {
+		u.Status.KeyRotation.AccessKey = *accessKey
+	}
+	next := metav1.NewTime(nextKeyRotation(u))
+	u.Status.KeyRotation.NextRotation = &next
+}
+
+// keyRotationRequeueAfter returns the requeue interval of a reconciled user, which is reconciled again
+// right after its key rotation is due
+func keyRotationRequeueAfter(u *cephv1.CephObjectStoreUser, requeueAfter time.Duration) time.Duration {
+	if u.Spec.KeyRotation == nil {
+		return requeueAfter
+	}
+	// Requeue just past the rotation, so it is due by then
+	untilRotation := nextKeyRotation(u).Sub(now()) + time.Second
+	if untilRotation < time.Second {
+		untilRotation = time.Second
+	}
+	if requeueAfter > 0 && requeueAfter < untilRotation {
+		return requeueAfter
+	}
+	return untilRotation
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"fmt"
+
+	"github.com/coreos/pkg/capnslog"
+)
+
+// userLogger logs the messages of a reconcile with the object store user it reconciles, so that the
+// lines of a user can be found in busy operator logs, e.g. with `grep 'uid="my-user"'`. A nil logger
+// logs without the user.
+type userLogger struct {
+	context string
+}
+
+// newUserLogger returns a logger for the user with the given uid in the store and namespace
+func newUserLogger(uid, store, namespace string) *userLogger {
+	return &userLogger{context: fmt.Sprintf("uid=%q store=%q namespace=%q", uid, store, namespace)}
+}
+
+func (l *userLogger) logf(level capnslog.LogLevel, format string, args ...interface{}) {
+	if !logger.LevelAt(level) {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	if l == nil {
+		logger.Logf(level, "%s", message)
+		return
+	}
+	logger.Logf(level, "%s: %s", l.context, message)
+}
+
+func (l *userLogger) Errorf(format string, args ...interface{}) {
+	l.logf(capnslog.ERROR, format, args...)
+}
+
+func (l *userLogger) Warningf(format string, args ...interface{}) {
+	l.logf(capnslog.WARNING, format, args...)
+}
+
+func (l *userLogger) Infof(format string, args ...interface{}) {
+	l.logf(capnslog.INFO, format, args...)
+}
+
+func (l *userLogger) Info(message string) {
+	l.logf(capnslog.INFO, "%s", message)
+}
+
+func (l *userLogger) Debugf(format string, args ...interface{}) {
+	l.logf(capnslog.DEBUG, format, args...)
+}
+
+func (l *userLogger) Debug(message string) {
+	l.logf(capnslog.DEBUG, "%s", message)
+}
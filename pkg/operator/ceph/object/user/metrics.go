@@ -0,0 +1,91 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	failureReasonInvalidSpec    = "invalid_spec"
+	failureReasonCephUser       = "ceph_user"
+	failureReasonSecret         = "secret"
+	failureReasonInitialBuckets = "initial_buckets"
+	failureReasonOther          = "other"
+)
+
+// userPhases are the phases reported by the user phase gauge
+var userPhases = []cephv1.ObjectStoreUserPhase{cephv1.ObjectStoreUserPhaseCreated, cephv1.ObjectStoreUserPhaseReconciling, cephv1.ObjectStoreUserPhaseReconcileFailed, cephv1.ObjectStoreUserPhaseReady, cephv1.ObjectStoreUserPhaseValidated}
+
+var (
+	reconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rook_ceph_object_user_reconcile_total",
+		Help: "Number of object store user reconciles",
+	}, []string{"namespace", "store"})
+
+	reconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rook_ceph_object_user_reconcile_errors_total",
+		Help: "Number of failed object store user reconciles by reason",
+	}, []string{"namespace", "store", "reason"})
+
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rook_ceph_object_user_reconcile_duration_seconds",
+		Help:    "Duration of object store user reconciles",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"namespace", "store"})
+
+	userPhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rook_ceph_object_user_phase",
+		Help: "Current phase of the object store user, 1 for the phase the user is in and 0 otherwise",
+	}, []string{"namespace", "store", "user", "phase"})
+)
+
+func init() {
+	// The metrics are served on the metrics endpoint of the controller-runtime manager
+	metrics.Registry.MustRegister(reconcileTotal, reconcileErrors, reconcileDuration, userPhase)
+}
+
+// observeReconcile records the outcome of a reconcile of a user of the given object store
+func observeReconcile(namespace, store string, duration time.Duration, failureReason string) {
+	reconcileTotal.WithLabelValues(namespace, store).Inc()
+	reconcileDuration.WithLabelValues(namespace, store).Observe(duration.Seconds())
+	if failureReason != "" {
+		reconcileErrors.WithLabelValues(namespace, store, failureReason).Inc()
+	}
+}
+
+// setPhaseMetric reports the current phase of the user
+func setPhaseMetric(u *cephv1.CephObjectStoreUser) {
+	for _, phase := range userPhases {
+		value := 0.0
+		if u.Status != nil && u.Status.Phase == phase {
+			value = 1
+		}
+		userPhase.WithLabelValues(u.Namespace, storeName(u), u.Name, string(phase)).Set(value)
+	}
+}
+
+// deletePhaseMetric stops reporting the phase of a deleted user
+func deletePhaseMetric(u *cephv1.CephObjectStoreUser) {
+	for _, phase := range userPhases {
+		userPhase.DeleteLabelValues(u.Namespace, storeName(u), u.Name, string(phase))
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"context"
+	"sort"
+
+	bktv1alpha1 "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/pkg/errors"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// obCephUserKey is the key of the additional state of an object bucket with the RGW user owning its bucket
+	obCephUserKey = "cephUser"
+	// The parameters of the storage class of an object bucket with the object store of its bucket
+	obObjectStoreNameParam      = "objectStoreName"
+	obObjectStoreNamespaceParam = "objectStoreNamespace"
+	// reasonObjectBucketsExist is the reason of a user whose deletion waits for its object buckets
+	reasonObjectBucketsExist = "ObjectBucketsExist"
+)
+
+// objectBucketsOfUser returns the names of the object buckets of bucket claims whose bucket is owned by
+// the RGW user. Deleting the user while a claim still uses it orphans the bucket of the claim. A bucket
+// whose storage class is gone cannot be matched with a store and is counted, to be safe.
+func (r *ReconcileObjectStoreUser) objectBucketsOfUser(u *cephv1.CephObjectStoreUser) ([]string, error) {
+	objectBuckets := &bktv1alpha1.ObjectBucketList{}
+	err := r.client.List(context.TODO(), objectBuckets)
+	if err != nil {
+		// Without the bucket provisioner CRDs there are no object buckets
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to list object buckets")
+	}
+
+	names := []string{}
+	for _, ob := range objectBuckets.Items {
+		if ob.Spec.Connection == nil || ob.Spec.AdditionalState[obCephUserKey] != userID(u) {
+			continue
+		}
+		storageClass := &storagev1.StorageClass{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: ob.Spec.StorageClassName}, storageClass)
+		if err != nil && !kerrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get storage class %q of object bucket %q", ob.Spec.StorageClassName, ob.Name)
+		}
+		if err == nil && (storageClass.Parameters[obObjectStoreNameParam] != storeName(u) || storageClass.Parameters[obObjectStoreNamespaceParam] != clusterNamespace(u)) {
+			continue
+		}
+		names = append(names, ob.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/operator/ceph/object"
+	v1 "k8s.io/api/core/v1"
+)
+
+// checkQuotaUsage reads the usage of the user and records whether it exceeds the user quota. RGW
+// accepts a quota below the current usage, which leaves the user unable to write until its usage drops.
+// Reading the usage is informational, a failure is only logged.
+func (r *ReconcileObjectStoreUser) checkQuotaUsage(quota *object.ObjectUserQuota) {
+	if r.dryRun {
+		return
+	}
+	stats, _, err := object.GetUserStats(r.objContext, r.userConfig.UserID)
+	if err != nil {
+		r.log.Warningf("failed to check the usage of ceph object user %q against its quota. %v", r.userConfig.UserID, err)
+		return
+	}
+	r.quotaUsageChecked = true
+	r.quotaUsage = stats
+	r.overQuota = ""
+	if quota == nil || !quota.Enabled {
+		return
+	}
+	if (quota.MaxSize >= 0 && stats.Size > quota.MaxSize) || (quota.MaxObjects >= 0 && stats.NumObjects > quota.MaxObjects) {
+		r.overQuota = fmt.Sprintf("the usage of %d bytes and %d objects exceeds the user quota of %d bytes and %d objects, -1 for unlimited", stats.Size, stats.NumObjects, quota.MaxSize, quota.MaxObjects)
+	}
+}
+
+// refreshOverQuota reports whether the usage of the user exceeds its quota, if it was checked
+func (r *ReconcileObjectStoreUser) refreshOverQuota(u *cephv1.CephObjectStoreUser) {
+	if !r.quotaUsageChecked {
+		return
+	}
+	if r.overQuota != "" {
+		r.log.Warningf("ceph object user %q is over quota, %s", r.userConfig.UserID, r.overQuota)
+		setCondition(u, cephv1.ConditionOverQuota, v1.ConditionTrue, "UsageExceedsQuota", r.overQuota)
+	} else if findCondition(u.Status.Conditions, cephv1.ConditionOverQuota) != nil {
+		setCondition(u, cephv1.ConditionOverQuota, v1.ConditionFalse, "UsageWithinQuota", "the usage of the user is within its quota")
+	}
+}
+
+// suspendsOnBreach returns whether the user of the spec is suspended while its usage exceeds its quota
+func suspendsOnBreach(spec cephv1.ObjectStoreUserSpec) bool {
+	return spec.Quotas != nil && spec.Quotas.SuspendOnBreach
+}
+
+// managesBreachSuspension returns whether the suspension of the user on a quota breach is reconciled,
+// which is only the case for a user that is suspended on a breach or was suspended because of one
+func (r *ReconcileObjectStoreUser) managesBreachSuspension() bool {
+	return suspendsOnBreach(r.userSpec) || r.suspendedOnBreach
+}
+
+// reconcileBreachSuspension suspends the user while its usage exceeds its user quota. A user the
+// operator suspended on a breach is enabled again once its usage is within the quota or the policy is
+// removed, while a user suspended by other means is left suspended. An expired user stays suspended.
+func (r *ReconcileObjectStoreUser) reconcileBreachSuspension(suspended bool) error {
+	if isExpired(r.userSpec) {
+		return nil
+	}
+	// Without the usage it is not known whether the breach ended
+	if suspendsOnBreach(r.userSpec) && !r.quotaUsageChecked {
+		return nil
+	}
+	breached := suspendsOnBreach(r.userSpec) && r.overQuota != ""
+	if !breached && !suspended {
+		// The user was enabled by other means
+		r.suspendedOnBreach = false
+		return nil
+	}
+	if breached == suspended || (!breached && !r.suspendedOnBreach) {
+		return nil
+	}
+
+	if breached {
+		r.log.Infof("suspending ceph object user %q, %s", r.userConfig.UserID, r.overQuota)
+	} else {
+		r.log.Infof("enabling ceph object user %q, it no longer breaches its quota", r.userConfig.UserID)
+	}
+	err := r.applyChange(func() error {
+		_, _, err := object.SuspendUser(r.objContext, r.userConfig.UserID, breached)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to change the suspension of ceph object user %q on its quota", r.userConfig.UserID)
+	}
+	r.recordChange("suspended", strconv.FormatBool(breached))
+	if r.dryRun {
+		return nil
+	}
+	r.suspendedOnBreach = breached
+	r.breachSuspension = "enabled"
+	if breached {
+		r.breachSuspension = "suspended"
+	}
+	return nil
+}
+
+// refreshBreachSuspension reports that the user is suspended because its usage exceeds its quota, and
+// emits an event when this reconcile suspended or enabled it
+func (r *ReconcileObjectStoreUser) refreshBreachSuspension(u *cephv1.CephObjectStoreUser) {
+	switch r.breachSuspension {
+	case "suspended":
+		r.recorder.Event(u, v1.EventTypeWarning, "SuspendedOnQuotaBreach", fmt.Sprintf("suspended the user, %s", r.overQuota))
+	case "enabled":
+		r.recorder.Event(u, v1.EventTypeNormal, "EnabledWithinQuota", "enabled the user suspended on a quota breach")
+	}
+	if r.suspendedOnBreach {
+		setCondition(u, cephv1.ConditionSuspendedOnQuotaBreach, v1.ConditionTrue, "UsageExceedsQuota", "the user is suspended while its usage exceeds its user quota")
+	} else if findCondition(u.Status.Conditions, cephv1.ConditionSuspendedOnQuotaBreach) != nil {
+		setCondition(u, cephv1.ConditionSuspendedOnQuotaBreach, v1.ConditionFalse, "NotSuspendedOnQuota", "the user is not suspended on a quota breach")
+	}
+}
+
+// refreshQuotaUtilization reports the usage of the user in percent of the size of its user quota as RGW
+// enforces it, e.g. for dashboards and alerts. It is not reported without an enforced size quota, and
+// like the effective quotas it is only reported, a failure to read the usage is only logged.
+func (r *ReconcileObjectStoreUser) refreshQuotaUtilization(u *cephv1.CephObjectStoreUser) {
+	quota := u.Status.EffectiveQuota
+	if quota == nil || !quota.User.Enabled || quota.User.MaxSize <= 0 {
+		u.Status.QuotaUtilizationPercent = nil
+		return
+	}
+	stats := r.quotaUsage
+	if stats == nil {
+		var err error
+		stats, _, err = object.GetUserStats(object.NewReadOnlyContext(r.objContext), r.userConfig.UserID)
+		if err != nil {
+			r.log.Warningf("failed to refresh the quota utilization of ceph object user %q. %v", r.userConfig.UserID, err)
+			return
+		}
+	}
+	percent := int(float64(stats.Size) * 100 / float64(quota.User.MaxSize))
+	u.Status.QuotaUtilizationPercent = &percent
+}
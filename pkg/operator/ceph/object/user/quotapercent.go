@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"github.com/pkg/errors"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	cephclient "github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/ceph/object"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// reasonQuotaCapacityUnavailable is the reason of a user whose size quota in percent cannot be computed
+const reasonQuotaCapacityUnavailable = "QuotaCapacityUnavailable"
+
+// storeCapacity returns the capacity in bytes of the data pool of the object store of the user, the
+// bytes the pool stores and the bytes still available to it
+func (r *ReconcileObjectStoreUser) storeCapacity(u *cephv1.CephObjectStoreUser) (int64, error) {
+	stats, err := cephclient.GetPoolStats(r.context, clusterNamespace(u))
+	if err != nil {
+		return 0, err
+	}
+	pool := object.DataPoolName(storeName(u))
+	for _, p := range stats.Pools {
+		if p.Name == pool {
+			return int64(p.Stats.BytesUsed + p.Stats.MaxAvail), nil
+		}
+	}
+	return 0, errors.Errorf("data pool %q of object store %q not found", pool, storeName(u))
+}
+
+// resolveMaxSizePercent replaces the size quota in percent of the spec with the size in bytes it
+// amounts to with the current capacity of the object store. RGW only enforces the size, which follows
+// the capacity of the store when the user is reconciled again, e.g. after the store was resized.
+func (r *ReconcileObjectStoreUser) resolveMaxSizePercent(u *cephv1.CephObjectStoreUser) error {
+	quotas := u.Spec.Quotas
+	if quotas == nil || quotas.MaxSizePercent == nil {
+		return nil
+	}
+	capacity, err := r.storeCapacity(u)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the capacity of object store %q for maxSizePercent", storeName(u))
+	}
+	maxSize := resource.NewQuantity(int64(float64(capacity)*float64(*quotas.MaxSizePercent)/100), resource.BinarySI)
+	r.log.Debugf("max size of %d%% of the capacity of %d bytes is %s", *quotas.MaxSizePercent, capacity, maxSize.String())
+	quotas.MaxSize = maxSize
+	quotas.MaxSizePercent = nil
+	return nil
+}
+
+// validateMaxSizePercent validates that the size quota in percent is from 1 to 100
+func validateMaxSizePercent(percent int) error {
+	if percent < 1 || percent > 100 {
+		return errors.Errorf("invalid max size percent %d, must be from 1 to 100", percent)
+	}
+	return nil
+}
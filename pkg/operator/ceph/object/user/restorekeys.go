@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// restoredKeyName is the name the key a user is restored with is tracked under
+const restoredKeyName = "restored"
+
+// validateRestoreKeys validates that the restored key is the only source of the S3 keys of the spec
+func validateRestoreKeys(spec cephv1.ObjectStoreUserSpec) error {
+	if len(spec.Keys) > 0 || spec.Adopt || spec.SuppressUserKeys || spec.GenerateMissingKey || (spec.GenerateKey != nil && *spec.GenerateKey) || spec.DeterministicKey != nil {
+		return errors.New("restoreKeysFrom is the only key of the user, it cannot be combined with keys, adopt, suppressUserKeys, generateMissingKey, generateKey or deterministicKey")
+	}
+	return nil
+}
+
+// withRestoredKeys returns the spec with the key it restores as its only key. The user is created with
+// the key, and the key is set on an existing user whose other keys are removed, like the key generated
+// when the user was created before the restore.
+func withRestoredKeys(spec cephv1.ObjectStoreUserSpec) cephv1.ObjectStoreUserSpec {
+	if spec.RestoreKeysFrom == "" {
+		return spec
+	}
+	spec.Keys = []cephv1.UserKeySpec{{Name: restoredKeyName, SecretName: spec.RestoreKeysFrom}}
+	generateKey := false
+	spec.GenerateKey = &generateKey
+	return spec
+}
+
+// readRestoredKeys reads the key the user is restored with from its secret
+func (r *ReconcileObjectStoreUser) readRestoredKeys(u *cephv1.CephObjectStoreUser) error {
+	if u.Spec.RestoreKeysFrom == "" {
+		return nil
+	}
+	secretName := u.Spec.RestoreKeysFrom
+	secret := &v1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: secretName, Namespace: u.Namespace}, secret)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get secret %q of the restored key", secretName)
+	}
+	accessKey, secretKey := secretValue(secret, "AccessKey"), secretValue(secret, "SecretKey")
+	if accessKey == "" || secretKey == "" {
+		return errors.Errorf("secret %q of the restored key must hold an AccessKey and a SecretKey", secretName)
+	}
+
+	if r.keyRefs == nil {
+		r.keyRefs = map[string]namedKey{}
+	}
+	r.keyRefs[restoredKeyName] = namedKey{name: restoredKeyName, accessKey: accessKey, secretKey: secretKey}
+	return nil
+}
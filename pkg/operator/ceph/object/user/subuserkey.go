@@ -0,0 +1,234 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/operator/ceph/object"
+)
+
+// reconcileSubuserKeys generates the S3 keys of the subusers of the spec with an s3 key type that have
+// none and sets the S3 keys read from a secret. A subuser keeps the other S3 keys it holds, the first
+// one is written to the secret. Without drift correction, the S3 keys are only resolved.
+func (r *ReconcileObjectStoreUser) reconcileSubuserKeys(objectUser *object.ObjectUser, correctDrift bool) (bool, error) {
+	live := map[string][]object.ObjectUserKey{}
+	for _, subuser := range objectUser.Subusers {
+		live[subuser.ID] = subuser.Keys
+	}
+
+	changed := false
+	r.subuserKeys = nil
+	for _, subuser := range r.userSpec.Subusers {
+		if !isS3Subuser(subuser) || (!subuser.GenerateKey && subuser.SecretName == "") {
+			continue
+		}
+		id := fmt.Sprintf("%s:%s", r.userConfig.UserID, subuser.Name)
+		keys := live[id]
+		if ref, referenced := r.subuserKeyRefs[id]; referenced {
+			if !hasKey(keys, ref) {
+				if !correctDrift {
+					continue
+				}
+				err := r.applyChange(func() error {
+					_, _, err := object.SetSubuserKey(r.objContext, r.userConfig.UserID, id, ref.accessKey, ref.secretKey)
+					return err
+				})
+				if err != nil {
+					return changed, errors.Wrapf(err, "failed to set s3 key of subuser %q", id)
+				}
+				r.recordChange("subuserKey", fmt.Sprintf("%s:set", id))
+				changed = true
+			}
+			r.subuserKeys = append(r.subuserKeys, ref)
+			continue
+		}
+
+		if len(keys) == 0 {
+			if !correctDrift {
+				continue
+			}
+			// There is no key to track until it is actually created
+			if r.dryRun {
+				r.recordChange("subuserKey", fmt.Sprintf("%s:created", id))
+				changed = true
+				continue
+			}
+			user, _, err := object.CreateSubuserKey(r.objContext, r.userConfig.UserID, id)
+			if err != nil {
+				return changed, errors.Wrapf(err, "failed to create s3 key of subuser %q", id)
+			}
+			for _, created := range user.Subusers {
+				if created.ID == id {
+					keys = created.Keys
+				}
+			}
+			if len(keys) == 0 {
+				return changed, errors.Errorf("failed to find the created s3 key of subuser %q", id)
+			}
+			r.recordChange("subuserKey", fmt.Sprintf("%s:created", id))
+			changed = true
+		}
+		r.subuserKeys = append(r.subuserKeys, namedKey{name: id, accessKey: keys[0].AccessKey, secretKey: keys[0].SecretKey})
+	}
+
+	return changed, nil
+}
+
+// hasKey returns whether the keys hold the given key with the same secret key
+func hasKey(keys []object.ObjectUserKey, key namedKey) bool {
+	for _, k := range keys {
+		if k.AccessKey == key.accessKey && k.SecretKey == key.secretKey {
+			return true
+		}
+	}
+	return false
+}
+
+// rotatesWithUser returns whether the key of the subuser is generated again when the S3 keys of the
+// user rotate. Only a generated key can be generated again.
+func rotatesWithUser(spec cephv1.ObjectStoreUserSpec, subuser cephv1.SubuserSpec) bool {
+	return subuser.GenerateKey && (subuser.RotateWithUser || spec.RotateSubuserKeys)
+}
+
+// keysRotated returns whether this reconcile set or created S3 keys of the spec on an existing user. The
+// keys of a user that was just created are not a rotation.
+func (r *ReconcileObjectStoreUser) keysRotated() bool {
+	rotated := false
+	for _, change := range r.changes {
+		if change == "user=created" {
+			return false
+		}
+		if strings.HasPrefix(change, "key=") && (strings.HasSuffix(change, ":set") || strings.HasSuffix(change, ":created")) {
+			rotated = true
+		}
+	}
+	return rotated
+}
+
+// rotateSubuserKeys generates the keys of the subusers that rotate with the user again once its S3 keys
+// rotated, and removes the previous S3 keys of the subusers. The new keys replace the tracked ones, so
+// that the secret is updated with the keys of the user and of its subusers at once.
+func (r *ReconcileObjectStoreUser) rotateSubuserKeys(objectUser *object.ObjectUser) (bool, error) {
+	if !r.keysRotated() {
+		return false, nil
+	}
+	live := map[string][]object.ObjectUserKey{}
+	for _, subuser := range objectUser.Subusers {
+		live[subuser.ID] = subuser.Keys
+	}
+
+	changed := false
+	for _, subuser := range r.userSpec.Subusers {
+		if !rotatesWithUser(r.userSpec, subuser) {
+			continue
+		}
+		id := fmt.Sprintf("%s:%s", r.userConfig.UserID, subuser.Name)
+		if isS3Subuser(subuser) {
+			r.log.Infof("rotating the s3 key of subuser %q with the keys of the user", id)
+			r.recordChange("subuserKey", fmt.Sprintf("%s:rotated", id))
+			changed = true
+			if r.dryRun {
+				continue
+			}
+			err := r.rotateSubuserS3Key(id, live[id])
+			if err != nil {
+				return changed, err
+			}
+			continue
+		}
+
+		r.log.Infof("rotating the swift key of subuser %q with the keys of the user", id)
+		r.recordChange("swiftKey", fmt.Sprintf("%s:rotated", id))
+		changed = true
+		if r.dryRun {
+			continue
+		}
+		// A subuser holds a single Swift key, which is replaced
+		user, _, err := object.CreateSwiftKey(r.objContext, r.userConfig.UserID, id)
+		if err != nil {
+			return changed, errors.Wrapf(err, "failed to rotate swift key of subuser %q", id)
+		}
+		for _, created := range user.Subusers {
+			if created.ID != id {
+				continue
+			}
+			for i, key := range r.swiftKeys {
+				if key.accessKey == id {
+					r.swiftKeys[i].secretKey = created.SwiftKey
+				}
+			}
+		}
+	}
+	return changed, nil
+}
+
+// rotateSubuserS3Key generates a new S3 key for the subuser, then removes its previous S3 keys
+func (r *ReconcileObjectStoreUser) rotateSubuserS3Key(id string, liveKeys []object.ObjectUserKey) error {
+	previous := map[string]bool{}
+	previousKeys := []string{}
+	for _, key := range liveKeys {
+		previous[key.AccessKey] = true
+		previousKeys = append(previousKeys, key.AccessKey)
+	}
+	tracked := -1
+	for i, key := range r.subuserKeys {
+		if key.name == id {
+			tracked = i
+			if !previous[key.accessKey] {
+				previous[key.accessKey] = true
+				previousKeys = append(previousKeys, key.accessKey)
+			}
+		}
+	}
+
+	user, _, err := object.CreateSubuserKey(r.objContext, r.userConfig.UserID, id)
+	if err != nil {
+		return errors.Wrapf(err, "failed to rotate s3 key of subuser %q", id)
+	}
+	var rotated *object.ObjectUserKey
+	for _, created := range user.Subusers {
+		if created.ID != id {
+			continue
+		}
+		for i, key := range created.Keys {
+			if !previous[key.AccessKey] {
+				rotated = &created.Keys[i]
+			}
+		}
+	}
+	if rotated == nil {
+		return errors.Errorf("failed to find the rotated s3 key of subuser %q", id)
+	}
+	for _, accessKey := range previousKeys {
+		_, _, err := object.RemoveSubuserKey(r.objContext, r.userConfig.UserID, id, accessKey)
+		if err != nil {
+			return errors.Wrapf(err, "failed to remove the previous s3 key of subuser %q", id)
+		}
+	}
+
+	key := namedKey{name: id, accessKey: rotated.AccessKey, secretKey: rotated.SecretKey}
+	if tracked >= 0 {
+		r.subuserKeys[tracked] = key
+	} else {
+		r.subuserKeys = append(r.subuserKeys, key)
+	}
+	return nil
+}
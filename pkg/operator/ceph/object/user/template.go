@@ -0,0 +1,143 @@
+/*
+Copyright 2020 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectuser
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	opcontroller "github.com/rook/rook/pkg/operator/ceph/controller"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// templateLabel is set on the users generated from a template to the name of the template
+const templateLabel = "ceph.rook.io/object-store-user-template"
+
+// isTemplate returns whether the CR is a template for the users of its spec rather than a user itself
+func isTemplate(u *cephv1.CephObjectStoreUser) bool {
+	return len(u.Spec.Users) > 0
+}
+
+// templateUserName returns the name of the CephObjectStoreUser generated for a user of the template
+func templateUserName(template *cephv1.CephObjectStoreUser, user string) string {
+	return fmt.Sprintf("%s-%s", template.Name, user)
+}
+
+// reconcileTemplate makes the CephObjectStoreUsers generated from the template match its users. Each
+// generated user is owned by the template and reconciled on its own, so removing a user from the
+// template deletes the CR and with it the RGW user and its secret. Deleting the template deletes all
+// of its users through the garbage collection of the owned CRs.
+func (r *ReconcileObjectStoreUser) reconcileTemplate(template *cephv1.CephObjectStoreUser) (reconcile.Result, error) {
+	if !template.GetDeletionTimestamp().IsZero() {
+		// A CR that was a user before it became a template still holds the finalizer of the user
+		err := opcontroller.RemoveFinalizer(r.client, template)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrap(err, "failed to remove finalizer")
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if template.Status == nil {
+		template.Status = &cephv1.ObjectStoreUserStatus{}
+	}
+	err := ValidateUser(template)
+	if err != nil {
+		setPhase(template, cephv1.ObjectStoreUserPhaseReconcileFailed, reasonInvalidSpec, err.Error())
+		errStatus := opcontroller.UpdateStatus(r.client, template)
+		if errStatus != nil {
+			return reconcile.Result{}, errors.Wrap(errStatus, "failed to set status")
+		}
+		return reconcile.Result{}, errors.Wrapf(err, "invalid object store user template CR %q spec", template.Name)
+	}
+
+	existing := &cephv1.CephObjectStoreUserList{}
+	err = r.client.List(context.TODO(), existing, client.InNamespace(template.Namespace), client.MatchingLabels{templateLabel: template.Name})
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to list the users of template %q", template.Name)
+	}
+	existingUsers := map[string]*cephv1.CephObjectStoreUser{}
+	for i := range existing.Items {
+		existingUsers[existing.Items[i].Name] = &existing.Items[i]
+	}
+
+	// The generated users have the spec of the template, without its users
+	spec := *template.Spec.DeepCopy()
+	spec.Users = nil
+
+	users := []string{}
+	for _, user := range template.Spec.Users {
+		name := templateUserName(template, user)
+		users = append(users, name)
+		if existingUser, ok := existingUsers[name]; ok {
+			delete(existingUsers, name)
+			if reflect.DeepEqual(existingUser.Spec, spec) {
+				continue
+			}
+			existingUser.Spec = *spec.DeepCopy()
+			err = r.client.Update(context.TODO(), existingUser)
+			if err != nil {
+				return reconcile.Result{}, errors.Wrapf(err, "failed to update object store user %q of template %q", name, template.Name)
+			}
+			r.log.Infof("updated object store user %q of template %q", name, template.Name)
+			continue
+		}
+
+		generatedUser := &cephv1.CephObjectStoreUser{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: template.Namespace,
+				Labels:    map[string]string{templateLabel: template.Name},
+			},
+			Spec: *spec.DeepCopy(),
+		}
+		err = controllerutil.SetControllerReference(template, generatedUser, r.scheme)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrapf(err, "failed to set owner reference of object store user %q", name)
+		}
+		err = r.client.Create(context.TODO(), generatedUser)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrapf(err, "failed to create object store user %q of template %q", name, template.Name)
+		}
+		r.log.Infof("created object store user %q of template %q", name, template.Name)
+	}
+
+	// The users left were removed from the template
+	for name, removedUser := range existingUsers {
+		err = r.client.Delete(context.TODO(), removedUser)
+		if err != nil {
+			return reconcile.Result{}, errors.Wrapf(err, "failed to delete object store user %q of template %q", name, template.Name)
+		}
+		r.log.Infof("deleted object store user %q removed from template %q", name, template.Name)
+	}
+
+	sort.Strings(users)
+	template.Status.Users = users
+	template.Status.ObservedGeneration = template.Generation
+	setPhase(template, cephv1.ObjectStoreUserPhaseReady, reasonReconciled, fmt.Sprintf("the %d users of the template are generated", len(users)))
+	err = opcontroller.UpdateStatus(r.client, template)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "failed to set status")
+	}
+	return reconcile.Result{}, nil
+}
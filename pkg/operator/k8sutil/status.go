@@ -29,4 +29,6 @@ const (
 	ReconcileFailedStatus = "ReconcileFailed"
 	// Created indicates the object just got created
 	Created = "Created"
+	// ValidatedStatus indicates a dry run reconcile validated the CR without applying it
+	ValidatedStatus = "Validated"
 )
@@ -16,6 +16,7 @@ limitations under the License.
 package exec
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"syscall"
@@ -48,6 +49,11 @@ func (e *CommandError) ExitStatus() int {
 	return exitStatus
 }
 
+// IsTimeout returns whether the command was killed because it did not complete in time
+func (e *CommandError) IsTimeout() bool {
+	return e.Err == context.DeadlineExceeded
+}
+
 func createCommandError(err error, actionName string) error {
 	return &CommandError{ActionName: actionName, Err: err}
 }
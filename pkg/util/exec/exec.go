@@ -25,6 +25,7 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/pkg/capnslog"
@@ -38,6 +39,7 @@ type Executor interface {
 	ExecuteCommandWithOutputFile(debug bool, actionName, command, outfileArg string, arg ...string) (string, error)
 	ExecuteCommandWithOutputFileTimeout(debug bool, timeout time.Duration, actionName, command, outfileArg string, arg ...string) (string, error)
 	ExecuteCommandWithTimeout(debug bool, timeout time.Duration, actionName string, command string, arg ...string) (string, error)
+	ExecuteCommandWithOutputTimeout(debug bool, timeout time.Duration, actionName string, command string, arg ...string) (string, error)
 	ExecuteStat(name string) (os.FileInfo, error)
 }
 
@@ -131,6 +133,20 @@ func (*CommandExecutor) ExecuteCommandWithOutput(debug bool, actionName string,
 	return runCommandWithOutput(actionName, cmd, false)
 }
 
+// ExecuteCommandWithOutputTimeout is the same as ExecuteCommandWithOutput, but kills the process once the
+// timeout passed. The error of a command that timed out wraps context.DeadlineExceeded.
+func (*CommandExecutor) ExecuteCommandWithOutputTimeout(debug bool, timeout time.Duration, actionName string, command string, arg ...string) (string, error) {
+	logCommand(debug, command, arg...)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, command, arg...)
+	output, err := runCommandWithOutput(actionName, cmd, false)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return output, createCommandError(ctx.Err(), actionName)
+	}
+	return output, err
+}
+
 func (*CommandExecutor) ExecuteCommandWithCombinedOutput(debug bool, actionName string, command string, arg ...string) (string, error) {
 	logCommand(debug, command, arg...)
 	cmd := exec.Command(command, arg...)
@@ -277,8 +293,40 @@ func runCommandWithOutput(actionName string, cmd *exec.Cmd, combinedOutput bool)
 	return out, nil
 }
 
+// secretFlags are the flags whose value is a secret, e.g. a key, that is never logged
+var secretFlags = struct {
+	sync.RWMutex
+	flags map[string]bool
+}{flags: map[string]bool{}}
+
+// AddSecretFlags registers flags whose value is a secret, so that it is redacted when a command is logged
+func AddSecretFlags(flags ...string) {
+	secretFlags.Lock()
+	defer secretFlags.Unlock()
+	for _, flag := range flags {
+		secretFlags.flags[flag] = true
+	}
+}
+
+// RedactArgs returns the arguments with the values of the secret flags redacted, given either as a
+// separate argument or as "<flag>=<value>". The arguments are not changed.
+func RedactArgs(args []string) []string {
+	secretFlags.RLock()
+	defer secretFlags.RUnlock()
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i, arg := range redacted {
+		if secretFlags.flags[arg] && i+1 < len(redacted) {
+			redacted[i+1] = "<redacted>"
+		} else if parts := strings.SplitN(arg, "=", 2); len(parts) == 2 && secretFlags.flags[parts[0]] {
+			redacted[i] = parts[0] + "=<redacted>"
+		}
+	}
+	return redacted
+}
+
 func logCommand(debug bool, command string, arg ...string) {
-	msg := fmt.Sprintf("Running command: %s %s", command, strings.Join(arg, " "))
+	msg := fmt.Sprintf("Running command: %s %s", command, strings.Join(RedactArgs(arg), " "))
 	if debug {
 		logger.Debug(msg)
 	} else {
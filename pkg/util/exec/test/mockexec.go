@@ -30,6 +30,7 @@ type MockExecutor struct {
 	MockExecuteCommandWithOutputFile        func(debug bool, actionName string, command, outfileArg string, arg ...string) (string, error)
 	MockExecuteCommandWithOutputFileTimeout func(debug bool, timeout time.Duration, actionName string, command, outfileArg string, arg ...string) (string, error)
 	MockExecuteCommandWithTimeout           func(debug bool, timeout time.Duration, actionName string, command string, arg ...string) (string, error)
+	MockExecuteCommandWithOutputTimeout     func(debug bool, timeout time.Duration, actionName string, command string, arg ...string) (string, error)
 	MockExecuteStat                         func(name string) (os.FileInfo, error)
 }
 
@@ -67,6 +68,14 @@ func (e *MockExecutor) ExecuteCommandWithTimeout(debug bool, timeout time.Durati
 	return "", nil
 }
 
+func (e *MockExecutor) ExecuteCommandWithOutputTimeout(debug bool, timeout time.Duration, actionName string, command string, arg ...string) (string, error) {
+	if e.MockExecuteCommandWithOutputTimeout != nil {
+		return e.MockExecuteCommandWithOutputTimeout(debug, timeout, actionName, command, arg...)
+	}
+
+	return "", nil
+}
+
 func (e *MockExecutor) ExecuteCommandWithCombinedOutput(debug bool, actionName string, command string, arg ...string) (string, error) {
 	if e.MockExecuteCommandWithCombinedOutput != nil {
 		return e.MockExecuteCommandWithCombinedOutput(debug, actionName, command, arg...)
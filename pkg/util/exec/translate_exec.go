@@ -78,6 +78,12 @@ func (e *TranslateCommandExecutor) ExecuteCommandWithTimeout(debug bool, timeout
 	return e.Executor.ExecuteCommandWithTimeout(debug, timeout, actionName, transCommand, transArgs...)
 }
 
+// ExecuteCommandWithOutputTimeout starts a process and wait for its completion with timeout.
+func (e *TranslateCommandExecutor) ExecuteCommandWithOutputTimeout(debug bool, timeout time.Duration, actionName string, command string, arg ...string) (string, error) {
+	transCommand, transArgs := e.Translator(debug, actionName, command, arg...)
+	return e.Executor.ExecuteCommandWithOutputTimeout(debug, timeout, actionName, transCommand, transArgs...)
+}
+
 // ExecuteStat returns a file stat
 func (e *TranslateCommandExecutor) ExecuteStat(name string) (os.FileInfo, error) {
 	return nil, fmt.Errorf("TODO: TranslateCommandExecutor.ExecuteStat() not implemented ... is it needed?")
@@ -463,6 +463,18 @@ spec:
     - objectuser
   scope: Namespaced
   version: v1
+  additionalPrinterColumns:
+    - name: Store
+      type: string
+      description: Object store of the user
+      JSONPath: .spec.store
+    - name: Phase
+      type: string
+      description: Phase
+      JSONPath: .status.phase
+    - name: Age
+      type: date
+      JSONPath: .metadata.creationTimestamp
   subresources:
     status: {}
 ---